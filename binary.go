@@ -3,19 +3,23 @@ package simpledb
 import (
 	"bytes"
 	"encoding/binary"
+	"fmt"
 	"io"
 	"reflect"
+	"sort"
 )
 
 // wrappedByteReader wraps an io.Reader with a ReadByte method, needed for binary.ReadUvarint
 type wrappedByteReader struct {
 	io.Reader
+
+	// buf is reused for reading single bytes and fixed-size values.
+	buf [16]byte
 }
 
 func (r *wrappedByteReader) ReadByte() (byte, error) {
-	p := make([]byte, 1)
-	_, err := r.Read(p)
-	return p[0], err
+	_, err := r.Read(r.buf[:1])
+	return r.buf[0], err
 }
 
 func encodeUvarint(n uint64) []byte {
@@ -41,10 +45,18 @@ func decodeUint64(r io.Reader) (n uint64, err error) {
 	return
 }
 
+// encodeRowHeader encodes a row header for sources with a format version of 1 or less.
 func encodeRowHeader(id, size uint64) []byte {
 	return append(encodeUint64(id), encodeUvarint(size)...)
 }
 
+// encodeTableRowHeader encodes a row header, including the row's table number, for
+// sources with a format version of 2 or greater.
+func encodeTableRowHeader(id, table, size uint64) []byte {
+	rowHeader := append(encodeUint64(id), encodeUvarint(table)...)
+	return append(rowHeader, encodeUvarint(size)...)
+}
+
 // encodeToBinary writes a given value to an io.Writer in simpledb's binary encoding scheme.
 // It returns the number of bytes written to w and any error encountered while encoding or writing.
 //
@@ -53,38 +65,63 @@ func encodeRowHeader(id, size uint64) []byte {
 //   - uint8 (byte), uint16, uint32, uint64
 //   - int8, int16, int32, int64
 //   - float32, float64, complex64, complex128
-//   - slices of any of these types
 //   - string
+//   - time.Time
+//   - types implementing encoding.BinaryMarshaler and encoding.BinaryUnmarshaler
+//   - structs whose exported fields are all of encodeable types
+//   - slices and arrays of any of these types
+//   - maps whose keys and values are of any of these types
+//   - pointers to any of these types
 //
 // If the value is a fixed size type (either [n]byte or a sized numerical type), then the
 // value is encoded in binary in BigEndian format. If the value is a variable-size type, it is
 // encoded as the concatenation of an unsigned length varint and the serialized bytes of the value.
+// Structs are encoded as the concatenation of their exported fields, in alphabetical order of
+// field name, just like the rows of a DB. Maps are encoded like slices of their entries, each
+// entry being the key followed by the value, sorted by key. Pointers are encoded as a presence
+// byte, which is 0 for nil pointers, or 1 followed by the encoding of the value pointed to.
+// A time.Time is encoded as the seconds and nanoseconds since the Unix epoch (see encodeTimeToBinary).
+// Any other type which implements encoding.BinaryMarshaler, but would not otherwise be a valid column,
+// is encoded as the output of its MarshalBinary method, prefixed with its length (see isBinaryMarshalerType).
 func encodeToBinary(w io.Writer, fieldValue reflect.Value) (int, error) {
+	fieldType := fieldValue.Type()
+	if fieldType == timeType {
+		return encodeTimeToBinary(w, fieldValue)
+	} else if isBinaryMarshalerType(fieldType) {
+		return encodeBinaryMarshalerToBinary(w, fieldValue)
+	}
+
+	switch fieldType.Kind() {
+	case reflect.Struct:
+		return encodeStructToBinary(w, fieldValue)
+	case reflect.Map:
+		return encodeMapToBinary(w, fieldValue)
+	case reflect.Ptr:
+		return encodePointerToBinary(w, fieldValue)
+	}
+
 	buf := new(bytes.Buffer)
 	fieldValueInterface := fieldValue.Interface()
-	fieldType := fieldValue.Type()
 
 	if isVariableSizeType(fieldType) {
 		// encoding/binary can't handle UTF8 strings
-		if fieldAsString, ok := fieldValueInterface.(string); ok {
-			fieldValueInterface = []byte(fieldAsString)
+		if fieldType.Kind() == reflect.String {
+			fieldValueInterface = []byte(fieldValue.String())
 		}
 
-		fieldValueLength := fieldValue.Len()
-
-		if _, err := buf.Write(encodeUvarint(uint64(fieldValueLength))); err != nil {
+		if _, err := buf.Write(encodeUvarint(uint64(fieldValue.Len()))); err != nil {
 			return 0, err
 		}
+	}
 
-		if needsRecursiveEncoding(fieldType) {
-			for i := 0; i < fieldValueLength; i++ {
-				if _, err := encodeToBinary(buf, fieldValue.Index(i)); err != nil {
-					return 0, err
-				}
+	if needsRecursiveEncoding(fieldType) {
+		for i := 0; i < fieldValue.Len(); i++ {
+			if _, err := encodeToBinary(buf, fieldValue.Index(i)); err != nil {
+				return 0, err
 			}
-			bytesWritten, err := buf.WriteTo(w)
-			return int(bytesWritten), err
 		}
+		bytesWritten, err := buf.WriteTo(w)
+		return int(bytesWritten), err
 	}
 
 	if err := binary.Write(buf, binary.BigEndian, fieldValueInterface); err != nil {
@@ -99,16 +136,83 @@ func encodeToBinary(w io.Writer, fieldValue reflect.Value) (int, error) {
 // Only exported fields are encoded.
 func encodeStructToBinary(w io.Writer, value reflect.Value) (int, error) {
 	value = reflect.Indirect(value)
-	bytesWritten := 0
-	for _, fieldName := range getExportedFieldNames(value.Type()) {
-		n, err := encodeToBinary(w, value.FieldByName(fieldName))
-		bytesWritten += n
-		if err != nil {
-			return bytesWritten, err
+	return getStructPlan(value.Type()).encode(w, value)
+}
+
+// encodePointerToBinary encodes a pointer value as a presence byte, followed by
+// the value pointed to if the pointer is not nil.
+func encodePointerToBinary(w io.Writer, pointerValue reflect.Value) (int, error) {
+	if pointerValue.IsNil() {
+		return w.Write([]byte{0})
+	}
+
+	if _, err := w.Write([]byte{1}); err != nil {
+		return 0, err
+	}
+	bytesWritten, err := encodeToBinary(w, pointerValue.Elem())
+	return bytesWritten + 1, err
+}
+
+// encodeMapToBinary encodes a map value as an unsigned varint number of entries, followed by the
+// encoded key and value of each entry, in the order given by encodeMapEntries.
+func encodeMapToBinary(w io.Writer, mapValue reflect.Value) (int, error) {
+	entries, err := encodeMapEntries(mapValue, func(buf *bytes.Buffer, v reflect.Value) error {
+		_, err := encodeToBinary(buf, v)
+		return err
+	})
+	if err != nil {
+		return 0, err
+	}
+
+	buf := new(bytes.Buffer)
+	buf.Write(encodeUvarint(uint64(len(entries))))
+	for _, entry := range entries {
+		buf.Write(entry)
+	}
+
+	bytesWritten, err := buf.WriteTo(w)
+	return int(bytesWritten), err
+}
+
+// encodeMapEntries encodes the key and value of each entry in a map with the given function. The encoded
+// entries are sorted by key if the map's key type can be ordered (see isOrderedColumnType), and otherwise
+// by their encoding, so that the same map is always encoded the same way.
+func encodeMapEntries(mapValue reflect.Value, encode func(*bytes.Buffer, reflect.Value) error) ([][]byte, error) {
+	// MapRange is used rather than MapIndex, which cannot look up NaN keys.
+	var keys []reflect.Value
+	var encoded [][]byte
+	for iter := mapValue.MapRange(); iter.Next(); {
+		buf := new(bytes.Buffer)
+		if err := encode(buf, iter.Key()); err != nil {
+			return nil, err
+		}
+		if err := encode(buf, iter.Value()); err != nil {
+			return nil, err
 		}
+		keys = append(keys, iter.Key())
+		encoded = append(encoded, buf.Bytes())
 	}
 
-	return bytesWritten, nil
+	ordered := isOrderedColumnType(mapValue.Type().Key())
+	order := make([]int, len(keys))
+	for i := range order {
+		order[i] = i
+	}
+	sort.Slice(order, func(i, j int) bool {
+		a, b := order[i], order[j]
+		if ordered {
+			if result := compareIndexKeys(keys[a], keys[b]); result != 0 {
+				return result < 0
+			}
+		}
+		return bytes.Compare(encoded[a], encoded[b]) < 0
+	})
+
+	entries := make([][]byte, len(keys))
+	for i, j := range order {
+		entries[i] = encoded[j]
+	}
+	return entries, nil
 }
 
 // decodeFromBinary reads values from an io.Reader to populate a given
@@ -118,16 +222,30 @@ func encodeStructToBinary(w io.Writer, value reflect.Value) (int, error) {
 func decodeFromBinary(r io.Reader, fieldValue reflect.Value) (int, error) {
 	fieldValue = reflect.Indirect(fieldValue)
 	fieldType := fieldValue.Type()
+	if fieldType == timeType {
+		return decodeTimeFromBinary(r, fieldValue)
+	} else if isBinaryMarshalerType(fieldType) {
+		return decodeBinaryMarshalerFromBinary(r, fieldValue)
+	}
+
+	switch fieldType.Kind() {
+	case reflect.Struct:
+		return decodeStructFromBinary(r, fieldValue)
+	case reflect.Map:
+		return decodeMapFromBinary(r, fieldValue)
+	case reflect.Ptr:
+		return decodePointerFromBinary(r, fieldValue)
+	}
 
 	// Short circuit for decoding strings: just decode as a byte-slice and convert the result to a string
 	if fieldType.Kind() == reflect.String {
 		var data []byte
 		bytesRead, err := decodeFromBinary(r, reflect.ValueOf(&data))
-		fieldValue.Set(reflect.ValueOf(string(data)))
+		fieldValue.Set(reflect.ValueOf(string(data)).Convert(fieldType))
 		return bytesRead, err
 	}
 
-	byteReader := &wrappedByteReader{r}
+	byteReader := &wrappedByteReader{Reader: r}
 
 	bytesRead := 0
 
@@ -140,17 +258,17 @@ func decodeFromBinary(r io.Reader, fieldValue reflect.Value) (int, error) {
 
 		fieldValueLength := int(length)
 		fieldValue.Set(reflect.MakeSlice(fieldType, fieldValueLength, fieldValueLength))
+	}
 
-		if needsRecursiveEncoding(fieldType) {
-			for i := 0; i < fieldValueLength; i++ {
-				n, err := decodeFromBinary(byteReader, fieldValue.Index(i))
-				bytesRead += n
-				if err != nil {
-					return bytesRead, err
-				}
+	if needsRecursiveEncoding(fieldType) {
+		for i := 0; i < fieldValue.Len(); i++ {
+			n, err := decodeFromBinary(byteReader, fieldValue.Index(i).Addr())
+			bytesRead += n
+			if err != nil {
+				return bytesRead, err
 			}
-			return bytesRead, nil
 		}
+		return bytesRead, nil
 	}
 
 	if err := binary.Read(byteReader, binary.BigEndian, fieldValue.Addr().Interface()); err != nil {
@@ -161,21 +279,66 @@ func decodeFromBinary(r io.Reader, fieldValue reflect.Value) (int, error) {
 	return bytesRead, nil
 }
 
-// decodeStructFromBinary decodes binary data and unmarshals it
-// into the given struct value pointer using simpledb encoding.
-// Only exported fields are decoded & populated.
-func decodeStructFromBinary(r io.Reader, value reflect.Value) (int, error) {
-	value = reflect.Indirect(value)
+// decodePointerFromBinary decodes a pointer encoded by encodePointerToBinary into the given pointer value,
+// which is set to nil, or to a newly allocated value.
+func decodePointerFromBinary(r io.Reader, pointerValue reflect.Value) (int, error) {
+	presence := make([]byte, 1)
+	if _, err := io.ReadFull(r, presence); err != nil {
+		return 0, err
+	}
 
-	bytesRead := 0
-	for _, fieldName := range getExportedFieldNames(value.Type()) {
-		fieldValue := value.FieldByName(fieldName)
-		n, err := decodeFromBinary(r, fieldValue)
+	switch presence[0] {
+	case 0:
+		pointerValue.Set(reflect.Zero(pointerValue.Type()))
+		return 1, nil
+	case 1:
+		value := reflect.New(pointerValue.Type().Elem())
+		bytesRead, err := decodeFromBinary(r, value)
+		pointerValue.Set(value)
+		return bytesRead + 1, err
+	}
+
+	return 1, fmt.Errorf("invalid pointer presence byte %#x", presence[0])
+}
+
+// decodeMapFromBinary decodes a map encoded by encodeMapToBinary into the given map value.
+func decodeMapFromBinary(r io.Reader, mapValue reflect.Value) (int, error) {
+	byteReader := &wrappedByteReader{Reader: r}
+
+	length, err := binary.ReadUvarint(byteReader)
+	if err != nil {
+		return 0, err
+	}
+	bytesRead := len(encodeUvarint(length))
+
+	mapType := mapValue.Type()
+	mapValue.Set(reflect.MakeMapWithSize(mapType, int(length)))
+
+	for i := uint64(0); i < length; i++ {
+		key := reflect.New(mapType.Key())
+		n, err := decodeFromBinary(byteReader, key)
+		bytesRead += n
+		if err != nil {
+			return bytesRead, err
+		}
+
+		value := reflect.New(mapType.Elem())
+		n, err = decodeFromBinary(byteReader, value)
 		bytesRead += n
 		if err != nil {
 			return bytesRead, err
 		}
+
+		mapValue.SetMapIndex(key.Elem(), value.Elem())
 	}
 
 	return bytesRead, nil
 }
+
+// decodeStructFromBinary decodes binary data and unmarshals it
+// into the given struct value pointer using simpledb encoding.
+// Only exported fields are decoded & populated.
+func decodeStructFromBinary(r io.Reader, value reflect.Value) (int, error) {
+	value = reflect.Indirect(value)
+	return getStructPlan(value.Type()).decode(r, value)
+}
@@ -134,3 +134,71 @@ func BenchmarkDecodeStructFromBinary(b *testing.B) {
 		}
 	}
 }
+
+// benchmarkRow is a typical row struct, used to benchmark encoding and decoding with a tableSchema.
+type benchmarkRow struct {
+	ID       uint64 `simpledb:"unique"`
+	Name     string `simpledb:"indexed"`
+	Age      uint16
+	Balance  float64
+	Active   bool
+	Scores   [4]int32
+	Tags     []string
+	Data     []byte
+	Location struct {
+		Lat, Long float64
+	}
+}
+
+func newBenchmarkSchema(b *testing.B) (*tableSchema, *benchmarkRow) {
+	schema := new(tableSchema)
+	if err := schema.Reflect(benchmarkRow{}); err != nil {
+		b.Fatalf("failed to reflect schema: %s", err)
+	}
+
+	row := &benchmarkRow{
+		ID:      0x1234567890,
+		Name:    "George Washington",
+		Age:     67,
+		Balance: 1234.5,
+		Active:  true,
+		Scores:  [4]int32{1, -2, 3, -4},
+		Tags:    []string{"president", "general"},
+		Data:    []byte("some secret data"),
+	}
+	row.Location.Lat = 38.7
+	row.Location.Long = -77.1
+	return schema, row
+}
+
+func BenchmarkSchemaEncode(b *testing.B) {
+	schema, row := newBenchmarkSchema(b)
+	buf := new(bytes.Buffer)
+
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		buf.Reset()
+		if _, err := schema.Encode(buf, row); err != nil {
+			b.Fatalf("failed to encode row: %s", err)
+		}
+	}
+}
+
+func BenchmarkSchemaDecode(b *testing.B) {
+	schema, row := newBenchmarkSchema(b)
+	buf := new(bytes.Buffer)
+	if _, err := schema.Encode(buf, row); err != nil {
+		b.Fatalf("failed to encode row: %s", err)
+	}
+	encoded := buf.Bytes()
+
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		var decoded benchmarkRow
+		if _, err := schema.Decode(bytes.NewReader(encoded), &decoded); err != nil {
+			b.Fatalf("failed to decode row: %s", err)
+		}
+	}
+}
@@ -0,0 +1,113 @@
+package simpledb
+
+import (
+	"bytes"
+	"encoding"
+	"encoding/binary"
+	"errors"
+	"io"
+	"reflect"
+	"sync"
+)
+
+var (
+	binaryMarshalerType   = reflect.TypeOf((*encoding.BinaryMarshaler)(nil)).Elem()
+	binaryUnmarshalerType = reflect.TypeOf((*encoding.BinaryUnmarshaler)(nil)).Elem()
+)
+
+// errHeaderlessBinaryMarshaler is returned when opening a DB source without a file header with a table
+// whose columns are encoded with MarshalBinary. Such sources were written before these columns existed,
+// when the same types were encoded as empty structs or were not valid columns at all.
+var errHeaderlessBinaryMarshaler = errors.New("DB source without a file header cannot store columns encoded with MarshalBinary")
+
+// binaryMarshalerTypes caches the result of isBinaryMarshalerType for each type implementing the interfaces.
+var binaryMarshalerTypes sync.Map
+
+// isBinaryMarshalerType returns true if values of type t encode themselves, because t implements
+// encoding.BinaryMarshaler and *t implements encoding.BinaryUnmarshaler. Methods with either value
+// or pointer receivers are accepted. Pointer types are not included, as they are encoded with a
+// presence byte before the value they point to, and neither is time.Time, which has its own encoding.
+// Types whose kind already has an encoding, such as a named [16]byte, keep it (see hasPlainEncoding).
+func isBinaryMarshalerType(t reflect.Type) bool {
+	if t.Kind() == reflect.Ptr || t == timeType {
+		return false
+	}
+	ptrType := reflect.PtrTo(t)
+	if !ptrType.Implements(binaryMarshalerType) || !ptrType.Implements(binaryUnmarshalerType) {
+		return false
+	}
+
+	if result, ok := binaryMarshalerTypes.Load(t); ok {
+		return result.(bool)
+	}
+	result := !hasPlainEncoding(t)
+	binaryMarshalerTypes.Store(t, result)
+	return result
+}
+
+// usesBinaryMarshaler returns true if t, or any type nested inside it, satisfies isBinaryMarshalerType.
+// t must be a valid column type.
+func usesBinaryMarshaler(t reflect.Type) bool {
+	if isBinaryMarshalerType(t) {
+		return true
+	}
+
+	switch t.Kind() {
+	case reflect.Slice, reflect.Array, reflect.Ptr:
+		return usesBinaryMarshaler(t.Elem())
+	case reflect.Map:
+		return usesBinaryMarshaler(t.Key()) || usesBinaryMarshaler(t.Elem())
+	case reflect.Struct:
+		for _, field := range getExportedFields(t) {
+			if usesBinaryMarshaler(field.Type) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// marshalBinaryValue calls the MarshalBinary method of a value whose type satisfies isBinaryMarshalerType.
+func marshalBinaryValue(value reflect.Value) ([]byte, error) {
+	if !value.CanAddr() {
+		// MarshalBinary may have a pointer receiver
+		addressable := reflect.New(value.Type()).Elem()
+		addressable.Set(value)
+		value = addressable
+	}
+	return value.Addr().Interface().(encoding.BinaryMarshaler).MarshalBinary()
+}
+
+// encodeBinaryMarshalerToBinary encodes a value whose type satisfies isBinaryMarshalerType
+// as the output of its MarshalBinary method, prefixed with its length as a uvarint.
+func encodeBinaryMarshalerToBinary(w io.Writer, value reflect.Value) (int, error) {
+	data, err := marshalBinaryValue(value)
+	if err != nil {
+		return 0, err
+	}
+
+	buf := bytes.NewBuffer(encodeUvarint(uint64(len(data))))
+	buf.Write(data)
+	bytesWritten, err := buf.WriteTo(w)
+	return int(bytesWritten), err
+}
+
+// decodeBinaryMarshalerFromBinary decodes a value encoded by encodeBinaryMarshalerToBinary
+// into the given value, using its UnmarshalBinary method.
+func decodeBinaryMarshalerFromBinary(r io.Reader, value reflect.Value) (int, error) {
+	length, err := binary.ReadUvarint(&wrappedByteReader{Reader: r})
+	if err != nil {
+		return 0, err
+	}
+	bytesRead := len(encodeUvarint(length))
+
+	data := make([]byte, length)
+	n, err := io.ReadFull(r, data)
+	bytesRead += n
+	if err != nil {
+		return bytesRead, err
+	}
+
+	unmarshaler := value.Addr().Interface().(encoding.BinaryUnmarshaler)
+	return bytesRead, unmarshaler.UnmarshalBinary(data)
+}
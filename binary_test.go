@@ -5,14 +5,59 @@ import (
 	"fmt"
 	"reflect"
 	"testing"
+	"time"
 )
 
+// testUUID implements encoding.BinaryMarshaler with a value receiver, but is encoded as an array.
+type testUUID [4]byte
+
+func (id testUUID) MarshalBinary() ([]byte, error) {
+	return id[:], nil
+}
+
+func (id *testUUID) UnmarshalBinary(data []byte) error {
+	if len(data) != len(id) {
+		return fmt.Errorf("invalid UUID length %d", len(data))
+	}
+	copy(id[:], data)
+	return nil
+}
+
+// testAmount implements encoding.BinaryMarshaler with a pointer receiver, and has no exported fields.
+type testAmount struct {
+	cents int64
+}
+
+func (amount *testAmount) MarshalBinary() ([]byte, error) {
+	return []byte(fmt.Sprintf("%d.%02d", amount.cents/100, amount.cents%100)), nil
+}
+
+func (amount *testAmount) UnmarshalBinary(data []byte) error {
+	var units, cents int64
+	if _, err := fmt.Sscanf(string(data), "%d.%d", &units, &cents); err != nil {
+		return err
+	}
+	amount.cents = units*100 + cents
+	return nil
+}
+
 func TestBinaryEncoding(t *testing.T) {
+	type Address struct {
+		Zip    uint16
+		City   string
+		hidden bool
+	}
+
+	type Model string
+
 	type Fixture struct {
 		inputValue  interface{}
 		expectedHex string
 	}
 
+	zip := uint16(0xabcd)
+	city := "foo"
+
 	fixtures := []Fixture{
 		{
 			int32(-1),
@@ -75,6 +120,122 @@ func TestBinaryEncoding(t *testing.T) {
 			},
 			"020203666f6f0362617202000568656c6c6f",
 		},
+		{
+			[2]string{"foo", ""},
+			"03666f6f00",
+		},
+		{
+			Address{Zip: 0xabcd, City: "foo"},
+			"03666f6fabcd", // fields in alphabetical order
+		},
+		{
+			[]Address{
+				{Zip: 1, City: "a"},
+				{Zip: 2, City: ""},
+			},
+			"0201610001000002",
+		},
+		{
+			[1]Address{{Zip: 1, City: "a"}},
+			"01610001",
+		},
+		{
+			map[string]uint8{"b": 2, "aa": 1},
+			"0202616101016202", // sorted by key
+		},
+		{
+			map[int8][]string{-1: {"x"}, 0: {}},
+			"02ff0101780000",
+		},
+		{
+			map[complex64]bool{1: true, 0: false},
+			"020000000000000000003f8000000000000001", // sorted by encoding
+		},
+		{
+			struct {
+				Home  Address
+				Other []Address
+			}{Home: Address{Zip: 1, City: "a"}, Other: []Address{}},
+			"0161000100",
+		},
+		{
+			&zip,
+			"01abcd", // presence byte
+		},
+		{
+			(*uint16)(nil),
+			"00",
+		},
+		{
+			struct {
+				City *string
+				Home *Address
+				Zip  *uint16
+			}{City: &city, Zip: &zip},
+			"0103666f6f0001abcd",
+		},
+		{
+			[]*Address{{Zip: 1, City: "a"}, nil},
+			"020101610001" + "00",
+		},
+		{
+			map[string]*uint16{"a": &zip, "b": nil},
+			"02016101abcd016200",
+		},
+		{
+			time.Unix(0x12345678, 999999999).UTC(),
+			"00000000123456783b9ac9ff", // seconds + nanoseconds
+		},
+		{
+			time.Time{},
+			"fffffff1886e090000000000",
+		},
+		{
+			[]time.Duration{time.Second, -1},
+			"02000000003b9aca00ffffffffffffffff",
+		},
+		{
+			testUUID{1, 2, 3, 4},
+			"01020304", // encoded as a plain array, not with MarshalBinary
+		},
+		{
+			testAmount{cents: 1234},
+			"0531322e3334", // "12.34"
+		},
+		{
+			struct {
+				Amounts []testAmount
+				ID      *testUUID
+			}{Amounts: []testAmount{{cents: 5}}},
+			"0104302e303500",
+		},
+		{
+			struct {
+				B      bool
+				Bytes  []byte
+				C128   complex128
+				C64    complex64
+				F32    float32
+				F64    float64
+				Flags  []bool
+				Floats []float32
+				I16    int16
+				I64    int64
+				I8     int8
+				Ints   [2]int16
+				Model  Model
+				Name   string
+				U64    uint64
+				U8     uint8
+			}{
+				B: true, Bytes: []byte{0xab}, C128: complex(1, -2), C64: complex(0.5, 0), F32: -1.5, F64: 2,
+				Flags: []bool{false, true}, Floats: []float32{1}, I16: -2, I64: -3, I8: -4, Ints: [2]int16{1, -1},
+				Model: "x", Name: "hé", U64: 1 << 63, U8: 0xff,
+			},
+			"01" + "01ab" + "3ff0000000000000c000000000000000" + "3f00000000000000" + "bfc00000" + "4000000000000000" +
+				"020001" + "013f800000" + "fffe" + "fffffffffffffffd" + "fc" + "0001ffff" + "0178" + "0368c3a9" +
+				"8000000000000000" + "ff",
+		},
 	}
 
 	for _, fixture := range fixtures {
@@ -98,7 +259,7 @@ func TestBinaryEncoding(t *testing.T) {
 		}
 
 		decodedValue := reflect.Indirect(reflect.New(reflect.TypeOf(fixture.inputValue)))
-		bytesRead, err := decodeFromBinary(buf, decodedValue)
+		bytesRead, err := decodeFromBinary(buf, decodedValue.Addr())
 		if err != nil {
 			t.Errorf("Failed to decode fixture from binary: %s", err)
 			continue
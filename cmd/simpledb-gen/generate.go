@@ -0,0 +1,738 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"go/format"
+	"go/token"
+	"go/types"
+	"sort"
+	"strings"
+)
+
+// generator writes the EncodeSimpleDB and DecodeSimpleDB methods of struct types in a package.
+// The code it writes mirrors the reflection-based encoding in simpledb's binary.go, and must
+// produce exactly the same bytes.
+type generator struct {
+	pkg     *types.Package
+	methods bytes.Buffer
+
+	// imports maps the path of each package used by the generated code to its name.
+	imports map[string]string
+
+	// body holds the statements of the method being generated, and vars counts the local
+	// variables declared in it, so that each can be given a unique name.
+	body       *bytes.Buffer
+	vars       int
+	useScratch bool
+}
+
+func newGenerator(pkg *types.Package) *generator {
+	return &generator{
+		pkg:     pkg,
+		imports: make(map[string]string),
+	}
+}
+
+// column is an exported field of a struct type, which is encoded as a column.
+type column struct {
+	name string
+	typ  types.Type
+}
+
+func (g *generator) printf(format string, args ...interface{}) {
+	fmt.Fprintf(g.body, format, args...)
+}
+
+// newVar returns a unique name for a local variable.
+func (g *generator) newVar(name string) string {
+	g.vars++
+	return fmt.Sprintf("%s%d", name, g.vars)
+}
+
+// use records that the generated code uses the standard library package with the given path.
+func (g *generator) use(path string) {
+	g.imports[path] = path[strings.LastIndex(path, "/")+1:]
+}
+
+// typeString returns the name of t in the generated code, importing the packages it refers to.
+func (g *generator) typeString(t types.Type) string {
+	return types.TypeString(t, func(p *types.Package) string {
+		if p == g.pkg {
+			return ""
+		}
+		g.imports[p.Path()] = p.Name()
+		return p.Name()
+	})
+}
+
+// convert returns expr converted to type t, where expr is of the basic type with the given kind.
+func (g *generator) convert(t types.Type, kind types.BasicKind, expr string) string {
+	if types.Identical(t, types.Typ[kind]) {
+		return expr
+	}
+	return g.typeString(t) + "(" + expr + ")"
+}
+
+// generate writes the methods of the given named struct type.
+func (g *generator) generate(named *types.Named) error {
+	st, ok := named.Underlying().(*types.Struct)
+	if !ok {
+		return fmt.Errorf("not a struct type")
+	}
+	if err := validate(named, nil); err != nil {
+		return err
+	}
+
+	columns, err := structColumns(st, g.pkg)
+	if err != nil {
+		return err
+	}
+	name := named.Obj().Name()
+
+	g.body, g.vars, g.useScratch = new(bytes.Buffer), 0, false
+	for _, c := range columns {
+		if err := g.encode("buf", "v."+c.name, c.typ); err != nil {
+			return err
+		}
+	}
+
+	fmt.Fprintf(&g.methods, "\n// EncodeSimpleDB appends the simpledb encoding of v to buf, without using reflection.\n")
+	fmt.Fprintf(&g.methods, "func (v %s) EncodeSimpleDB(buf []byte) ([]byte, error) {\n", name)
+	if g.useScratch {
+		g.use("encoding/binary")
+		fmt.Fprintf(&g.methods, "var scratch [binary.MaxVarintLen64]byte\n")
+	}
+	g.body.WriteTo(&g.methods)
+	fmt.Fprintf(&g.methods, "return buf, nil\n}\n")
+
+	g.body, g.vars = new(bytes.Buffer), 0
+	for _, c := range columns {
+		if err := g.decode("v."+c.name, c.typ); err != nil {
+			return err
+		}
+	}
+
+	fmt.Fprintf(&g.methods, "\n// DecodeSimpleDB decodes the simpledb encoding of a %s from the start of data into v,\n", name)
+	fmt.Fprintf(&g.methods, "// returning the number of bytes read.\n")
+	fmt.Fprintf(&g.methods, "func (v *%s) DecodeSimpleDB(data []byte) (int, error) {\n", name)
+	fmt.Fprintf(&g.methods, "n := 0\n")
+	g.body.WriteTo(&g.methods)
+	fmt.Fprintf(&g.methods, "return n, nil\n}\n")
+
+	return nil
+}
+
+// file returns the formatted source of the generated file.
+func (g *generator) file(args string) ([]byte, error) {
+	src := new(bytes.Buffer)
+	fmt.Fprintf(src, "// Code generated by \"simpledb-gen %s\"; DO NOT EDIT.\n\n", args)
+	fmt.Fprintf(src, "package %s\n\n", g.pkg.Name())
+
+	paths := make([]string, 0, len(g.imports))
+	for path := range g.imports {
+		paths = append(paths, path)
+	}
+	sort.Strings(paths)
+
+	fmt.Fprintf(src, "import (\n")
+	for _, path := range paths {
+		if name := g.imports[path]; name != path[strings.LastIndex(path, "/")+1:] {
+			fmt.Fprintf(src, "%s %q\n", name, path)
+		} else {
+			fmt.Fprintf(src, "%q\n", path)
+		}
+	}
+	fmt.Fprintf(src, ")\n")
+	g.methods.WriteTo(src)
+
+	return format.Source(src.Bytes())
+}
+
+// isTime returns true if t is time.Time, which is encoded as an instant in time.
+func isTime(t types.Type) bool {
+	named, ok := t.(*types.Named)
+	return ok && named.Obj().Pkg() != nil && named.Obj().Pkg().Path() == "time" && named.Obj().Name() == "Time"
+}
+
+var binaryMarshaler = func() *types.Interface {
+	byteSlice := types.NewSlice(types.Typ[types.Byte])
+	errorType := types.Universe.Lookup("error").Type()
+	result := func(t types.Type) *types.Var {
+		return types.NewVar(token.NoPos, nil, "", t)
+	}
+
+	marshal := types.NewFunc(token.NoPos, nil, "MarshalBinary", types.NewSignature(nil,
+		nil, types.NewTuple(result(byteSlice), result(errorType)), false))
+	unmarshal := types.NewFunc(token.NoPos, nil, "UnmarshalBinary", types.NewSignature(nil,
+		types.NewTuple(result(byteSlice)), types.NewTuple(result(errorType)), false))
+	return types.NewInterfaceType([]*types.Func{marshal, unmarshal}, nil).Complete()
+}()
+
+// isBinaryMarshaler returns true if t is encoded with its MarshalBinary and UnmarshalBinary
+// methods, like simpledb's isBinaryMarshalerType. Types which would be valid columns without
+// those methods keep the encoding of their kind, like simpledb's hasPlainEncoding.
+func isBinaryMarshaler(t types.Type) bool {
+	if _, ok := t.Underlying().(*types.Pointer); ok || isTime(t) {
+		return false
+	}
+	return types.Implements(types.NewPointer(t), binaryMarshaler) && validateColumn(t, nil, false) != nil
+}
+
+// isOrdered returns true if values of type t are ordered by value, rather than by their encoding,
+// when they are the keys of a map, like simpledb's isOrderedColumnType.
+func isOrdered(t types.Type) bool {
+	if isTime(t) {
+		return true
+	}
+	switch u := t.Underlying().(type) {
+	case *types.Basic:
+		return u.Info()&(types.IsInteger|types.IsFloat|types.IsString|types.IsBoolean) != 0
+	case *types.Array:
+		return isOrdered(u.Elem())
+	case *types.Slice:
+		return isOrdered(u.Elem())
+	}
+	return false
+}
+
+// basicSizes holds the encoded size of each fixed-size basic type.
+var basicSizes = map[types.BasicKind]int{
+	types.Bool:       1,
+	types.Int8:       1,
+	types.Uint8:      1,
+	types.Int16:      2,
+	types.Uint16:     2,
+	types.Int32:      4,
+	types.Uint32:     4,
+	types.Float32:    4,
+	types.Int64:      8,
+	types.Uint64:     8,
+	types.Float64:    8,
+	types.Complex64:  8,
+	types.Complex128: 16,
+}
+
+// validate returns an error if t is not a valid simpledb column type when nested inside the given
+// struct types, like simpledb's isValidNestedColumnType.
+func validate(t types.Type, parents []types.Type) error {
+	return validateColumn(t, parents, true)
+}
+
+// validateColumn is like validate, but if marshalers is false, types encoded with their MarshalBinary
+// method and structs without any columns are not valid, like simpledb's isValidColumnKind.
+func validateColumn(t types.Type, parents []types.Type, marshalers bool) error {
+	if isTime(t) || (marshalers && isBinaryMarshaler(t)) {
+		return nil
+	}
+
+	switch u := t.Underlying().(type) {
+	case *types.Basic:
+		if _, ok := basicSizes[u.Kind()]; ok || u.Kind() == types.String {
+			return nil
+		}
+	case *types.Array:
+		return validateColumn(u.Elem(), parents, marshalers)
+	case *types.Slice:
+		return validateColumn(u.Elem(), parents, marshalers)
+	case *types.Pointer:
+		return validateColumn(u.Elem(), parents, marshalers)
+	case *types.Map:
+		if err := validateColumn(u.Key(), parents, marshalers); err != nil {
+			return err
+		}
+		return validateColumn(u.Elem(), parents, marshalers)
+	case *types.Struct:
+		for _, parent := range parents {
+			if types.Identical(parent, t) {
+				return fmt.Errorf("recursive struct type %s is not a valid column type", t)
+			}
+		}
+		columns, err := structColumns(u, nil)
+		if err != nil {
+			return err
+		} else if !marshalers && len(columns) == 0 {
+			return fmt.Errorf("struct type %s has no columns", t)
+		}
+		for _, c := range columns {
+			if err := validateColumn(c.typ, append(parents, t), marshalers); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+
+	return fmt.Errorf("%s is not a valid column type", t)
+}
+
+// structColumns returns the columns of a struct type, sorted by name, like simpledb's getExportedFields.
+// Fields promoted from an exported embedded struct are not included, as they are encoded as part of
+// the embedded struct, but fields promoted from an unexported embedded struct are.
+func structColumns(st *types.Struct, pkg *types.Package) ([]column, error) {
+	var names []string
+	seen := make(map[string]bool)
+	visited := make(map[*types.Struct]bool)
+	var collect func(st *types.Struct)
+	collect = func(st *types.Struct) {
+		visited[st] = true
+		for i := 0; i < st.NumFields(); i++ {
+			field := st.Field(i)
+			if field.Exported() {
+				if !seen[field.Name()] {
+					seen[field.Name()] = true
+					names = append(names, field.Name())
+				}
+				continue
+			}
+
+			// collect the fields promoted from unexported embedded structs
+			embeddedType := field.Type()
+			if pointer, ok := embeddedType.Underlying().(*types.Pointer); ok {
+				embeddedType = pointer.Elem()
+			}
+			if embedded, ok := embeddedType.Underlying().(*types.Struct); ok && field.Embedded() && !visited[embedded] {
+				collect(embedded)
+			}
+		}
+	}
+	collect(st)
+
+	var columns []column
+	for _, name := range names {
+		obj, index, indirect := types.LookupFieldOrMethod(st, false, pkg, name)
+		field, ok := obj.(*types.Var)
+		if !ok {
+			// ambiguous fields are not visible
+			continue
+		} else if indirect {
+			return nil, fmt.Errorf("field %s is promoted through an embedded pointer, which is not supported", name)
+		}
+
+		// skip fields promoted from exported embedded structs
+		parent, promoted := st, false
+		for _, i := range index[:len(index)-1] {
+			embedded := parent.Field(i)
+			if embedded.Exported() {
+				promoted = true
+				break
+			}
+			parent = embedded.Type().Underlying().(*types.Struct)
+		}
+		if !promoted {
+			columns = append(columns, column{name, field.Type()})
+		}
+	}
+
+	sort.Slice(columns, func(i, j int) bool {
+		return columns[i].name < columns[j].name
+	})
+	return columns, nil
+}
+
+// minSize returns the smallest number of bytes a value of type t can be encoded in.
+func minSize(t types.Type) int {
+	if isTime(t) {
+		return 12
+	} else if isBinaryMarshaler(t) {
+		return 1
+	}
+
+	switch u := t.Underlying().(type) {
+	case *types.Basic:
+		if size, ok := basicSizes[u.Kind()]; ok {
+			return size
+		}
+		return 1
+	case *types.Array:
+		return int(u.Len()) * minSize(u.Elem())
+	case *types.Struct:
+		size := 0
+		for i := 0; i < u.NumFields(); i++ {
+			if u.Field(i).Exported() {
+				size += minSize(u.Field(i).Type())
+			}
+		}
+		return size
+	}
+	return 1
+}
+
+func isByte(t types.Type) bool {
+	return types.Identical(t, types.Typ[types.Byte])
+}
+
+// appendUvarint writes a statement which appends the uvarint encoding of n to dst.
+func (g *generator) appendUvarint(dst, n string) {
+	g.useScratch = true
+	g.use("encoding/binary")
+	g.printf("%s = append(%s, scratch[:binary.PutUvarint(scratch[:], uint64(%s))]...)\n", dst, dst, n)
+}
+
+// appendUint writes a statement which appends the big-endian encoding of the unsigned integer n to dst.
+func (g *generator) appendUint(dst, n string, size int) {
+	if size == 1 {
+		g.printf("%s = append(%s, byte(%s))\n", dst, dst, n)
+		return
+	}
+	g.useScratch = true
+	g.use("encoding/binary")
+	g.printf("binary.BigEndian.PutUint%d(scratch[:], uint%d(%s))\n", size*8, size*8, n)
+	g.printf("%s = append(%s, scratch[:%d]...)\n", dst, dst, size)
+}
+
+// encode writes statements which append the encoding of expression x, of type t, to dst.
+// Expression x must be addressable.
+func (g *generator) encode(dst, x string, t types.Type) error {
+	if isTime(t) {
+		g.appendUint(dst, x+".Unix()", 8)
+		g.appendUint(dst, x+".Nanosecond()", 4)
+		return nil
+	}
+
+	if isBinaryMarshaler(t) {
+		data := g.newVar("data")
+		g.printf("%s, err := %s.MarshalBinary()\n", data, x)
+		g.printf("if err != nil {\nreturn nil, err\n}\n")
+		g.appendUvarint(dst, "len("+data+")")
+		g.printf("%s = append(%s, %s...)\n", dst, dst, data)
+		return nil
+	}
+
+	switch u := t.Underlying().(type) {
+	case *types.Basic:
+		return g.encodeBasic(dst, x, t, u)
+
+	case *types.Array:
+		if isByte(u.Elem()) {
+			g.printf("%s = append(%s, %s[:]...)\n", dst, dst, x)
+			return nil
+		}
+		i := g.newVar("i")
+		g.printf("for %s := range %s {\n", i, x)
+		if err := g.encode(dst, x+"["+i+"]", u.Elem()); err != nil {
+			return err
+		}
+		g.printf("}\n")
+		return nil
+
+	case *types.Slice:
+		g.appendUvarint(dst, "len("+x+")")
+		if isByte(u.Elem()) {
+			g.printf("%s = append(%s, %s...)\n", dst, dst, x)
+			return nil
+		}
+		i := g.newVar("i")
+		g.printf("for %s := range %s {\n", i, x)
+		if err := g.encode(dst, x+"["+i+"]", u.Elem()); err != nil {
+			return err
+		}
+		g.printf("}\n")
+		return nil
+
+	case *types.Struct:
+		columns, err := structColumns(u, g.pkg)
+		if err != nil {
+			return err
+		}
+		for _, c := range columns {
+			if err := g.encode(dst, x+"."+c.name, c.typ); err != nil {
+				return err
+			}
+		}
+		return nil
+
+	case *types.Pointer:
+		g.printf("if %s == nil {\n%s = append(%s, 0)\n} else {\n%s = append(%s, 1)\n", x, dst, dst, dst, dst)
+		if err := g.encode(dst, "(*"+x+")", u.Elem()); err != nil {
+			return err
+		}
+		g.printf("}\n")
+		return nil
+
+	case *types.Map:
+		return g.encodeMap(dst, x, u)
+	}
+
+	return fmt.Errorf("%s is not a valid column type", t)
+}
+
+func (g *generator) encodeBasic(dst, x string, t types.Type, u *types.Basic) error {
+	switch u.Kind() {
+	case types.Bool:
+		g.printf("if %s {\n%s = append(%s, 1)\n} else {\n%s = append(%s, 0)\n}\n", x, dst, dst, dst, dst)
+	case types.Int8, types.Uint8, types.Int16, types.Uint16, types.Int32, types.Uint32, types.Int64, types.Uint64:
+		g.appendUint(dst, x, basicSizes[u.Kind()])
+	case types.Float32:
+		g.use("math")
+		g.appendUint(dst, "math.Float32bits(float32("+x+"))", 4)
+	case types.Float64:
+		g.use("math")
+		g.appendUint(dst, "math.Float64bits(float64("+x+"))", 8)
+	case types.Complex64:
+		g.use("math")
+		g.appendUint(dst, "math.Float32bits(real("+x+"))", 4)
+		g.appendUint(dst, "math.Float32bits(imag("+x+"))", 4)
+	case types.Complex128:
+		g.use("math")
+		g.appendUint(dst, "math.Float64bits(real("+x+"))", 8)
+		g.appendUint(dst, "math.Float64bits(imag("+x+"))", 8)
+	case types.String:
+		g.appendUvarint(dst, "len("+x+")")
+		if !types.Identical(t, types.Typ[types.String]) {
+			x = "string(" + x + ")"
+		}
+		g.printf("%s = append(%s, %s...)\n", dst, dst, x)
+	default:
+		return fmt.Errorf("%s is not a valid column type", t)
+	}
+	return nil
+}
+
+// encodeMap writes statements which append the encoding of the map x to dst. Like simpledb's
+// encodeMapEntries, entries are sorted by key if the key type is ordered, and otherwise by their
+// encoding.
+func (g *generator) encodeMap(dst, x string, u *types.Map) error {
+	keys, entries, order := g.newVar("keys"), g.newVar("entries"), g.newVar("order")
+	key, value, entry := g.newVar("key"), g.newVar("value"), g.newVar("entry")
+	ordered := isOrdered(u.Key())
+
+	g.use("bytes")
+	g.use("sort")
+
+	if ordered {
+		g.printf("%s := make([]%s, 0, len(%s))\n", keys, g.typeString(u.Key()), x)
+	}
+	g.printf("%s := make([][]byte, 0, len(%s))\n", entries, x)
+	g.printf("for %s, %s := range %s {\n", key, value, x)
+	g.printf("var %s []byte\n", entry)
+	if err := g.encode(entry, key, u.Key()); err != nil {
+		return err
+	}
+	if err := g.encode(entry, value, u.Elem()); err != nil {
+		return err
+	}
+	if ordered {
+		g.printf("%s = append(%s, %s)\n", keys, keys, key)
+	}
+	g.printf("%s = append(%s, %s)\n", entries, entries, entry)
+	g.printf("}\n")
+
+	g.printf("%s := make([]int, len(%s))\n", order, entries)
+	g.printf("for i := range %s {\n%s[i] = i\n}\n", order, order)
+	g.printf("sort.Slice(%s, func(i, j int) bool {\n", order)
+	g.printf("a, b := %s[i], %s[j]\n", order, order)
+	if ordered {
+		g.compareKeys(keys+"[a]", keys+"[b]", u.Key())
+	}
+	g.printf("return bytes.Compare(%s[a], %s[b]) < 0\n", entries, entries)
+	g.printf("})\n")
+
+	g.appendUvarint(dst, "len("+entries+")")
+	g.printf("for _, i := range %s {\n%s = append(%s, %s[i]...)\n}\n", order, dst, dst, entries)
+	return nil
+}
+
+// compareKeys writes statements which return whether a is ordered before b, if they are not equal,
+// where a and b are of an ordered type. Like simpledb's compareIndexKeys, NaN is ordered before
+// every other number.
+func (g *generator) compareKeys(a, b string, t types.Type) {
+	if isTime(t) {
+		g.printf("if !%s.Equal(%s) {\nreturn %s.Before(%s)\n}\n", a, b, a, b)
+		return
+	}
+
+	switch u := t.Underlying().(type) {
+	case *types.Basic:
+		switch {
+		case u.Info()&types.IsBoolean != 0:
+			g.printf("if %s != %s {\nreturn !%s\n}\n", a, b, a)
+		case u.Info()&types.IsFloat != 0:
+			g.printf("if %s != %s && (%s == %s || %s == %s) {\nreturn %s != %s || %s < %s\n}\n", a, b, a, a, b, b, a, a, a, b)
+		default:
+			g.printf("if %s != %s {\nreturn %s < %s\n}\n", a, b, a, b)
+		}
+	case *types.Array:
+		i := g.newVar("i")
+		g.printf("for %s := range %s {\n", i, a)
+		g.compareKeys(a+"["+i+"]", b+"["+i+"]", u.Elem())
+		g.printf("}\n")
+	}
+}
+
+// need writes a statement which returns io.ErrUnexpectedEOF if fewer than size bytes remain in data.
+func (g *generator) need(size string) {
+	g.use("io")
+	g.printf("if len(data)-n < %s {\nreturn n, io.ErrUnexpectedEOF\n}\n", size)
+}
+
+// readUvarint writes statements which read a uvarint from data into a new variable, returning its name.
+func (g *generator) readUvarint() string {
+	g.use("encoding/binary")
+	g.use("io")
+	length, size := g.newVar("length"), g.newVar("size")
+	g.printf("%s, %s := binary.Uvarint(data[n:])\n", length, size)
+	g.printf("if %s <= 0 {\nreturn n, io.ErrUnexpectedEOF\n}\n", size)
+	g.printf("n += %s\n", size)
+	return length
+}
+
+// readLength writes statements which read the uvarint length of a value whose elements each
+// take at least minSize bytes, and check that data holds enough bytes for them.
+func (g *generator) readLength(minSize int) string {
+	length := g.readUvarint()
+	if minSize > 0 {
+		g.printf("if %s > uint64(len(data)-n)/%d {\nreturn n, io.ErrUnexpectedEOF\n}\n", length, minSize)
+	}
+	return length
+}
+
+// decode writes statements which decode a value of type t from data into the addressable expression x.
+func (g *generator) decode(x string, t types.Type) error {
+	if isTime(t) {
+		g.use("encoding/binary")
+		g.need("12")
+		g.printf("%s = time.Unix(int64(binary.BigEndian.Uint64(data[n:])), int64(binary.BigEndian.Uint32(data[n+8:]))).UTC()\n", x)
+		g.printf("n += 12\n")
+		g.use("time")
+		return nil
+	}
+
+	if isBinaryMarshaler(t) {
+		length := g.readLength(1)
+		g.printf("if err := %s.UnmarshalBinary(data[n : n+int(%s)]); err != nil {\nreturn n, err\n}\n", x, length)
+		g.printf("n += int(%s)\n", length)
+		return nil
+	}
+
+	switch u := t.Underlying().(type) {
+	case *types.Basic:
+		return g.decodeBasic(x, t, u)
+
+	case *types.Array:
+		if isByte(u.Elem()) {
+			g.need(fmt.Sprint(u.Len()))
+			g.printf("copy(%s[:], data[n:])\n", x)
+			g.printf("n += %d\n", u.Len())
+			return nil
+		}
+		i := g.newVar("i")
+		g.printf("for %s := range %s {\n", i, x)
+		if err := g.decode(x+"["+i+"]", u.Elem()); err != nil {
+			return err
+		}
+		g.printf("}\n")
+		return nil
+
+	case *types.Slice:
+		length := g.readLength(minSize(u.Elem()))
+		g.printf("%s = make(%s, %s)\n", x, g.typeString(t), length)
+		if isByte(u.Elem()) {
+			g.printf("n += copy(%s, data[n:])\n", x)
+			return nil
+		}
+		i := g.newVar("i")
+		g.printf("for %s := range %s {\n", i, x)
+		if err := g.decode(x+"["+i+"]", u.Elem()); err != nil {
+			return err
+		}
+		g.printf("}\n")
+		return nil
+
+	case *types.Struct:
+		columns, err := structColumns(u, g.pkg)
+		if err != nil {
+			return err
+		}
+		for _, c := range columns {
+			if err := g.decode(x+"."+c.name, c.typ); err != nil {
+				return err
+			}
+		}
+		return nil
+
+	case *types.Pointer:
+		g.use("fmt")
+		g.need("1")
+		g.printf("switch data[n] {\ncase 0:\nn++\n%s = nil\ncase 1:\nn++\n", x)
+		g.printf("%s = new(%s)\n", x, g.typeString(u.Elem()))
+		if err := g.decode("(*"+x+")", u.Elem()); err != nil {
+			return err
+		}
+		g.printf("default:\nreturn n, fmt.Errorf(\"invalid pointer presence byte %%#x\", data[n])\n}\n")
+		return nil
+
+	case *types.Map:
+		length := g.readLength(minSize(u.Key()) + minSize(u.Elem()))
+		key, value := g.newVar("key"), g.newVar("value")
+		g.printf("%s = make(%s, %s)\n", x, g.typeString(t), length)
+		g.printf("for i := uint64(0); i < %s; i++ {\n", length)
+		g.printf("var %s %s\n", key, g.typeString(u.Key()))
+		g.printf("var %s %s\n", value, g.typeString(u.Elem()))
+		if err := g.decode(key, u.Key()); err != nil {
+			return err
+		}
+		if err := g.decode(value, u.Elem()); err != nil {
+			return err
+		}
+		g.printf("%s[%s] = %s\n", x, key, value)
+		g.printf("}\n")
+		return nil
+	}
+
+	return fmt.Errorf("%s is not a valid column type", t)
+}
+
+func (g *generator) decodeBasic(x string, t types.Type, u *types.Basic) error {
+	readUint := func(size int) string {
+		if size == 1 {
+			return "data[n]"
+		}
+		g.use("encoding/binary")
+		return fmt.Sprintf("binary.BigEndian.Uint%d(data[n:])", size*8)
+	}
+
+	kind := u.Kind()
+	switch kind {
+	case types.Bool:
+		g.need("1")
+		g.printf("%s = %s\n", x, g.convert(t, types.Bool, "data[n] != 0"))
+		g.printf("n++\n")
+	case types.Int8, types.Uint8, types.Int16, types.Uint16, types.Int32, types.Uint32, types.Int64, types.Uint64:
+		size := basicSizes[kind]
+		unsigned := map[int]types.BasicKind{1: types.Uint8, 2: types.Uint16, 4: types.Uint32, 8: types.Uint64}[size]
+		g.need(fmt.Sprint(size))
+		g.printf("%s = %s\n", x, g.convert(t, unsigned, readUint(size)))
+		g.printf("n += %d\n", size)
+	case types.Float32:
+		g.use("math")
+		g.need("4")
+		g.printf("%s = %s\n", x, g.convert(t, types.Float32, "math.Float32frombits("+readUint(4)+")"))
+		g.printf("n += 4\n")
+	case types.Float64:
+		g.use("math")
+		g.need("8")
+		g.printf("%s = %s\n", x, g.convert(t, types.Float64, "math.Float64frombits("+readUint(8)+")"))
+		g.printf("n += 8\n")
+	case types.Complex64:
+		g.use("math")
+		g.use("encoding/binary")
+		g.need("8")
+		g.printf("%s = %s\n", x, g.convert(t, types.Complex64,
+			"complex(math.Float32frombits(binary.BigEndian.Uint32(data[n:])), math.Float32frombits(binary.BigEndian.Uint32(data[n+4:])))"))
+		g.printf("n += 8\n")
+	case types.Complex128:
+		g.use("math")
+		g.use("encoding/binary")
+		g.need("16")
+		g.printf("%s = %s\n", x, g.convert(t, types.Complex128,
+			"complex(math.Float64frombits(binary.BigEndian.Uint64(data[n:])), math.Float64frombits(binary.BigEndian.Uint64(data[n+8:])))"))
+		g.printf("n += 16\n")
+	case types.String:
+		length := g.readLength(1)
+		g.printf("%s = %s(data[n : n+int(%s)])\n", x, g.typeString(t), length)
+		g.printf("n += int(%s)\n", length)
+	default:
+		return fmt.Errorf("%s is not a valid column type", t)
+	}
+	return nil
+}
@@ -0,0 +1,130 @@
+// Command simpledb-gen generates reflection-free encoders for the struct types of simpledb tables.
+//
+// For each struct type given with -type, it writes an EncodeSimpleDB method, which appends the same
+// bytes as simpledb's reflection-based row encoding, and a DecodeSimpleDB method which reverses it.
+// A simpledb.DB whose struct type has both methods uses them to encode and decode its rows, instead
+// of reflection. It is intended to be run with go generate:
+//
+//	//go:generate go run github.com/kklash/simpledb/cmd/simpledb-gen -type Car,Person
+//
+// The methods are written to a file named after the first type, such as car_simpledb.go, in the
+// directory of the package, unless another file is given with -output.
+package main
+
+import (
+	"flag"
+	"fmt"
+	"go/ast"
+	"go/build"
+	"go/importer"
+	"go/parser"
+	"go/token"
+	"go/types"
+	"log"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+func main() {
+	log.SetFlags(0)
+	log.SetPrefix("simpledb-gen: ")
+
+	typeNames := flag.String("type", "", "comma-separated list of struct type names; required")
+	output := flag.String("output", "", "output file name; default <dir>/<type>_simpledb.go")
+	flag.Usage = func() {
+		fmt.Fprintf(os.Stderr, "Usage: simpledb-gen -type T [-output file] [directory]\n")
+		flag.PrintDefaults()
+	}
+	flag.Parse()
+
+	if *typeNames == "" || flag.NArg() > 1 {
+		flag.Usage()
+		os.Exit(2)
+	}
+
+	dir := "."
+	if flag.NArg() == 1 {
+		dir = flag.Arg(0)
+	}
+
+	typeList := strings.Split(*typeNames, ",")
+	outputPath := *output
+	if outputPath == "" {
+		outputPath = filepath.Join(dir, strings.ToLower(typeList[0])+"_simpledb.go")
+	}
+
+	src, err := generateFile(dir, filepath.Base(outputPath), typeList, strings.Join(os.Args[1:], " "))
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	if err := os.WriteFile(outputPath, src, 0644); err != nil {
+		log.Fatal(err)
+	}
+}
+
+// generateFile type-checks the package in dir, ignoring the output file if it already exists, and
+// returns the source of a file declaring the generated methods of the given struct types.
+func generateFile(dir, outputName string, typeNames []string, args string) ([]byte, error) {
+	pkg, err := loadPackage(dir, outputName)
+	if err != nil {
+		return nil, err
+	}
+
+	g := newGenerator(pkg)
+	for _, typeName := range typeNames {
+		obj := pkg.Scope().Lookup(typeName)
+		if obj == nil {
+			return nil, fmt.Errorf("type %s not found in package %s", typeName, pkg.Name())
+		}
+		named, ok := obj.Type().(*types.Named)
+		if !ok {
+			return nil, fmt.Errorf("%s is not a named type", typeName)
+		}
+		if err := g.generate(named); err != nil {
+			return nil, fmt.Errorf("%s: %w", typeName, err)
+		}
+	}
+
+	return g.file(args)
+}
+
+// loadPackage parses and type-checks the non-test Go files of the package in dir,
+// except for the file with the given name.
+func loadPackage(dir, excludeName string) (*types.Package, error) {
+	buildPkg, err := build.ImportDir(dir, 0)
+	if err != nil {
+		return nil, err
+	}
+
+	fset := token.NewFileSet()
+	var files []*ast.File
+	for _, name := range buildPkg.GoFiles {
+		if name == excludeName {
+			continue
+		}
+		file, err := parser.ParseFile(fset, filepath.Join(dir, name), nil, 0)
+		if err != nil {
+			return nil, err
+		}
+		files = append(files, file)
+	}
+
+	// Errors are ignored, so that the package can still be loaded if other files
+	// refer to methods which have not been generated yet.
+	var firstErr error
+	config := types.Config{
+		Importer: importer.ForCompiler(fset, "source", nil),
+		Error: func(err error) {
+			if firstErr == nil {
+				firstErr = err
+			}
+		},
+	}
+	pkg, _ := config.Check(buildPkg.ImportPath, fset, files, nil)
+	if pkg == nil {
+		return nil, firstErr
+	}
+	return pkg, nil
+}
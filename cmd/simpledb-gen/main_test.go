@@ -0,0 +1,32 @@
+package main
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestGenerateFile(t *testing.T) {
+	dir := filepath.Join("..", "..", "internal", "gentest")
+	expected, err := os.ReadFile(filepath.Join(dir, "gentest_simpledb.go"))
+	if err != nil {
+		t.Fatalf("Failed to read generated file: %s", err)
+	}
+
+	src, err := generateFile(dir, "gentest_simpledb.go", []string{"Everything", "Event"}, "-type Everything,Event -output gentest_simpledb.go")
+	if err != nil {
+		t.Fatalf("Failed to generate file: %s", err)
+	}
+
+	if !bytes.Equal(src, expected) {
+		t.Fatalf("generated file is out of date; run go generate ./internal/gentest")
+	}
+
+	if _, err := generateFile(dir, "gentest_simpledb.go", []string{"Missing"}, ""); err == nil {
+		t.Fatalf("expected error generating methods for a missing type")
+	}
+	if _, err := generateFile(dir, "gentest_simpledb.go", []string{"UUID"}, ""); err == nil {
+		t.Fatalf("expected error generating methods for a non-struct type")
+	}
+}
@@ -0,0 +1,95 @@
+package simpledb
+
+import (
+	"fmt"
+	"io"
+	"reflect"
+)
+
+// Codec encodes and decodes the bodies of a DB table's rows. By default, a table encodes its rows
+// with simpledb's reflection-based binary encoding, or with the EncodeSimpleDB and DecodeSimpleDB
+// methods of its struct type if it has them (see cmd/simpledb-gen). Another Codec can be given
+// with WithCodec, such as a human-readable format for debugging. The file header, row headers and
+// index snapshots are not affected by the Codec.
+//
+// A Codec must be able to decode every row it encodes, and the same Codec must be used every
+// time the table is opened. A Codec must be safe for concurrent use, as rows may be decoded by
+// many goroutines at once.
+type Codec interface {
+	// Encode writes the encoding of a row to w, returning the number of bytes written. The value is
+	// either a struct of the table's type, or a pointer to one.
+	Encode(w io.Writer, value interface{}) (int, error)
+
+	// Decode reads the encoding of a row from r into destPtr, which is a pointer to a struct of the
+	// table's type, returning the number of bytes read. r holds only the row's encoding, and returns
+	// io.EOF once it has been read.
+	Decode(r io.Reader, destPtr interface{}) (int, error)
+}
+
+// generatedRow is implemented by pointers to struct types which have EncodeSimpleDB and DecodeSimpleDB
+// methods, such as those generated by cmd/simpledb-gen. EncodeSimpleDB appends the same encoding of
+// the struct as simpledb's reflection-based binary encoding to buf, and DecodeSimpleDB decodes it
+// from the start of data, returning the number of bytes read. A DB whose struct type has these
+// methods uses them as its default Codec.
+type generatedRow interface {
+	EncodeSimpleDB(buf []byte) ([]byte, error)
+	DecodeSimpleDB(data []byte) (int, error)
+}
+
+var generatedRowType = reflect.TypeOf((*generatedRow)(nil)).Elem()
+
+// generatedCodec is a Codec which encodes rows with their generatedRow methods.
+type generatedCodec struct{}
+
+// Encode implements Codec.
+func (generatedCodec) Encode(w io.Writer, value interface{}) (int, error) {
+	row, ok := value.(generatedRow)
+	if !ok {
+		// value is a struct, rather than a pointer to one
+		ptr := reflect.New(reflect.TypeOf(value))
+		ptr.Elem().Set(reflect.ValueOf(value))
+		row = ptr.Interface().(generatedRow)
+	}
+
+	data, err := row.EncodeSimpleDB(nil)
+	if err != nil {
+		return 0, err
+	}
+	return w.Write(data)
+}
+
+// Decode implements Codec.
+func (generatedCodec) Decode(r io.Reader, destPtr interface{}) (int, error) {
+	data, err := io.ReadAll(r)
+	if err != nil {
+		return 0, err
+	}
+	return destPtr.(generatedRow).DecodeSimpleDB(data)
+}
+
+// WithCodec is an Option which makes the DB table encode and decode its rows with the given Codec,
+// instead of simpledb's reflection-based binary encoding. It can be passed to NewDB or db.Table.
+func WithCodec(codec Codec) Option {
+	return func(db *DB) error {
+		db.codec = codec
+		return nil
+	}
+}
+
+// encodeRow encodes the body of a row with the DB's Codec, after checking the value is
+// of the DB's struct type.
+func (db *DB) encodeRow(w io.Writer, value interface{}) (int, error) {
+	if valueType := reflect.TypeOf(value); valueType != db.schema.dataType && valueType != reflect.PtrTo(db.schema.dataType) {
+		return 0, fmt.Errorf("invalid data type for DB encoding '%s'", valueType)
+	}
+	return db.codec.Encode(w, value)
+}
+
+// decodeRow decodes the body of a row of the given size with the DB's Codec, after checking
+// destPtr is a pointer to the DB's struct type.
+func (db *DB) decodeRow(r io.Reader, size uint64, destPtr interface{}) (int, error) {
+	if valueType := reflect.TypeOf(destPtr); valueType != reflect.PtrTo(db.schema.dataType) {
+		return 0, fmt.Errorf("invalid data type for DB decoding '%s'", valueType)
+	}
+	return db.codec.Decode(io.LimitReader(r, int64(size)), destPtr)
+}
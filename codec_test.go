@@ -0,0 +1,287 @@
+package simpledb
+
+import (
+	"bytes"
+	"encoding/json"
+	"io"
+	"math"
+	"os"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/kklash/simpledb/internal/gentest"
+)
+
+// jsonCodec is a Codec which encodes rows as JSON.
+type jsonCodec struct{}
+
+func (jsonCodec) Encode(w io.Writer, value interface{}) (int, error) {
+	data, err := json.Marshal(value)
+	if err != nil {
+		return 0, err
+	}
+	return w.Write(data)
+}
+
+func (jsonCodec) Decode(r io.Reader, destPtr interface{}) (int, error) {
+	data, err := io.ReadAll(r)
+	if err != nil {
+		return len(data), err
+	}
+	return len(data), json.Unmarshal(data, destPtr)
+}
+
+func TestCodec(t *testing.T) {
+	type Car struct {
+		Make string `simpledb:"indexed"`
+		Year uint16
+	}
+
+	type Person struct {
+		Name string
+	}
+
+	tempFile, err := os.CreateTemp(os.TempDir(), "simpledb-")
+	if err != nil {
+		t.Fatalf("Failed to create temp file: %s", err)
+	}
+
+	t.Cleanup(func() {
+		tempFile.Close()
+		os.Remove(tempFile.Name())
+	})
+
+	db, err := NewDB(tempFile, Car{}, WithCodec(jsonCodec{}))
+	if err != nil {
+		t.Fatalf("failed to create DB: %s", err)
+	}
+	people, err := db.Table("people", Person{})
+	if err != nil {
+		t.Fatalf("failed to open table: %s", err)
+	}
+
+	carID, err := db.Insert(Car{Make: "Mazda", Year: 2008})
+	if err != nil {
+		t.Fatalf("Failed to insert car: %s", err)
+	}
+	if _, err := db.Insert(&Car{Make: "Ford", Year: 1999}); err != nil {
+		t.Fatalf("Failed to insert car: %s", err)
+	}
+	personID, err := people.Insert(Person{Name: "alice"})
+	if err != nil {
+		t.Fatalf("Failed to insert person: %s", err)
+	}
+
+	if _, err := db.Insert(Person{Name: "bob"}); err == nil {
+		t.Fatalf("expected error inserting a value of the wrong type")
+	}
+
+	tx := db.Begin()
+	if _, err := tx.Insert(Car{Make: "Toyota", Year: 2012}); err != nil {
+		t.Fatalf("Failed to insert car in transaction: %s", err)
+	}
+	if err := tx.Commit(); err != nil {
+		t.Fatalf("Failed to commit transaction: %s", err)
+	}
+
+	contents, err := os.ReadFile(tempFile.Name())
+	if err != nil {
+		t.Fatalf("Failed to read DB file: %s", err)
+	}
+	if !bytes.Contains(contents, []byte(`{"Make":"Mazda","Year":2008}`)) {
+		t.Fatalf("expected rows to be encoded as JSON")
+	}
+
+	if _, err := db.Table("logs", Person{}, WithJournal(nil)); err == nil {
+		t.Fatalf("expected error passing WithJournal to db.Table")
+	}
+
+	db, err = NewDB(tempFile, Car{}, WithCodec(jsonCodec{}))
+	if err != nil {
+		t.Fatalf("Failed to reopen DB: %s", err)
+	}
+	people, err = db.Table("people", Person{})
+	if err != nil {
+		t.Fatalf("failed to reopen table: %s", err)
+	}
+
+	var car Car
+	if err := db.Find(carID, &car); err != nil {
+		t.Fatalf("Failed to find car: %s", err)
+	}
+	if expected := (Car{Make: "Mazda", Year: 2008}); !reflect.DeepEqual(car, expected) {
+		t.Fatalf("found car does not match\nWanted %+v\nGot    %+v", expected, car)
+	}
+
+	var person Person
+	if err := people.Find(personID, &person); err != nil {
+		t.Fatalf("Failed to find person: %s", err)
+	}
+	if person.Name != "alice" {
+		t.Fatalf("expected table without a Codec to use the default encoding, got %+v", person)
+	}
+
+	rows, err := db.Filter(FilterQuery{"Make": In("Ford", "Toyota")})
+	if err != nil {
+		t.Fatalf("Failed to filter cars: %s", err)
+	}
+	if len(rows) != 2 {
+		t.Fatalf("expected 2 cars, got %d", len(rows))
+	}
+
+	if err := db.Defrag(); err != nil {
+		t.Fatalf("Failed to defrag DB: %s", err)
+	}
+	if n := db.RowCount(); n != 3 {
+		t.Fatalf("expected 3 cars after defrag, got %d", n)
+	}
+}
+
+func TestGeneratedCodec(t *testing.T) {
+	label := "label"
+	year := gentest.Year(2008)
+	zip := uint16(0xabcd)
+	start := time.Date(2021, time.March, 4, 12, 30, 0, 500, time.UTC)
+	amount := gentest.NewAmount(-1050)
+
+	full := gentest.Everything{
+		Bool: true, Int8: -1, Int16: -2, Int32: -3, Int64: -4,
+		Uint8: 1, Uint16: 2, Uint32: 3, Uint64: 1 << 63,
+		Float32: 1.5, Float64: math.Inf(-1), Complex64: complex(1, -1), Complex128: complex(math.Pi, 0),
+		String: "héllo",
+
+		Make: "Mazda", Year: 2008, Flag: true, Level: -5, Ratio: 0.25, Point: complex(2, 3), Bytes: gentest.Bytes("xyz"),
+
+		ByteArray:   [4]byte{1, 2, 3, 4},
+		Int16Array:  [2]int16{-1, 1},
+		StringArray: [2]string{"a", ""},
+		ByteSlice:   []byte{0, 0xff},
+		Uint32Slice: []uint32{0xffffaaaa, 0},
+		Strings:     [][]string{{"foo", "bar"}, {}},
+		Years:       []gentest.Year{1999, 2004},
+
+		Address:   gentest.Address{Zip: 1, City: "Paris"},
+		Addresses: []gentest.Address{{Zip: 2}, {City: "Oslo"}},
+
+		Pointer:        &zip,
+		AddressPointer: &gentest.Address{City: "Rome"},
+		PointerSlice:   []*gentest.Address{nil, {Zip: 3}},
+
+		Labels:      map[string]uint8{"b": 2, "aa": 1, "": 0},
+		Ports:       map[int8][]string{-1: {"x"}, 0: {}, 80: {"http"}},
+		Weights:     map[float64]bool{math.Inf(1): true, -0.5: false, 0: true},
+		Coordinates: map[[2]float32]string{{1, 2}: "a", {1, -2}: "b", {0, 0}: "c"},
+		Complex:     map[complex64]bool{1: true, 0: false, complex(0, 1): true},
+		ByAddress:   map[gentest.Address]int32{{Zip: 1}: 1, {City: "a"}: 2},
+		ByUUID:      map[gentest.UUID]*gentest.Amount{{1}: &amount, {0, 1}: nil},
+		Nested:      map[string]map[gentest.Year]string{"x": {year: "y", 1: "z"}, "empty": {}},
+
+		Time:     start,
+		TimePtr:  &start,
+		Duration: -time.Second,
+		Times:    map[time.Time]uint8{start: 1, start.Add(-time.Hour): 2, {}: 3},
+
+		UUID:    gentest.UUID{9, 8, 7, 6},
+		Amount:  amount,
+		Amounts: []gentest.Amount{gentest.NewAmount(1), gentest.NewAmount(22)},
+	}
+	full.Anonymous.A = &label
+	full.Anonymous.B = []int8{-128, 127}
+	full.Shared = "shared"
+	full.Created = 12345
+
+	// NaN keys are all distinct, and -0 has a different encoding from 0.
+	odd := gentest.Everything{
+		Weights:     map[float64]bool{math.NaN(): true, math.Copysign(0, -1): false, 1: true},
+		Coordinates: map[[2]float32]string{{float32(math.NaN()), 1}: "a", {0, 1}: "b", {float32(math.Inf(-1)), 0}: "c"},
+		Float64:     math.NaN(),
+	}
+	odd.Weights[math.NaN()] = false
+
+	for _, fixture := range []gentest.Everything{{}, full, odd} {
+		expected := new(bytes.Buffer)
+		if _, err := encodeStructToBinary(expected, reflect.ValueOf(fixture)); err != nil {
+			t.Fatalf("Failed to encode fixture with reflection: %s", err)
+		}
+
+		encoded, err := fixture.EncodeSimpleDB(nil)
+		if err != nil {
+			t.Fatalf("Failed to encode fixture with generated code: %s", err)
+		}
+		if !bytes.Equal(encoded, expected.Bytes()) {
+			t.Fatalf("generated encoding does not match\nWanted %x\nGot    %x", expected.Bytes(), encoded)
+		}
+
+		var decoded, reflected gentest.Everything
+		n, err := decoded.DecodeSimpleDB(encoded)
+		if err != nil {
+			t.Fatalf("Failed to decode fixture with generated code: %s", err)
+		} else if n != len(encoded) {
+			t.Fatalf("expected %d bytes to be decoded, got %d", len(encoded), n)
+		}
+		if _, err := decodeStructFromBinary(bytes.NewReader(encoded), reflect.ValueOf(&reflected)); err != nil {
+			t.Fatalf("Failed to decode fixture with reflection: %s", err)
+		}
+
+		reencoded := new(bytes.Buffer)
+		if _, err := encodeStructToBinary(reencoded, reflect.ValueOf(decoded)); err != nil {
+			t.Fatalf("Failed to encode decoded fixture: %s", err)
+		}
+		if !bytes.Equal(reencoded.Bytes(), encoded) {
+			t.Fatalf("decoded fixture does not have the same encoding\nWanted %x\nGot    %x", encoded, reencoded.Bytes())
+		}
+		if fixture.Float64 == fixture.Float64 && !reflect.DeepEqual(decoded, reflected) {
+			t.Fatalf("generated decoding does not match\nWanted %+v\nGot    %+v", reflected, decoded)
+		}
+
+		for i := 0; i < len(encoded); i++ {
+			if _, err := decoded.DecodeSimpleDB(encoded[:i]); err == nil {
+				t.Fatalf("expected error decoding truncated fixture of length %d", i)
+			}
+		}
+	}
+
+	tempFile, err := os.CreateTemp(os.TempDir(), "simpledb-")
+	if err != nil {
+		t.Fatalf("Failed to create temp file: %s", err)
+	}
+
+	t.Cleanup(func() {
+		tempFile.Close()
+		os.Remove(tempFile.Name())
+	})
+
+	db, err := NewDB(tempFile, gentest.Event{})
+	if err != nil {
+		t.Fatalf("failed to create DB: %s", err)
+	}
+	if _, ok := db.codec.(generatedCodec); !ok {
+		t.Fatalf("expected DB to use generated methods as its codec, got %T", db.codec)
+	}
+
+	event := gentest.Event{ID: gentest.UUID{1}, Name: "launch", Start: start, Tags: []string{"a", "b"}}
+	id, err := db.Insert(event)
+	if err != nil {
+		t.Fatalf("Failed to insert event: %s", err)
+	}
+
+	db, err = NewDB(tempFile, gentest.Event{})
+	if err != nil {
+		t.Fatalf("Failed to reopen DB: %s", err)
+	}
+	var found gentest.Event
+	if err := db.Find(id, &found); err != nil {
+		t.Fatalf("Failed to find event: %s", err)
+	}
+	if !reflect.DeepEqual(found, event) {
+		t.Fatalf("found event does not match\nWanted %+v\nGot    %+v", event, found)
+	}
+
+	rows, err := db.Filter(FilterQuery{"Start": GreaterOrEqual(start)})
+	if err != nil {
+		t.Fatalf("Failed to filter events: %s", err)
+	} else if len(rows) != 1 {
+		t.Fatalf("expected 1 event, got %d", len(rows))
+	}
+}
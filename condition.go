@@ -0,0 +1,376 @@
+package simpledb
+
+import (
+	"bytes"
+	"fmt"
+	"reflect"
+	"strings"
+)
+
+// Condition is a test of a column's value. A Condition can be used as a value in a FilterQuery or
+// Where, in place of a plain value which the column must be strictly equal to.
+//
+//  rows, err := db.Filter(simpledb.FilterQuery{
+//    "Year":  simpledb.GreaterThan(2005),
+//    "Price": simpledb.Between(10, 20),
+//  })
+type Condition interface {
+	// Match returns true if the given column value satisfies the condition.
+	Match(columnValue interface{}) bool
+}
+
+type comparisonOperator int
+
+const (
+	opEqual comparisonOperator = iota
+	opNotEqual
+	opLessThan
+	opLessOrEqual
+	opGreaterThan
+	opGreaterOrEqual
+	opIn
+	opBetween
+)
+
+var comparisonOperatorSymbols = map[comparisonOperator]string{
+	opEqual:          "=",
+	opNotEqual:       "!=",
+	opLessThan:       "<",
+	opLessOrEqual:    "<=",
+	opGreaterThan:    ">",
+	opGreaterOrEqual: ">=",
+	opIn:             "in",
+	opBetween:        "between",
+}
+
+// comparison is a Condition which compares a column's value against one or more operands.
+type comparison struct {
+	operator comparisonOperator
+	operands []interface{}
+}
+
+// Equal returns a Condition which matches column values equal to the given value. Unlike a plain value in
+// a FilterQuery, numeric values of different types are compared by value, so Equal(2008) matches a uint16
+// column holding 2008.
+func Equal(value interface{}) Condition {
+	return &comparison{opEqual, []interface{}{value}}
+}
+
+// NotEqual returns a Condition which matches column values not equal to the given value.
+func NotEqual(value interface{}) Condition {
+	return &comparison{opNotEqual, []interface{}{value}}
+}
+
+// LessThan returns a Condition which matches column values less than the given value.
+func LessThan(value interface{}) Condition {
+	return &comparison{opLessThan, []interface{}{value}}
+}
+
+// LessOrEqual returns a Condition which matches column values less than or equal to the given value.
+func LessOrEqual(value interface{}) Condition {
+	return &comparison{opLessOrEqual, []interface{}{value}}
+}
+
+// GreaterThan returns a Condition which matches column values greater than the given value.
+func GreaterThan(value interface{}) Condition {
+	return &comparison{opGreaterThan, []interface{}{value}}
+}
+
+// GreaterOrEqual returns a Condition which matches column values greater than or equal to the given value.
+func GreaterOrEqual(value interface{}) Condition {
+	return &comparison{opGreaterOrEqual, []interface{}{value}}
+}
+
+// In returns a Condition which matches column values equal to any of the given values.
+func In(values ...interface{}) Condition {
+	return &comparison{opIn, values}
+}
+
+// Between returns a Condition which matches column values between min and max, inclusive.
+func Between(min, max interface{}) Condition {
+	return &comparison{opBetween, []interface{}{min, max}}
+}
+
+func (c *comparison) String() string {
+	operands := make([]string, len(c.operands))
+	for i, operand := range c.operands {
+		operands[i] = fmt.Sprint(operand)
+	}
+	return comparisonOperatorSymbols[c.operator] + " " + strings.Join(operands, ", ")
+}
+
+// Match implements Condition.
+func (c *comparison) Match(columnValue interface{}) bool {
+	column := reflect.ValueOf(columnValue)
+
+	compare := func(i int) (int, bool) {
+		return compareValues(column, reflect.ValueOf(c.operands[i]))
+	}
+
+	switch c.operator {
+	case opEqual:
+		result, ok := compare(0)
+		return ok && result == 0
+	case opNotEqual:
+		result, ok := compare(0)
+		return !ok || result != 0
+	case opLessThan:
+		result, ok := compare(0)
+		return ok && result < 0
+	case opLessOrEqual:
+		result, ok := compare(0)
+		return ok && result <= 0
+	case opGreaterThan:
+		result, ok := compare(0)
+		return ok && result > 0
+	case opGreaterOrEqual:
+		result, ok := compare(0)
+		return ok && result >= 0
+	case opIn:
+		for i := range c.operands {
+			if result, ok := compare(i); ok && result == 0 {
+				return true
+			}
+		}
+		return false
+	case opBetween:
+		min, ok := compare(0)
+		if !ok || min < 0 {
+			return false
+		}
+		max, ok := compare(1)
+		return ok && max <= 0
+	}
+
+	return false
+}
+
+// mapCondition is a Condition which tests the entries of a map column.
+type mapCondition struct {
+	key      interface{}
+	value    interface{}
+	hasValue bool
+}
+
+// HasKey returns a Condition which matches map columns holding the given key. Numeric keys
+// are compared by value, so HasKey(1) matches a map[uint16]string column holding the key 1.
+func HasKey(key interface{}) Condition {
+	return &mapCondition{key: key}
+}
+
+// HasEntry returns a Condition which matches map columns holding the given key, mapped to a value
+// which matches the given value. Like a value in a FilterQuery, the value can be either a plain value
+// which the entry's value must be strictly equal to, or a Condition which it must match.
+func HasEntry(key, value interface{}) Condition {
+	return &mapCondition{key: key, value: value, hasValue: true}
+}
+
+func (c *mapCondition) String() string {
+	if c.hasValue {
+		return fmt.Sprintf("has entry %v: %v", c.key, c.value)
+	}
+	return fmt.Sprintf("has key %v", c.key)
+}
+
+// Match implements Condition.
+func (c *mapCondition) Match(columnValue interface{}) bool {
+	column := reflect.ValueOf(columnValue)
+	if column.Kind() != reflect.Map {
+		return false
+	}
+
+	value, ok := mapLookup(column, reflect.ValueOf(c.key))
+	if !ok {
+		return false
+	}
+	return !c.hasValue || matchesColumn(c.value, value.Interface())
+}
+
+// mapLookup returns the value mapped to the given key in a map. If the key is not of the map's
+// key type, it is compared by value with each of the map's keys.
+func mapLookup(mapValue, key reflect.Value) (reflect.Value, bool) {
+	if !key.IsValid() {
+		return reflect.Value{}, false
+	}
+
+	if key.Type() == mapValue.Type().Key() {
+		value := mapValue.MapIndex(key)
+		return value, value.IsValid()
+	}
+
+	iter := mapValue.MapRange()
+	for iter.Next() {
+		if result, ok := compareValues(iter.Key(), key); ok && result == 0 {
+			return iter.Value(), true
+		}
+	}
+	return reflect.Value{}, false
+}
+
+// nullCondition is a Condition which tests whether a pointer column is nil.
+type nullCondition struct {
+	null bool
+}
+
+// IsNull returns a Condition which matches pointer columns holding a nil pointer.
+func IsNull() Condition {
+	return &nullCondition{null: true}
+}
+
+// IsNotNull returns a Condition which matches every column value except nil pointers.
+func IsNotNull() Condition {
+	return &nullCondition{null: false}
+}
+
+func (c *nullCondition) String() string {
+	if c.null {
+		return "is null"
+	}
+	return "is not null"
+}
+
+// Match implements Condition.
+func (c *nullCondition) Match(columnValue interface{}) bool {
+	column := reflect.ValueOf(columnValue)
+	isNull := column.Kind() == reflect.Ptr && column.IsNil()
+	return isNull == c.null
+}
+
+// matchesColumn returns true if the given column value matches the value given in a FilterQuery,
+// which is either a Condition or a plain value which the column must be deeply equal to.
+func matchesColumn(queryValue, columnValue interface{}) bool {
+	if condition, ok := queryValue.(Condition); ok {
+		return condition.Match(columnValue)
+	}
+	return reflect.DeepEqual(queryValue, columnValue)
+}
+
+// compareValues compares two values, returning -1 if a < b, 0 if a == b, or 1 if a > b. Numeric values
+// of any type are compared by value. Strings are compared lexically, bools are ordered with false before
+// true, and times are ordered by the instant they represent. Arrays and slices are compared element by
+// element, with shorter slices ordered first if they are otherwise equal. Pointers are compared by the
+// values they point to. Complex numbers can only be compared for equality, as can values of any other
+// type implementing encoding.BinaryMarshaler, by their marshaled bytes. NaN and nil pointers cannot be
+// compared at all. The second return value is false if the values cannot be compared.
+func compareValues(a, b reflect.Value) (int, bool) {
+	if a.Kind() == reflect.Ptr && !a.IsNil() {
+		a = a.Elem()
+	}
+	if b.Kind() == reflect.Ptr && !b.IsNil() {
+		b = b.Elem()
+	}
+	if !a.IsValid() || !b.IsValid() {
+		return 0, false
+	}
+
+	aKind, bKind := a.Kind(), b.Kind()
+
+	switch {
+	case isIntegerKind(aKind) && isIntegerKind(bKind):
+		return compareIntegers(a, b), true
+
+	case isRealKind(aKind) && isRealKind(bKind):
+		if isNaN(a) || isNaN(b) {
+			return 0, false
+		}
+		return compareFloats(toFloat64(a), toFloat64(b)), true
+
+	case isComplexKind(aKind) && isComplexKind(bKind):
+		if a.Complex() == b.Complex() {
+			return 0, true
+		}
+		return 0, false
+
+	case aKind == reflect.String && bKind == reflect.String:
+		return strings.Compare(a.String(), b.String()), true
+
+	case a.Type() == timeType && b.Type() == timeType:
+		return compareTimes(a, b), true
+
+	case aKind == reflect.Bool && bKind == reflect.Bool:
+		if a.Bool() == b.Bool() {
+			return 0, true
+		} else if b.Bool() {
+			return -1, true
+		}
+		return 1, true
+
+	case (aKind == reflect.Array || aKind == reflect.Slice) && (bKind == reflect.Array || bKind == reflect.Slice):
+		for i := 0; i < a.Len() && i < b.Len(); i++ {
+			result, ok := compareValues(a.Index(i), b.Index(i))
+			if !ok {
+				return 0, false
+			} else if result != 0 {
+				return result, true
+			}
+		}
+		return compareIntegers(reflect.ValueOf(a.Len()), reflect.ValueOf(b.Len())), true
+
+	case a.Type() == b.Type() && isBinaryMarshalerType(a.Type()):
+		aData, aErr := marshalBinaryValue(a)
+		bData, bErr := marshalBinaryValue(b)
+		if aErr == nil && bErr == nil && bytes.Equal(aData, bData) {
+			return 0, true
+		}
+		return 0, false
+	}
+
+	return 0, false
+}
+
+func toFloat64(v reflect.Value) float64 {
+	if isSignedKind(v.Kind()) {
+		return float64(v.Int())
+	} else if isUnsignedKind(v.Kind()) {
+		return float64(v.Uint())
+	}
+	return v.Float()
+}
+
+func compareFloats(a, b float64) int {
+	if a < b {
+		return -1
+	} else if a > b {
+		return 1
+	}
+	return 0
+}
+
+// compareIntegers compares two integer values of any signedness without losing precision.
+func compareIntegers(a, b reflect.Value) int {
+	aSigned, bSigned := isSignedKind(a.Kind()), isSignedKind(b.Kind())
+
+	if aSigned && a.Int() < 0 {
+		if bSigned && b.Int() < 0 {
+			if a.Int() < b.Int() {
+				return -1
+			} else if a.Int() > b.Int() {
+				return 1
+			}
+			return 0
+		}
+		return -1
+	} else if bSigned && b.Int() < 0 {
+		return 1
+	}
+
+	// both are non-negative
+	var aUint, bUint uint64
+	if aSigned {
+		aUint = uint64(a.Int())
+	} else {
+		aUint = a.Uint()
+	}
+	if bSigned {
+		bUint = uint64(b.Int())
+	} else {
+		bUint = b.Uint()
+	}
+
+	if aUint < bUint {
+		return -1
+	} else if aUint > bUint {
+		return 1
+	}
+	return 0
+}
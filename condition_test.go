@@ -0,0 +1,150 @@
+package simpledb
+
+import (
+	"math"
+	"os"
+	"testing"
+	"time"
+)
+
+func TestConditions(t *testing.T) {
+	type Fixture struct {
+		condition   Condition
+		columnValue interface{}
+		expected    bool
+	}
+
+	year := uint16(2008)
+	now := time.Now()
+
+	fixtures := []Fixture{
+		{Equal(2008), uint16(2008), true},
+		{Equal(2008), uint16(2009), false},
+		{Equal("foo"), "foo", true},
+		{Equal(1.5), float32(1.5), true},
+		{Equal(complex(1, 2)), complex64(complex(1, 2)), true},
+		{Equal("foo"), 1, false},
+		{NotEqual(2008), uint16(2009), true},
+		{NotEqual("foo"), 1, true},
+		{LessThan(0), uint64(0), false},
+		{LessThan(0), int8(-1), true},
+		{LessThan(uint64(1 << 63)), int64(-1), true},
+		{LessThan(int64(-1)), uint64(1 << 63), false},
+		{LessOrEqual(10), 10.0, true},
+		{GreaterThan(2005), uint16(2008), true},
+		{GreaterThan(2005), uint16(2005), false},
+		{GreaterThan(2.5), 3, true},
+		{GreaterOrEqual(2005), uint16(2005), true},
+		{GreaterThan("apple"), "banana", true},
+		{LessThan([4]byte{1, 2, 3, 4}), [4]byte{1, 2, 3, 3}, true},
+		{LessThan([]byte{1, 2}), []byte{1, 2, 0}, false},
+		{GreaterThan([]byte{1, 2}), []byte{1, 2, 0}, true},
+		{GreaterThan(false), true, true},
+		{LessThan(complex(1, 2)), complex(0, 0), false},
+		{In(1, 2, 3), uint8(2), true},
+		{In(1, 2, 3), uint8(4), false},
+		{In(), uint8(4), false},
+		{Between(10, 20), 15.5, true},
+		{Between(10, 20), uint32(10), true},
+		{Between(10, 20), uint32(20), true},
+		{Between(10, 20), int64(21), false},
+		{Between("a", "c"), "b", true},
+		{LessOrEqual(5), math.NaN(), false},
+		{Equal(math.NaN()), math.NaN(), false},
+		{NotEqual(5), math.NaN(), true},
+		{HasKey("env"), map[string]string{"env": "prod"}, true},
+		{HasKey("env"), map[string]string{"tier": "web"}, false},
+		{HasKey("env"), []string{"env"}, false},
+		{HasKey(1), map[uint16]string{1: "a"}, true},
+		{HasKey(uint16(2)), map[uint16]string{1: "a"}, false},
+		{HasEntry("env", "prod"), map[string]string{"env": "prod"}, true},
+		{HasEntry("env", "prod"), map[string]string{"env": "dev"}, false},
+		{HasEntry("count", GreaterThan(2)), map[string]int32{"count": 3}, true},
+		{HasEntry("count", 3), map[string]int32{"count": 3}, false},
+		{IsNull(), (*uint16)(nil), true},
+		{IsNull(), &year, false},
+		{IsNull(), uint16(0), false},
+		{IsNotNull(), (*uint16)(nil), false},
+		{IsNotNull(), &year, true},
+		{IsNotNull(), "", true},
+		{Equal(2008), &year, true},
+		{GreaterThan(2010), &year, false},
+		{Equal(0), (*uint16)(nil), false},
+		{NotEqual(0), (*uint16)(nil), true},
+		{Equal(&year), uint16(2008), true},
+		{Equal(now), now.In(time.FixedZone("UTC+1", 3600)), true},
+		{LessThan(now), now.Add(-time.Nanosecond), true},
+		{GreaterThan(now), now.Add(-time.Nanosecond), false},
+		{Between(time.Minute, time.Hour), 5 * time.Minute, true},
+		{LessThan(now), now.Unix(), false},
+	}
+
+	for _, fixture := range fixtures {
+		if actual := fixture.condition.Match(fixture.columnValue); actual != fixture.expected {
+			t.Errorf("condition '%s' on %T(%v) returned %v, expected %v",
+				fixture.condition, fixture.columnValue, fixture.columnValue, actual, fixture.expected)
+		}
+	}
+}
+
+func TestFilterConditions(t *testing.T) {
+	type Car struct {
+		Year  uint16 `simpledb:"indexed"`
+		Make  string
+		Price float64
+	}
+
+	tempFile, err := os.CreateTemp(os.TempDir(), "simpledb-")
+	if err != nil {
+		t.Fatalf("Failed to create temp file: %s", err)
+	}
+
+	t.Cleanup(func() {
+		tempFile.Close()
+		os.Remove(tempFile.Name())
+	})
+
+	db, err := NewDB(tempFile, Car{})
+	if err != nil {
+		t.Fatalf("failed to create DB: %s", err)
+	}
+
+	cars := []Car{
+		{Year: 1999, Make: "Ford", Price: 5},
+		{Year: 2004, Make: "Mazda", Price: 12},
+		{Year: 2008, Make: "Mazda", Price: 18},
+		{Year: 2012, Make: "Toyota", Price: 25},
+	}
+	for _, car := range cars {
+		if _, err := db.Insert(car); err != nil {
+			t.Fatalf("Failed to insert car: %s", err)
+		}
+	}
+
+	countMatches := func(query FilterQuery) int {
+		rows, err := db.Filter(query)
+		if err != nil {
+			t.Fatalf("Failed to filter cars: %s", err)
+		}
+		return len(rows)
+	}
+
+	if n := countMatches(FilterQuery{"Year": GreaterThan(2005)}); n != 2 {
+		t.Errorf("expected 2 cars newer than 2005, got %d", n)
+	}
+	if n := countMatches(FilterQuery{"Price": Between(10, 20)}); n != 2 {
+		t.Errorf("expected 2 cars priced between 10 and 20, got %d", n)
+	}
+	if n := countMatches(FilterQuery{"Make": "Mazda", "Year": LessOrEqual(2004)}); n != 1 {
+		t.Errorf("expected 1 Mazda from 2004 or earlier, got %d", n)
+	}
+	if n := countMatches(FilterQuery{"Make": In("Ford", "Toyota")}); n != 2 {
+		t.Errorf("expected 2 Fords or Toyotas, got %d", n)
+	}
+	if n := countMatches(FilterQuery{"Make": NotEqual("Mazda"), "Price": LessThan(20)}); n != 1 {
+		t.Errorf("expected 1 cheap car not made by Mazda, got %d", n)
+	}
+	if n := countMatches(FilterQuery{"Year": uint16(2008)}); n != 1 {
+		t.Errorf("expected plain values to still be matched for equality, got %d", n)
+	}
+}
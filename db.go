@@ -1,10 +1,13 @@
-// Package simpledb provides a minimal, single-table No-SQL database.
+// Package simpledb provides a minimal No-SQL database.
 package simpledb
 
 import (
+	"errors"
 	"io"
+	"os"
 	"reflect"
 	"sync"
+	"time"
 )
 
 const (
@@ -16,9 +19,13 @@ const (
 )
 
 // Source is an interface for the long-term storage used by DB.
-// Usually, this is an *os.File. Read and Write calls should both
+// Usually, this is an *os.File, or an MmapSource wrapping one. Read and Write calls should both
 // move the same cursor of the Seeker. Seek calls should
 // support all three whence values.
+//
+// If the Source also implements io.ReaderAt, rows are read with ReadAt, which does not move the cursor,
+// so that many goroutines can read from the DB at once. Likewise, if it implements io.WriterAt, writes
+// are made with WriteAt.
 type Source interface {
 	io.Reader
 	io.Writer
@@ -27,9 +34,11 @@ type Source interface {
 	Truncate(size int64) error
 }
 
-// DB is a simple database with a single table. The table stores golang struct types as binary data on-disk.
+// DB is a simple database table stored in a Source. The table stores golang struct types as binary data on-disk.
 // It maintains an in-memory index of where specific data structures are stored on-disk, for faster lookup.
-// Reads from and writes to the DB block one-another with a mutex.
+// Writes to the DB block every other read and write with a mutex. If the Source implements io.ReaderAt,
+// as *os.File does, reads such as db.Find, db.Filter and db.Iterate can run concurrently with one another.
+// Otherwise, reads also block one-another, as reading moves the Source's cursor.
 //
 // Rows can be dropped, which zeros their data on-disk. If many drops have occurred, defragging should be
 // performed to reduce the DB size and speed up performance on later opening. It is good practice to call
@@ -37,18 +46,100 @@ type Source interface {
 //
 // If you wish to do frequent Filter calls on the DB using a particular field, you should add a tag to
 // that struct field: `simpledb:"indexed"`. This causes the DB to cache values from that field in-memory,
-// so that they can be looked up faster. This causes Filter calls which query that indexed field to
-// finish much faster. The trade-off is a significant increase in memory consumption, and a slow-down
-// on first-open for large databases.
+// so that they can be looked up faster. Each value is also mapped to the IDs of the rows holding it, so
+// Filter calls which query that indexed field for equality only decode the matching rows. The trade-off
+// is a significant increase in memory consumption, and a slow-down on first-open for large databases.
+// Tagging a field `simpledb:"indexed,ordered"` also keeps its values in a sorted B-tree, so that range
+// queries on that field only visit matching rows, and rows can be returned in the field's order with
+// db.FilterSorted. Tagging a field `simpledb:"unique"` indexes it, and prevents any two rows of the
+// table from holding the same value in that field, other than a nil pointer.
+//
+// A single Source can store several named tables, each with its own struct type. The DB returned by
+// NewDB is the table named with an empty string. Other tables are opened with db.Table, and share
+// the Source and mutex of the DB they were opened from.
 type DB struct {
+	*dbFile
+	name          string
+	number        uint64
+	columns       []Column
 	schema        *tableSchema
-	source        Source
-	mutex         sync.Mutex
+	codec         Codec
 	index         map[uint64]int64
 	customIndices map[string]map[uint64]interface{}
+
+	// hashIndices holds an inverted index of each indexed field, mapping values to the rows holding them.
+	hashIndices map[string]hashIndex
+
+	// uniqueFields holds the names of the indexed fields tagged `simpledb:"unique"`.
+	uniqueFields []string
+
+	// orderedIndices holds an ordered index of each indexed field tagged `simpledb:"indexed,ordered"`.
+	orderedIndices map[string]*orderedIndex
 }
 
-// ReflectSchema sets the schema of the DB based on the given struct type value.
+// dbFile is the state shared by every table stored in the same Source.
+type dbFile struct {
+	source  Source
+	journal Source
+	mutex   sync.RWMutex
+
+	// lockMode is the advisory lock taken on the source if it is an *os.File, and lockTimeout
+	// is how long NewDB waits for the lock if the source is already locked.
+	lockMode    LockMode
+	lockTimeout time.Duration
+
+	// lockedFile is the *os.File locked by lockSource, if it took a lock which has not been released.
+	lockedFile *os.File
+
+	// readOnly is true if the DB was opened with OpenReadOnly, or with a shared lock.
+	readOnly bool
+
+	// recoveryNeeded is true if a commit failed after it was recorded in the journal, but before
+	// it was fully applied to the source. The DB cannot be modified until it is reopened.
+	recoveryNeeded bool
+
+	// indexSnapshot is an optional Source which stores a snapshot of every table's indices, and
+	// indexSnapshotValid is true if it holds a snapshot which matches the current DB source.
+	indexSnapshot      Source
+	indexSnapshotValid bool
+
+	// version is the format version of the source, or zero for sources without a file header.
+	version uint16
+
+	// headerBodySize is the size of the body of the source's file header, including the padding
+	// left for recording more tables in it.
+	headerBodySize uint64
+
+	// tables holds every table in the source, indexed by table number. The first headerTables
+	// tables are recorded in the source's file header, while the rest have been opened with
+	// db.Table but do not yet have any rows on-disk.
+	tables       []*DB
+	headerTables int
+}
+
+// rlock locks the DB source for reading. Readers share the lock if the source implements io.ReaderAt,
+// and otherwise they hold it exclusively, as reading moves the source's cursor.
+func (file *dbFile) rlock() {
+	if _, ok := file.source.(io.ReaderAt); ok {
+		file.mutex.RLock()
+	} else {
+		file.mutex.Lock()
+	}
+}
+
+// runlock unlocks a lock taken by rlock.
+func (file *dbFile) runlock() {
+	if _, ok := file.source.(io.ReaderAt); ok {
+		file.mutex.RUnlock()
+	} else {
+		file.mutex.Unlock()
+	}
+}
+
+// ReflectSchema sets the schema of the DB based on the given struct type value. The DB's rows
+// are then encoded with simpledb's binary encoding, replacing any Codec given with WithCodec.
+// If the struct type has EncodeSimpleDB and DecodeSimpleDB methods, generated by cmd/simpledb-gen,
+// they are used to encode rows instead of reflection.
 //  type Car struct {
 //    Color uint8
 //    Year  uint16
@@ -64,19 +155,44 @@ func (db *DB) ReflectSchema(value interface{}) error {
 		return err
 	}
 
+	db.columns = db.schema.Columns()
+	db.codec = db.schema
+	if reflect.PtrTo(db.schema.dataType).Implements(generatedRowType) {
+		db.codec = generatedCodec{}
+	}
 	db.customIndices = make(map[string]map[uint64]interface{})
+	db.hashIndices = make(map[string]hashIndex)
 	for _, fieldName := range getExportedIndexedFields(reflect.TypeOf(value)) {
 		db.customIndices[fieldName] = make(map[uint64]interface{})
+		db.hashIndices[fieldName] = make(hashIndex)
+	}
+	db.uniqueFields = getExportedUniqueFields(reflect.TypeOf(value))
+	db.orderedIndices = make(map[string]*orderedIndex)
+	for _, fieldName := range getExportedOrderedFields(reflect.TypeOf(value)) {
+		db.orderedIndices[fieldName] = new(orderedIndex)
 	}
 
 	return nil
 }
 
 // NewDB opens a DB on the target Source, usually an os.File pointer. Upon opening, NewDB reads the
-// the source from start to finish and in doing so, populates its in-memory index for faster lookups later.
-func NewDB(source Source, exampleValue interface{}) (*DB, error) {
+// the source from start to finish and in doing so, populates its in-memory index for faster lookups later,
+// unless the DB has an up-to-date index snapshot (see WithIndexSnapshot).
+// If the source's file header describes a different schema than that of exampleValue, NewDB returns
+// a *SchemaMismatchError. Any number of Options can be given to configure the DB.
+//
+// If the source is an *os.File, NewDB first takes an exclusive advisory lock on it, so that no other
+// process can open the DB at the same time. If the file is already locked, NewDB returns a *LockedError.
+// The lock is released when the DB is closed, or if NewDB returns an error after taking it. See WithLock
+// and WithLockTimeout to configure the lock.
+func NewDB(source Source, exampleValue interface{}, options ...Option) (*DB, error) {
+	return openDB(&dbFile{source: source}, exampleValue, options)
+}
+
+// openDB opens the DB table named with an empty string in the given file, as described by NewDB.
+func openDB(file *dbFile, exampleValue interface{}, options []Option) (*DB, error) {
 	db := &DB{
-		source: source,
+		dbFile: file,
 		index:  make(map[uint64]int64),
 	}
 
@@ -84,28 +200,82 @@ func NewDB(source Source, exampleValue interface{}) (*DB, error) {
 		return nil, err
 	}
 
+	for _, option := range options {
+		if err := option(db); err != nil {
+			return nil, err
+		}
+	}
+
+	db.tables = []*DB{db}
+
+	// other processes may hold a shared lock at the same time, so a DB holding one must not write
+	if db.lockMode == LockShared {
+		db.readOnly = true
+	}
+
+	if db.readOnly && db.journal != nil {
+		return nil, errors.New("WithJournal cannot be used with a read-only DB")
+	}
+
+	if err := db.lockSource(); err != nil {
+		return nil, err
+	}
+
+	if db.journal != nil {
+		if err := db.recoverJournal(); err != nil {
+			db.unlockSource()
+			return nil, err
+		}
+	}
+
 	if err := db.PopulateIndex(); err != nil {
+		db.unlockSource()
 		return nil, err
 	}
 
 	return db, nil
 }
 
-// Size returns the byte-size (disk usage) of the DB.
+// Size returns the byte-size (disk usage) of the DB source, including every table stored in it.
 func (db *DB) Size() (int64, error) {
 	db.mutex.Lock()
 	defer db.mutex.Unlock()
 	return db.source.Seek(0, io.SeekEnd)
 }
 
-// RowCount returns the number of rows in the DB.
+// RowCount returns the number of rows in the DB table.
 func (db *DB) RowCount() int {
+	db.mutex.RLock()
+	defer db.mutex.RUnlock()
 	return len(db.index)
 }
 
-// Close closes the underlying DB Source.
+// Close closes the underlying DB Source, shared by every table stored in it, and the DB's journal if it has one.
+// If the DB has an index snapshot Source, a snapshot of the DB's indices is written to it before closing.
 func (db *DB) Close() error {
 	db.mutex.Lock()
 	defer db.mutex.Unlock()
-	return db.source.Close()
+
+	// every Source is closed even if an error occurs, so that the DB source is never left locked
+	var err error
+	if db.indexSnapshot != nil {
+		err = db.writeIndexSnapshot()
+		if closeErr := db.indexSnapshot.Close(); err == nil {
+			err = closeErr
+		}
+	}
+
+	if db.journal != nil {
+		if closeErr := db.journal.Close(); err == nil {
+			err = closeErr
+		}
+	}
+
+	if unlockErr := db.unlockSource(); err == nil {
+		err = unlockErr
+	}
+	if closeErr := db.source.Close(); err == nil {
+		err = closeErr
+	}
+	return err
 }
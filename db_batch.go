@@ -0,0 +1,203 @@
+package simpledb
+
+import (
+	"bytes"
+	"io"
+)
+
+// sourceWrite is a single write of data to the DB source at the given offset.
+// If the offset is truncateOffset, the DB source is instead truncated to the size held in data.
+type sourceWrite struct {
+	offset int64
+	data   []byte
+}
+
+// batch is a set of writes to the DB source which are committed together, along with
+// the changes to the in-memory indices which are made once the writes are committed.
+type batch struct {
+	writes   []sourceWrite
+	onCommit []func() error
+
+	// end is the size of the DB source once the batch's writes are committed.
+	end int64
+
+	// dropped holds the IDs of the rows dropped by the batch, and unique maps the name of each unique
+	// column to the values inserted by the batch, so that unique constraints can be checked before
+	// the batch is committed.
+	dropped map[uint64]bool
+	unique  map[string]map[string]uint64
+}
+
+// newBatch starts a new batch of writes to the DB source. If the table is not yet recorded in the file
+// header, the batch begins by overwriting the header with one which includes it. If the header has no
+// room left for the table, or the source was written by an older format version, the DB source is first
+// rewritten instead. It assumes the caller is handling db.mutex.
+func (db *DB) newBatch() (*batch, error) {
+	end, err := db.source.Seek(0, io.SeekEnd)
+	if err != nil {
+		return nil, err
+	}
+
+	// The file header must be updated before inserting the first row of a table which is not yet
+	// recorded in it, so that the table's schema is known when the source is next opened.
+	if end == 0 || db.number < uint64(db.headerTables) {
+		return &batch{end: end}, nil
+	}
+
+	err = errHeaderFull
+	var header []byte
+	if db.version == FormatVersion {
+		header, _, err = encodeFileHeader(db.tableColumns(), db.headerBodySize)
+	}
+
+	if err == errHeaderFull {
+		if err := db.rewrite(db.tableColumns(), db.copyRows); err != nil {
+			return nil, err
+		}
+		if end, err = db.source.Seek(0, io.SeekEnd); err != nil {
+			return nil, err
+		}
+		return &batch{end: end}, nil
+	} else if err != nil {
+		return nil, err
+	}
+
+	headerTables := len(db.tables)
+	b := &batch{end: end}
+	b.writes = append(b.writes, sourceWrite{offset: 0, data: header})
+	b.onCommit = append(b.onCommit, func() error {
+		db.headerTables = headerTables
+		return nil
+	})
+	return b, nil
+}
+
+// insertInto adds the writes needed to insert a row with the given value and ID to the end of the DB source.
+// If the row would violate a unique constraint, it returns a *UniqueViolationError.
+func (db *DB) insertInto(b *batch, value interface{}, id uint64) error {
+	buf := new(bytes.Buffer)
+	bytesWritten, err := db.encodeRow(buf, value)
+	if err != nil {
+		return err
+	}
+
+	if err := db.checkUnique(b, value, id); err != nil {
+		return err
+	}
+
+	// The file header is written along with the first row inserted into an empty DB.
+	if b.end == 0 {
+		header, bodySize, err := encodeFileHeader(db.tableColumns(), 0)
+		if err != nil {
+			return err
+		}
+		b.writes = append(b.writes, sourceWrite{offset: 0, data: header})
+		b.end = int64(len(header))
+		db.version = FormatVersion
+		db.headerTables = len(db.tables)
+		db.headerBodySize = bodySize
+	}
+
+	cursor := b.end
+	row := append(db.encodeRowHeader(id, db.number, uint64(bytesWritten)), buf.Bytes()...)
+	b.writes = append(b.writes, sourceWrite{offset: cursor, data: row})
+	b.end += int64(len(row))
+
+	b.onCommit = append(b.onCommit, func() error {
+		return db.addToIndex(id, cursor, func() (interface{}, error) {
+			return value, nil
+		})
+	})
+
+	return nil
+}
+
+// dropInto adds the writes needed to drop the row with the given ID to the batch. The row's ID is
+// set to DeletedID, and its data is zeroed. If the row does not exist, it returns ErrNotFound.
+func (db *DB) dropInto(b *batch, id uint64) error {
+	if id == DeletedID {
+		return ErrNotFound
+	}
+
+	cursor, ok := db.index[id]
+	if !ok {
+		return ErrNotFound
+	}
+
+	rowHeader, err := db.readRowHeaderAt(cursor)
+	if err != nil {
+		return err
+	}
+
+	b.writes = append(b.writes,
+		sourceWrite{offset: cursor, data: encodeUint64(DeletedID)},
+		sourceWrite{offset: cursor + rowHeader.length, data: make([]byte, rowHeader.size)},
+	)
+
+	if b.dropped == nil {
+		b.dropped = make(map[uint64]bool)
+	}
+	b.dropped[id] = true
+
+	b.onCommit = append(b.onCommit, func() error {
+		db.removeFromIndex(id)
+		return nil
+	})
+
+	return nil
+}
+
+// commit commits the batch's writes to the DB source, and then updates the in-memory indices.
+func (file *dbFile) commit(b *batch) error {
+	if err := file.commitWrites(b.writes); err != nil {
+		return err
+	}
+
+	for _, onCommit := range b.onCommit {
+		if err := onCommit(); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// applyWrites performs the given writes on w. The writes are made with WriteAt if w implements io.WriterAt.
+func applyWrites(w Source, writes []sourceWrite) error {
+	for _, write := range writes {
+		if write.offset == truncateOffset {
+			size, err := decodeUint64(bytes.NewReader(write.data))
+			if err != nil {
+				return err
+			}
+			if err := w.Truncate(int64(size)); err != nil {
+				return err
+			}
+			continue
+		}
+
+		if writerAt, ok := w.(io.WriterAt); ok {
+			if _, err := writerAt.WriteAt(write.data, write.offset); err != nil {
+				return err
+			}
+			continue
+		}
+
+		if _, err := w.Seek(write.offset, io.SeekStart); err != nil {
+			return err
+		}
+		if _, err := w.Write(write.data); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// syncSource flushes writes to the given source to stable storage, if it supports doing so.
+func syncSource(source interface{}) error {
+	if syncer, ok := source.(interface{ Sync() error }); ok {
+		return syncer.Sync()
+	}
+	return nil
+}
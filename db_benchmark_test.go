@@ -217,3 +217,134 @@ func BenchmarkIndexedFields(b *testing.B) {
 		benchWithUserType(b, reflect.TypeOf(User{}))
 	})
 }
+
+func BenchmarkSources(b *testing.B) {
+	type Human struct {
+		Age         uint16
+		Name        string `simpledb:"indexed"`
+		Information []byte
+	}
+
+	humanNames := []string{"George", "Marg", "Bill", "Wendy", "Ace"}
+
+	randomHuman := func() Human {
+		info := make([]byte, randomData.Intn(30))
+		randomData.Read(info)
+		return Human{
+			Name:        humanNames[randomData.Intn(len(humanNames))],
+			Age:         uint16(randomData.Uint32() >> 24),
+			Information: info,
+		}
+	}
+
+	benchWithSource := func(b *testing.B, openSource func(*os.File) (Source, error)) {
+		tempFile, err := os.CreateTemp(os.TempDir(), "simpledb-")
+		if err != nil {
+			b.Fatalf("Failed to create temp file: %s", err)
+		}
+
+		b.Cleanup(func() {
+			tempFile.Close()
+			os.Remove(tempFile.Name())
+		})
+
+		source, err := openSource(tempFile)
+		if err != nil {
+			b.Fatalf("Failed to open source: %s", err)
+		}
+
+		db, err := NewDB(source, Human{})
+		if err != nil {
+			b.Fatalf("failed to create DB: %s", err)
+		}
+		b.Cleanup(func() { db.Close() })
+
+		ids := make([]uint64, 0, 20_000)
+		for i := 0; i < 20_000; i++ {
+			id, err := db.Insert(randomHuman())
+			if err != nil {
+				b.Fatalf("failed to insert human: %s", err)
+			}
+			ids = append(ids, id)
+		}
+
+		b.Run("insert", func(b *testing.B) {
+			for i := 0; i < b.N; i++ {
+				if _, err := db.Insert(randomHuman()); err != nil {
+					b.Fatalf("failed to insert human: %s", err)
+				}
+			}
+		})
+
+		b.Run("find", func(b *testing.B) {
+			for i := 0; i < b.N; i++ {
+				var foundHuman Human
+				id := ids[i%len(ids)]
+				if err := db.Find(id, &foundHuman); err != nil {
+					b.Fatalf("Failed to find human 0x%x: %s", id, err)
+				}
+			}
+		})
+
+		b.Run("filter", func(b *testing.B) {
+			for i := 0; i < b.N; i++ {
+				_, err := db.Filter(FilterQuery{
+					"Name": humanNames[i%len(humanNames)],
+					"Age":  uint16(i),
+				})
+				if err != nil {
+					b.Fatalf("Failed to filter humans: %s", err)
+				}
+			}
+		})
+
+		b.Run("iterate", func(b *testing.B) {
+			for i := 0; i < b.N; i++ {
+				nextRow := db.Iterate()
+				for {
+					row, err := nextRow()
+					if err != nil {
+						b.Fatalf("failed to get next row from iterator: %s", err)
+					}
+					if row == nil {
+						break
+					}
+				}
+			}
+		})
+
+		b.Run("PopulateIndex", func(b *testing.B) {
+			for i := 0; i < b.N; i++ {
+				if err := db.PopulateIndex(); err != nil {
+					b.Fatalf("Failed to populate DB index: %s", err)
+				}
+			}
+		})
+
+		b.Run("defrag", func(b *testing.B) {
+			for i := 0; i < b.N; i++ {
+				if err := db.Drop(ids[i%len(ids)]); err != nil && err != ErrNotFound {
+					b.Fatalf("Failed to drop human row: %s", err)
+				}
+				if err := db.Defrag(); err != nil {
+					b.Fatalf("Failed to defrag DB: %s", err)
+				}
+			}
+		})
+	}
+
+	b.Run("os.File", func(b *testing.B) {
+		benchWithSource(b, func(file *os.File) (Source, error) {
+			return file, nil
+		})
+	})
+
+	b.Run("MmapSource", func(b *testing.B) {
+		if !mmapSupported {
+			b.Skip("MmapSource is not supported on this system")
+		}
+		benchWithSource(b, func(file *os.File) (Source, error) {
+			return NewMmapSource(file)
+		})
+	})
+}
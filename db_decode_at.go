@@ -1,23 +1,35 @@
 package simpledb
 
 import (
-	"encoding/binary"
+	"bytes"
 	"io"
 )
 
-// decodeAt decodes a struct from the given cursor in the DB source. It assumes
-// the caller is handling db.mutex.
+// decodeAt decodes a struct from the given cursor in the DB source. If the source implements
+// io.ReaderAt, the source's cursor is not moved, so the caller need only hold a read lock with
+// db.rlock. Otherwise, it assumes the caller is handling db.mutex.
 func (db *DB) decodeAt(cursor int64, destPtr interface{}) error {
-	// +8 bytes for the uint64 id part of the header
-	if _, err := db.source.Seek(cursor+8, io.SeekStart); err != nil {
+	rowHeader, err := db.readRowHeaderAt(cursor)
+	if err != nil {
 		return err
 	}
 
-	if _, err := binary.ReadUvarint(&wrappedByteReader{db.source}); err != nil {
+	readerAt, ok := db.source.(io.ReaderAt)
+	if !ok {
+		// the source's cursor is already at the start of the row's body
+		_, err := db.decodeRow(db.source, rowHeader.size, destPtr)
 		return err
 	}
 
-	if _, err := db.schema.Decode(db.source, destPtr); err != nil {
+	body := make([]byte, rowHeader.size)
+	if n, err := readerAt.ReadAt(body, cursor+rowHeader.length); n < len(body) {
+		if err == io.EOF {
+			err = io.ErrUnexpectedEOF
+		}
+		return err
+	}
+
+	if _, err := db.decodeRow(bytes.NewReader(body), rowHeader.size, destPtr); err != nil {
 		return err
 	}
 
@@ -1,12 +1,11 @@
 package simpledb
 
 import (
+	"fmt"
 	"reflect"
+	"sort"
 )
 
-// FilterQuery is a set of strict equal requirements which are passed to db.Filter.
-type FilterQuery = map[string]interface{}
-
 // Row is a struct representing a row in the DB, including the struct Value
 // and the unique ID number.
 type Row struct {
@@ -14,28 +13,74 @@ type Row struct {
 	ID    uint64
 }
 
-// Filter searches the database for all rows which match the FilterQuery.
-// Decoded rows are checked against the query, each column compared with the value in
-// the query. If the row matches all queried values, it is included.
-//
-// TODO extend FilterQuery type as an interface.
+// Filter searches the database for all rows which match the FilterQuery, each column compared with
+// the value or Condition in the query. It is the same as calling db.Select with a Where.
 func (db *DB) Filter(query FilterQuery) ([]*Row, error) {
+	return db.Select(Where(query))
+}
+
+// Select searches the database for all rows which match the Query. If the query tests
+// any indexed columns, rows which cannot match are skipped using the cached values of
+// those columns. If it tests ordered columns, only rows in the matching range of values
+// are visited at all. Otherwise, rows are decoded and checked against the query.
+func (db *DB) Select(query Query) ([]*Row, error) {
+	db.rlock()
+	defer db.runlock()
+
+	ids, ok := db.candidates(query)
+	if !ok {
+		ids = make([]uint64, 0, len(db.index))
+		for id := range db.index {
+			ids = append(ids, id)
+		}
+	}
+
+	return db.filterRows(ids, query)
+}
+
+// FilterSorted searches the database for all rows which match the Query, like Filter, and returns
+// them sorted by the value of the given column, in descending order if reverse is true. Rows with
+// equal values are sorted by ID. The column must be tagged with `simpledb:"indexed,ordered"`.
+func (db *DB) FilterSorted(query Query, columnName string, reverse bool) ([]*Row, error) {
+	db.rlock()
+	defer db.runlock()
+
+	index, ok := db.orderedIndices[columnName]
+	if !ok {
+		return nil, fmt.Errorf("column %q of table %q does not have an ordered index", columnName, db.name)
+	}
+
+	ids, ok := db.candidates(query)
+	if ok {
+		// only sort the rows which could match
+		values := db.customIndices[columnName]
+		sort.Slice(ids, func(i, j int) bool {
+			result := compareBtreeItems(
+				btreeItem{reflect.ValueOf(values[ids[i]]), ids[i]},
+				btreeItem{reflect.ValueOf(values[ids[j]]), ids[j]},
+			)
+			return (result < 0) != reverse
+		})
+	} else {
+		ids = make([]uint64, 0, len(db.index))
+		index.scan(keyRange{}, reverse, func(id uint64) bool {
+			ids = append(ids, id)
+			return true
+		})
+	}
+
+	return db.filterRows(ids, query)
+}
+
+// filterRows decodes each of the rows with the given IDs which could match the query,
+// and returns those which do, in the same order. It assumes the caller is handling the mutex.
+func (db *DB) filterRows(ids []uint64, query Query) ([]*Row, error) {
 	results := make([]*Row, 0)
 
-	db.mutex.Lock()
-	defer db.mutex.Unlock()
-
-nextRow:
-	for id, cursor := range db.index {
-		if len(db.customIndices) > 0 {
-			for fieldName, queryValue := range query {
-				if customIndex, ok := db.customIndices[fieldName]; ok {
-					// query is using an indexed field
-					if indexedValue, ok := customIndex[id]; !ok || !reflect.DeepEqual(queryValue, indexedValue) {
-						continue nextRow
-					}
-				}
-			}
+	for _, id := range ids {
+		cursor, ok := db.index[id]
+		if !ok || !db.mayMatch(query, id) {
+			continue
 		}
 
 		destPtr := reflect.New(db.schema.dataType).Interface()
@@ -44,7 +89,7 @@ nextRow:
 			return nil, err
 		}
 
-		if matchesFilterQuery(destPtr, query) {
+		if query.Match(destPtr) {
 			results = append(results, &Row{
 				Value: destPtr,
 				ID:    id,
@@ -54,14 +99,3 @@ nextRow:
 
 	return results, nil
 }
-
-func matchesFilterQuery(value interface{}, query FilterQuery) bool {
-	for columnName, columnValue := range query {
-		fieldValue := reflect.Indirect(reflect.ValueOf(value)).FieldByName(columnName)
-		if !reflect.DeepEqual(fieldValue.Interface(), columnValue) {
-			return false
-		}
-	}
-
-	return true
-}
@@ -11,8 +11,8 @@ var ErrNotFound = errors.New("given ID was not found in the DB")
 // Find searches the DB index for the given id number. If the ID is not found, it returns ErrNotFound.
 // If a match is found, Find unmarshals the encoded value from the DB source into the given pointer.
 func (db *DB) Find(id uint64, destPtr interface{}) error {
-	db.mutex.Lock()
-	defer db.mutex.Unlock()
+	db.rlock()
+	defer db.runlock()
 
 	cursor, ok := db.index[id]
 	if !ok {
@@ -2,6 +2,9 @@ package simpledb
 
 // Has returns true if the given ID is stored in the DB's index.
 func (db *DB) Has(id uint64) bool {
+	db.mutex.RLock()
+	defer db.mutex.RUnlock()
+
 	_, ok := db.index[id]
 	return ok
 }
@@ -1,7 +1,7 @@
 package simpledb
 
 import (
-	"encoding/binary"
+	"fmt"
 	"io"
 	"reflect"
 )
@@ -19,7 +19,16 @@ func (db *DB) addToIndex(id uint64, cursor int64, decodeValue func() (interface{
 		valueReflected := reflect.Indirect(reflect.ValueOf(value))
 
 		for fieldName, customIndex := range db.customIndices {
-			customIndex[id] = valueReflected.FieldByName(fieldName).Interface()
+			if oldValue, ok := customIndex[id]; ok {
+				db.removeFieldFromIndex(fieldName, id, oldValue)
+			}
+
+			fieldValue := valueReflected.FieldByName(fieldName).Interface()
+			customIndex[id] = fieldValue
+			db.hashIndices[fieldName].add(id, fieldValue)
+			if orderedIndex, ok := db.orderedIndices[fieldName]; ok {
+				orderedIndex.add(id, fieldValue)
+			}
 		}
 	}
 
@@ -30,26 +39,78 @@ func (db *DB) addToIndex(id uint64, cursor int64, decodeValue func() (interface{
 func (db *DB) removeFromIndex(id uint64) {
 	delete(db.index, id)
 
-	for _, customIndex := range db.customIndices {
-		delete(customIndex, id)
+	for fieldName, customIndex := range db.customIndices {
+		if value, ok := customIndex[id]; ok {
+			db.removeFieldFromIndex(fieldName, id, value)
+		}
+	}
+}
+
+// removeFieldFromIndex removes the given value of an indexed field of the row with the given ID
+// from the indices of that field.
+func (db *DB) removeFieldFromIndex(fieldName string, id uint64, value interface{}) {
+	delete(db.customIndices[fieldName], id)
+	db.hashIndices[fieldName].remove(id, value)
+	if orderedIndex, ok := db.orderedIndices[fieldName]; ok {
+		orderedIndex.remove(id, value)
 	}
 }
 
-// PopulateIndex reads through the underlying database source to populate the in-memory index.
+// PopulateIndex reads through the underlying database source to populate the in-memory index of
+// every table stored in it. If the source begins with a file header, PopulateIndex validates the
+// columns recorded in the header against the schema of each table opened so far, returning a
+// *SchemaMismatchError if they differ.
 func (db *DB) PopulateIndex() error {
 	db.mutex.Lock()
 	defer db.mutex.Unlock()
+	return db.populateIndex()
+}
+
+// populateIndex populates the in-memory indices of every table. It assumes the caller is handling the mutex.
+func (file *dbFile) populateIndex() error {
+	sourceSize, err := file.source.Seek(0, io.SeekEnd)
+	if err != nil {
+		return err
+	}
+
+	if _, err := file.source.Seek(0, io.SeekStart); err != nil {
+		return err
+	}
+
+	header, err := readFileHeader(file.source)
+	if err != nil {
+		return err
+	}
+
+	// Files written before headers were introduced store a single unnamed table.
+	if header.size == 0 && sourceSize > 0 {
+		header.tables = []TableColumns{{Name: ""}}
+		for _, table := range file.tables {
+			if table.name == "" && table.schema != nil && usesBinaryMarshaler(table.schema.dataType) {
+				return errHeaderlessBinaryMarshaler
+			}
+		}
+	}
 
-	if _, err := db.source.Seek(0, io.SeekStart); err != nil {
+	if err := file.reconcileTables(header.tables); err != nil {
 		return err
 	}
+	file.version = header.version
+	file.headerBodySize = header.bodySize
+
+	if file.indexSnapshot != nil {
+		if loaded, err := file.loadIndexSnapshot(); err != nil || loaded {
+			return err
+		}
+	}
 
-	db.index = make(map[uint64]int64)
+	if _, err := file.source.Seek(header.size, io.SeekStart); err != nil {
+		return err
+	}
 
-	offset := int64(0)
-	byteReader := &wrappedByteReader{db.source}
+	offset := header.size
 	for {
-		id, err := decodeUint64(db.source)
+		rowHeader, err := file.readRowHeader(file.source)
 		if err != nil {
 			// END of DB
 			if err == io.EOF {
@@ -58,28 +119,87 @@ func (db *DB) PopulateIndex() error {
 			return err
 		}
 
-		size, err := binary.ReadUvarint(byteReader)
-		if err != nil {
-			return err
+		if rowHeader.table >= uint64(len(file.tables)) {
+			return fmt.Errorf("row at offset %d belongs to unknown table number %d", offset, rowHeader.table)
 		}
+		table := file.tables[rowHeader.table]
+
+		// Dropped rows are skipped entirely; their data has been zeroed.
+		if rowHeader.id != DeletedID {
+			decodeValue := func() (interface{}, error) {
+				destPtr := reflect.New(table.schema.dataType).Interface()
+				if _, err := table.decodeRow(file.source, rowHeader.size, destPtr); err != nil {
+					return nil, err
+				}
+				return destPtr, nil
+			}
 
-		decodeValue := func() (interface{}, error) {
-			destPtr := reflect.New(db.schema.dataType).Interface()
-			if _, err := db.schema.Decode(db.source, destPtr); err != nil {
-				return nil, err
+			if err := table.addToIndex(rowHeader.id, offset, decodeValue); err != nil {
+				return err
 			}
-			return destPtr, nil
 		}
 
-		if err := db.addToIndex(id, offset, decodeValue); err != nil {
+		offset += int64(rowHeader.size) + rowHeader.length
+
+		if _, err = file.source.Seek(offset, io.SeekStart); err != nil {
 			return err
 		}
+	}
+}
+
+// reconcileTables numbers the tables opened so far according to the given tables from the file header,
+// validating their schemas. Tables which are recorded in the file header but have not been opened are
+// tracked without a schema, so that their rows can be preserved. The index of every table is cleared.
+func (file *dbFile) reconcileTables(headerTables []TableColumns) error {
+	tables := make([]*DB, 0, len(headerTables)+len(file.tables))
+	included := make(map[*DB]bool)
+
+	findTable := func(name string) *DB {
+		for _, table := range file.tables {
+			if table.name == name {
+				return table
+			}
+		}
+		return nil
+	}
 
-		rowHeaderSize := int64(len(encodeUvarint(size))) + 8
-		offset += int64(size) + rowHeaderSize
+	for _, headerTable := range headerTables {
+		table := findTable(headerTable.Name)
+		if table == nil || table.schema == nil {
+			table = &DB{
+				dbFile:  file,
+				name:    headerTable.Name,
+				columns: headerTable.Columns,
+			}
+		} else if headerTable.Columns != nil {
+			if err := table.validateColumns(headerTable.Columns); err != nil {
+				return err
+			}
+		}
+		tables = append(tables, table)
+		included[table] = true
+	}
 
-		if _, err = db.source.Seek(offset, io.SeekStart); err != nil {
-			return err
+	// tables which have been opened, but are not yet recorded in the file header
+	for _, table := range file.tables {
+		if table.schema != nil && !included[table] {
+			tables = append(tables, table)
 		}
 	}
+
+	for i, table := range tables {
+		table.number = uint64(i)
+		table.index = make(map[uint64]int64)
+		for fieldName := range table.customIndices {
+			table.customIndices[fieldName] = make(map[uint64]interface{})
+			table.hashIndices[fieldName] = make(hashIndex)
+		}
+		for fieldName := range table.orderedIndices {
+			table.orderedIndices[fieldName] = new(orderedIndex)
+		}
+	}
+
+	file.tables = tables
+	file.headerTables = len(headerTables)
+	return nil
 }
@@ -1,10 +1,5 @@
 package simpledb
 
-import (
-	"bytes"
-	"io"
-)
-
 // newID generates a new ID number which is not already present in the DB.
 func (db *DB) newID() uint64 {
 	var id uint64
@@ -13,67 +8,69 @@ func (db *DB) newID() uint64 {
 
 		// Make sure IDs are unique
 		if _, ok := db.index[id]; ok {
-			continue
+			id = DeletedID
 		}
 	}
 	return id
 }
 
-func (db *DB) insert(value interface{}, id uint64) error {
+// Insert inserts a given value into the DB. The value must be the same
+// struct type that was given to NewDB or db.ReflectSchema most recently.
+// The value can be a value or a pointer to a value, of that type. If another row already holds the
+// same value in a unique column, it returns a *UniqueViolationError.
+func (db *DB) Insert(value interface{}) (uint64, error) {
+	if db.readOnly {
+		return DeletedID, ErrReadOnly
+	}
+
 	db.mutex.Lock()
 	defer db.mutex.Unlock()
 
-	cursor, err := db.source.Seek(0, io.SeekEnd)
+	b, err := db.newBatch()
 	if err != nil {
-		return err
+		return DeletedID, err
 	}
 
-	buf := new(bytes.Buffer)
-	bytesWritten, err := db.schema.Encode(buf, value)
-	if err != nil {
-		return err
+	id := db.newID()
+	if err := db.insertInto(b, value, id); err != nil {
+		return DeletedID, err
 	}
 
-	rowHeader := encodeRowHeader(id, uint64(bytesWritten))
-	if _, err := db.source.Write(rowHeader); err != nil {
-		return err
+	if err := db.commit(b); err != nil {
+		return DeletedID, err
 	}
 
-	if _, err := buf.WriteTo(db.source); err != nil {
-		return err
-	}
+	return id, nil
+}
 
-	err = db.addToIndex(id, cursor, func() (interface{}, error) {
-		return value, nil
-	})
-	if err != nil {
-		return err
+// Update drops the given row and reinserts a new one with the same ID. If the DB has
+// a journal, the drop and reinsertion are committed together as one atomic operation.
+// If the row does not exist, it returns ErrNotFound. If another row already holds the same value
+// in a unique column, it returns a *UniqueViolationError.
+func (db *DB) Update(id uint64, value interface{}) error {
+	if db.readOnly {
+		return ErrReadOnly
 	}
 
-	return nil
-}
+	db.mutex.Lock()
+	defer db.mutex.Unlock()
 
-// Insert inserts a given value into the DB. The value must be the same
-// struct type that was given to NewDB or db.ReflectSchema most recently.
-// The value can be a value or a pointer to a value, of that type.
-func (db *DB) Insert(value interface{}) (uint64, error) {
-	id := db.newID()
-	if err := db.insert(value, id); err != nil {
-		return DeletedID, err
+	if _, ok := db.index[id]; !ok || id == DeletedID {
+		return ErrNotFound
 	}
 
-	return id, nil
-}
+	b, err := db.newBatch()
+	if err != nil {
+		return err
+	}
 
-// Update drops the given row and reinserts a new one with the same ID.
-func (db *DB) Update(id uint64, value interface{}) error {
-	if err := db.Drop(id); err != nil {
+	if err := db.dropInto(b, id); err != nil {
 		return err
 	}
 
-	if err := db.insert(value, id); err != nil {
+	if err := db.insertInto(b, value, id); err != nil {
 		return err
 	}
 
-	return nil
+	return db.commit(b)
 }
@@ -2,6 +2,7 @@ package simpledb
 
 import (
 	"reflect"
+	"sync"
 )
 
 // RowGenerator is a generator function which yields a new Row, and a possible decoding
@@ -11,44 +12,61 @@ type RowGenerator = func() (*Row, error)
 // Iterate returns a RowGenerator function which can be used to iterate over every row currently in the database.
 // The returned generator caches every ID currently in the DB and decodes a new one each time it is called.
 // If a row is dropped from the DB before the generator can reach it, the generator will ignore that row.
-func (db *DB) Iterate() RowGenerator {
+// The generator can be shared by several goroutines, in which case each row is yielded to only one of them.
+//
+// If any queries are given, the generator only yields rows which match all of them. Rows which cannot
+// match are skipped without being decoded, where the queries test indexed columns, and only rows in
+// the matching range of values are visited, where the queries test ordered columns.
+func (db *DB) Iterate(queries ...Query) RowGenerator {
+	query := And(queries...)
+
 	// Pull all ids ahead of time to prevent concurrent map read/writes
-	ids := make([]uint64, db.RowCount())
-	i := 0
-	for id, _ := range db.index {
-		ids[i] = id
-		i += 1
+	db.rlock()
+	ids, ok := db.candidates(query)
+	if !ok {
+		ids = make([]uint64, 0, len(db.index))
+		for id := range db.index {
+			ids = append(ids, id)
+		}
 	}
+	db.runlock()
 
-	i = 0
-	var iter RowGenerator
-	iter = func() (*Row, error) {
-		if i >= len(ids) {
-			return nil, nil
-		}
+	// readers share the DB's lock, so the generator's position needs its own
+	var iterMutex sync.Mutex
+	i := 0
+	iter := func() (*Row, error) {
+		iterMutex.Lock()
+		defer iterMutex.Unlock()
 
-		db.mutex.Lock()
-		defer db.mutex.Unlock()
+		db.rlock()
+		defer db.runlock()
 
-		id := ids[i]
-		cursor, ok := db.index[id]
-		if !ok {
-			// row was dropped, continue to next row
-			i += 1
-			return iter()
-		}
+		for ; i < len(ids); i += 1 {
+			id := ids[i]
+			cursor, ok := db.index[id]
+			if !ok || !db.mayMatch(query, id) {
+				// row was dropped or cannot match, continue to next row
+				continue
+			}
 
-		valuePtr := reflect.New(db.schema.dataType).Interface()
+			valuePtr := reflect.New(db.schema.dataType).Interface()
 
-		err := db.decodeAt(cursor, valuePtr)
-		if err != nil {
-			return nil, err
-		}
+			err := db.decodeAt(cursor, valuePtr)
+			if err != nil {
+				return nil, err
+			}
+
+			if !query.Match(valuePtr) {
+				continue
+			}
 
-		row := &Row{ID: id, Value: valuePtr}
-		i += 1
+			row := &Row{ID: id, Value: valuePtr}
+			i += 1
+
+			return row, nil
+		}
 
-		return row, nil
+		return nil, nil
 	}
 
 	return iter
@@ -0,0 +1,119 @@
+package simpledb
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"reflect"
+)
+
+// MigrationFunc is an optional function passed to db.Migrate, which is called for every row in the DB.
+// It receives a pointer to the row decoded with the old struct type, and a pointer to the row which will
+// be written with the new struct type. Columns present in both types have already been copied across
+// when it is called. If it returns an error, the migration is aborted.
+type MigrationFunc = func(oldPtr, newPtr interface{}) error
+
+// Migrate rewrites every row in the DB table from the schema of oldExample to the schema of newExample. The DB
+// must have been opened with the same struct type as oldExample. Columns which appear in both struct types
+// are copied into the new row, converting numeric types (or strings and byte slices) where the type of the
+// column has changed. Columns which appear only in newExample are left as zero values, and columns which
+// appear only in oldExample are dropped. If transform is not nil, it is called on each row to fill in
+// any other values in the new row. Rows of other tables stored in the same source are left unchanged.
+//
+// Like Defrag, Migrate writes the migrated DB to a temporary file in os.TempDir() before copying it back
+// to the DB source, so the source is not modified unless every row is migrated without error. If the DB has
+// a journal, the migrated DB is recorded in it before being copied back, so that a crash while copying is
+// recovered from when the DB is next opened. Without a journal, such a crash can corrupt the DB. Upon success,
+// the DB's schema is set to that of newExample, and the DB should be opened with newExample from then on.
+// Migrated rows are written with simpledb's reflection-based binary encoding, so if the DB was opened with
+// WithCodec, it must be reopened without it, or with a Codec for newExample which reads that encoding.
+func (db *DB) Migrate(oldExample, newExample interface{}, transform MigrationFunc) error {
+	if db.readOnly {
+		return ErrReadOnly
+	}
+
+	db.mutex.Lock()
+	defer db.mutex.Unlock()
+
+	if oldType := reflect.TypeOf(oldExample); oldType != db.schema.dataType {
+		return fmt.Errorf("old struct type '%s' passed to Migrate does not match DB schema type '%s'", oldType, db.schema.dataType)
+	}
+
+	newSchema := new(tableSchema)
+	if err := newSchema.Reflect(newExample); err != nil {
+		return err
+	}
+
+	tables := db.tableColumns()
+	tables[db.number].Columns = newSchema.Columns()
+
+	err := db.rewrite(tables, func(table *DB, w io.Writer, offset int64) (map[uint64]int64, error) {
+		if table != db {
+			return db.copyRows(table, w, offset)
+		}
+
+		newIndex := make(map[uint64]int64)
+
+		for id, cursor := range db.index {
+			oldPtr := reflect.New(db.schema.dataType)
+			if err := db.decodeAt(cursor, oldPtr.Interface()); err != nil {
+				return nil, err
+			}
+
+			newPtr := reflect.New(newSchema.dataType)
+			migrateColumns(reflect.Indirect(newPtr), reflect.Indirect(oldPtr))
+
+			if transform != nil {
+				if err := transform(oldPtr.Interface(), newPtr.Interface()); err != nil {
+					return nil, err
+				}
+			}
+
+			buf := new(bytes.Buffer)
+			bytesWritten, err := newSchema.Encode(buf, newPtr.Interface())
+			if err != nil {
+				return nil, err
+			}
+
+			rowHeader := encodeTableRowHeader(id, db.number, uint64(bytesWritten))
+			if _, err := w.Write(rowHeader); err != nil {
+				return nil, err
+			}
+			if _, err := buf.WriteTo(w); err != nil {
+				return nil, err
+			}
+
+			newIndex[id] = offset
+			offset += int64(bytesWritten) + int64(len(rowHeader))
+		}
+
+		return newIndex, nil
+	})
+	if err != nil {
+		return err
+	}
+
+	if err := db.ReflectSchema(newExample); err != nil {
+		return err
+	}
+
+	// rebuild custom indices for the new schema
+	return db.populateIndex()
+}
+
+// migrateColumns copies the exported fields of oldValue into the fields of newValue with the same name.
+func migrateColumns(newValue, oldValue reflect.Value) {
+	for _, fieldName := range getExportedFieldNames(newValue.Type()) {
+		oldField := oldValue.FieldByName(fieldName)
+		if !oldField.IsValid() {
+			continue
+		}
+
+		newField := newValue.FieldByName(fieldName)
+		if oldField.Type().AssignableTo(newField.Type()) {
+			newField.Set(oldField)
+		} else if isConvertibleColumnType(oldField.Type(), newField.Type()) {
+			newField.Set(oldField.Convert(newField.Type()))
+		}
+	}
+}
@@ -0,0 +1,110 @@
+package simpledb
+
+import (
+	"errors"
+	"os"
+	"testing"
+)
+
+func TestMigrate(t *testing.T) {
+	type UserV1 struct {
+		Name  string `simpledb:"indexed"`
+		Age   uint16
+		Notes string
+	}
+
+	type UserV2 struct {
+		Name     string `simpledb:"indexed"`
+		Age      uint32
+		Email    string
+		Initials []byte
+	}
+
+	tempFile, err := os.CreateTemp(os.TempDir(), "simpledb-")
+	if err != nil {
+		t.Fatalf("Failed to create temp file: %s", err)
+	}
+
+	t.Cleanup(func() {
+		tempFile.Close()
+		os.Remove(tempFile.Name())
+	})
+
+	db, err := NewDB(tempFile, UserV1{})
+	if err != nil {
+		t.Fatalf("failed to create DB: %s", err)
+	}
+
+	id1, err := db.Insert(UserV1{Name: "James", Age: 40, Notes: "spy"})
+	if err != nil {
+		t.Fatalf("Failed to insert user: %s", err)
+	}
+	id2, err := db.Insert(UserV1{Name: "Moneypenny", Age: 30})
+	if err != nil {
+		t.Fatalf("Failed to insert user: %s", err)
+	}
+	if err := db.Drop(id2); err != nil {
+		t.Fatalf("Failed to drop user: %s", err)
+	}
+
+	if err := db.Migrate(UserV2{}, UserV2{}, nil); err == nil {
+		t.Fatalf("expected error when migrating with wrong old struct type")
+	}
+
+	failure := errors.New("transform failed")
+	err = db.Migrate(UserV1{}, UserV2{}, func(oldPtr, newPtr interface{}) error {
+		return failure
+	})
+	if err != failure {
+		t.Fatalf("expected transform error to abort migration, got: %v", err)
+	}
+
+	var user1 UserV1
+	if err := db.Find(id1, &user1); err != nil || user1.Name != "James" {
+		t.Fatalf("aborted migration should leave DB intact: %v", err)
+	}
+
+	err = db.Migrate(UserV1{}, UserV2{}, func(oldPtr, newPtr interface{}) error {
+		oldUser := oldPtr.(*UserV1)
+		newUser := newPtr.(*UserV2)
+		newUser.Email = oldUser.Notes + "@mi6.gov.uk"
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("Failed to migrate DB: %s", err)
+	}
+
+	if db.RowCount() != 1 {
+		t.Fatalf("expected 1 row after migrating, got %d", db.RowCount())
+	}
+
+	check := func(db *DB) {
+		var user UserV2
+		if err := db.Find(id1, &user); err != nil {
+			t.Fatalf("Failed to find migrated user: %s", err)
+		}
+		if user.Name != "James" || user.Age != 40 || user.Email != "spy@mi6.gov.uk" || len(user.Initials) != 0 {
+			t.Fatalf("migrated user does not match: %+v", user)
+		}
+
+		rows, err := db.Filter(FilterQuery{"Name": "James"})
+		if err != nil {
+			t.Fatalf("Failed to filter migrated users: %s", err)
+		} else if len(rows) != 1 {
+			t.Fatalf("expected to find 1 migrated user, got %d", len(rows))
+		}
+	}
+
+	check(db)
+
+	var mismatch *SchemaMismatchError
+	if _, err := NewDB(tempFile, UserV1{}); !errors.As(err, &mismatch) {
+		t.Fatalf("expected SchemaMismatchError opening migrated DB with old struct type, got: %v", err)
+	}
+
+	db, err = NewDB(tempFile, UserV2{})
+	if err != nil {
+		t.Fatalf("Failed to reopen migrated DB: %s", err)
+	}
+	check(db)
+}
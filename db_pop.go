@@ -6,6 +6,10 @@ import "reflect"
 // The result is not set in destPtr until the row is decoded & dropped
 // cleanly from the database.
 func (db *DB) Pop(id uint64, destPtr interface{}) error {
+	if db.readOnly {
+		return ErrReadOnly
+	}
+
 	db.mutex.Lock()
 	defer db.mutex.Unlock()
 
@@ -0,0 +1,119 @@
+package simpledb
+
+import (
+	"errors"
+	"fmt"
+	"io"
+)
+
+// ErrReadOnly is returned by calls which would modify a DB opened with OpenReadOnly, or with a shared lock.
+var ErrReadOnly = errors.New("DB was opened read-only")
+
+// readOnlySource is a Source which reads from an io.ReadSeeker, and fails every write with ErrReadOnly.
+type readOnlySource struct {
+	io.ReadSeeker
+
+	// underlying is the value given to OpenReadOnly, which is closed along with the source
+	// if it implements io.Closer.
+	underlying interface{}
+}
+
+// readOnlySourceAt is a readOnlySource which also supports positional reads.
+type readOnlySourceAt struct {
+	readOnlySource
+	io.ReaderAt
+}
+
+func (source readOnlySource) Write(p []byte) (int, error) {
+	return 0, ErrReadOnly
+}
+
+func (source readOnlySource) Truncate(size int64) error {
+	return ErrReadOnly
+}
+
+func (source readOnlySource) Close() error {
+	if closer, ok := source.underlying.(io.Closer); ok {
+		return closer.Close()
+	}
+	return nil
+}
+
+// unwrapSource returns the value given to OpenReadOnly.
+func (source readOnlySource) unwrapSource() interface{} {
+	return source.underlying
+}
+
+// OpenReadOnly opens a DB on the given source without ever writing to it, which must be either an io.ReadSeeker
+// or an io.ReaderAt, such as an *os.File opened with os.Open, or a *bytes.Reader. Otherwise, it behaves like
+// NewDB. Calls which would modify the DB, such as Insert, Update, Drop, Pop, Defrag and Migrate, return ErrReadOnly.
+// If the source implements io.ReaderAt, reads can run concurrently, and it is never seeked.
+//
+// If the source is an *os.File, OpenReadOnly takes a shared lock on it by default, so that other read-only
+// processes can open the DB at the same time, but not a process writing to it (see WithLock). The source is
+// closed when the DB is closed, if it implements io.Closer. WithJournal cannot be used with OpenReadOnly,
+// as recovering the journal would write to the source.
+func OpenReadOnly(source interface{}, exampleValue interface{}, options ...Option) (*DB, error) {
+	var wrapped Source
+	if readerAt, ok := source.(io.ReaderAt); ok {
+		size, err := readerAtSize(readerAt)
+		if err != nil {
+			return nil, err
+		}
+		section := io.NewSectionReader(readerAt, 0, size)
+		wrapped = readOnlySourceAt{readOnlySource{section, source}, section}
+	} else if readSeeker, ok := source.(io.ReadSeeker); ok {
+		wrapped = readOnlySource{readSeeker, source}
+	} else {
+		return nil, fmt.Errorf("source of type %T passed to OpenReadOnly is neither an io.ReadSeeker nor an io.ReaderAt", source)
+	}
+
+	return openDB(&dbFile{source: wrapped, readOnly: true, lockMode: LockShared}, exampleValue, options)
+}
+
+// readerAtSize returns the size of the data readable from r, found with its Size or Seek methods if it
+// has them, or otherwise by searching for the first offset from which no data can be read.
+func readerAtSize(r io.ReaderAt) (int64, error) {
+	if sizer, ok := r.(interface{ Size() int64 }); ok {
+		return sizer.Size(), nil
+	} else if seeker, ok := r.(io.Seeker); ok {
+		return seeker.Seek(0, io.SeekEnd)
+	}
+
+	buf := make([]byte, 1)
+	readable := func(offset int64) (bool, error) {
+		n, err := r.ReadAt(buf, offset)
+		if n == 1 {
+			return true, nil
+		} else if err == io.EOF {
+			return false, nil
+		}
+		return false, err
+	}
+
+	// find an upper bound on the size, and then search below it
+	high := int64(1)
+	for {
+		ok, err := readable(high - 1)
+		if err != nil {
+			return 0, err
+		} else if !ok {
+			break
+		}
+		high *= 2
+	}
+
+	low := high / 2
+	for low < high {
+		middle := low + (high-low)/2
+		ok, err := readable(middle)
+		if err != nil {
+			return 0, err
+		} else if ok {
+			low = middle + 1
+		} else {
+			high = middle
+		}
+	}
+	return low, nil
+}
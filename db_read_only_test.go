@@ -0,0 +1,179 @@
+package simpledb
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"os"
+	"testing"
+)
+
+// readerAtOnly hides every method of a *bytes.Reader except ReadAt.
+type readerAtOnly struct {
+	r *bytes.Reader
+}
+
+func (r readerAtOnly) ReadAt(p []byte, offset int64) (int, error) {
+	return r.r.ReadAt(p, offset)
+}
+
+// readSeekerOnly hides the ReadAt method of a *bytes.Reader.
+type readSeekerOnly struct {
+	io.ReadSeeker
+}
+
+func TestOpenReadOnly(t *testing.T) {
+	type Car struct {
+		Make string `simpledb:"indexed"`
+		Year uint16 `simpledb:"indexed,ordered"`
+	}
+
+	tempFile, err := os.CreateTemp(os.TempDir(), "simpledb-")
+	if err != nil {
+		t.Fatalf("Failed to create temp file: %s", err)
+	}
+
+	t.Cleanup(func() {
+		tempFile.Close()
+		os.Remove(tempFile.Name())
+	})
+
+	db, err := NewDB(tempFile, Car{})
+	if err != nil {
+		t.Fatalf("failed to create DB: %s", err)
+	}
+	mazdaID, err := db.Insert(Car{Make: "Mazda", Year: 2008})
+	if err != nil {
+		t.Fatalf("Failed to insert car: %s", err)
+	}
+	for _, car := range []Car{{"Ford", 1999}, {"Ford", 2012}, {"Toyota", 2004}} {
+		if _, err := db.Insert(car); err != nil {
+			t.Fatalf("Failed to insert car: %s", err)
+		}
+	}
+	droppedID, err := db.Insert(Car{Make: "Honda", Year: 2020})
+	if err != nil {
+		t.Fatalf("Failed to insert car: %s", err)
+	}
+	if err := db.Drop(droppedID); err != nil {
+		t.Fatalf("Failed to drop car: %s", err)
+	}
+	if err := db.Close(); err != nil {
+		t.Fatalf("Failed to close DB: %s", err)
+	}
+
+	contents, err := os.ReadFile(tempFile.Name())
+	if err != nil {
+		t.Fatalf("Failed to read DB file: %s", err)
+	}
+
+	check := func(db *DB) {
+		if n := db.RowCount(); n != 4 {
+			t.Fatalf("expected 4 rows, got %d", n)
+		}
+		if !db.Has(mazdaID) || db.Has(droppedID) {
+			t.Fatalf("Has returned incorrect results")
+		}
+
+		var car Car
+		if err := db.Find(mazdaID, &car); err != nil {
+			t.Fatalf("Failed to find car: %s", err)
+		} else if car != (Car{"Mazda", 2008}) {
+			t.Fatalf("found car does not match: %+v", car)
+		}
+
+		rows, err := db.FilterSorted(Where{"Make": "Ford"}, "Year", false)
+		if err != nil {
+			t.Fatalf("Failed to filter cars: %s", err)
+		} else if len(rows) != 2 || rows[0].Value.(*Car).Year != 1999 {
+			t.Fatalf("unexpected filter results: %v", rows)
+		}
+
+		next := db.Iterate()
+		count := 0
+		for row, err := next(); row != nil || err != nil; row, err = next() {
+			if err != nil {
+				t.Fatalf("Failed to iterate: %s", err)
+			}
+			count += 1
+		}
+		if count != 4 {
+			t.Fatalf("expected to iterate over 4 rows, got %d", count)
+		}
+
+		expectReadOnly := func(name string, err error) {
+			if !errors.Is(err, ErrReadOnly) {
+				t.Fatalf("expected ErrReadOnly from %s, got %v", name, err)
+			}
+		}
+
+		_, err = db.Insert(Car{Make: "Kia"})
+		expectReadOnly("Insert", err)
+		expectReadOnly("Update", db.Update(mazdaID, Car{Make: "Kia"}))
+		expectReadOnly("Drop", db.Drop(mazdaID))
+		expectReadOnly("Pop", db.Pop(mazdaID, &car))
+		expectReadOnly("Defrag", db.Defrag())
+		expectReadOnly("Migrate", db.Migrate(Car{}, Car{}, nil))
+
+		tx := db.Begin()
+		if _, err := tx.Insert(Car{Make: "Kia"}); err != nil {
+			t.Fatalf("Failed to insert car in transaction: %s", err)
+		}
+		expectReadOnly("tx.Commit", tx.Commit())
+
+		if !db.Has(mazdaID) {
+			t.Fatalf("expected row to remain after failed writes")
+		}
+		if err := db.Close(); err != nil {
+			t.Fatalf("Failed to close DB: %s", err)
+		}
+	}
+
+	file, err := os.Open(tempFile.Name())
+	if err != nil {
+		t.Fatalf("Failed to open DB file: %s", err)
+	}
+
+	sources := []interface{}{
+		file,
+		bytes.NewReader(contents),
+		readerAtOnly{bytes.NewReader(contents)},
+		readSeekerOnly{bytes.NewReader(contents)},
+	}
+	for _, source := range sources {
+		db, err := OpenReadOnly(source, Car{})
+		if err != nil {
+			t.Fatalf("Failed to open %T read-only: %s", source, err)
+		}
+		check(db)
+	}
+
+	if after, _ := os.ReadFile(tempFile.Name()); !bytes.Equal(after, contents) {
+		t.Fatalf("expected read-only DB not to modify the file")
+	}
+
+	if _, err := OpenReadOnly(bytes.NewBuffer(contents), Car{}); err == nil {
+		t.Fatalf("expected error opening a source which cannot seek")
+	}
+	if _, err := OpenReadOnly(bytes.NewReader(contents), Car{}, WithJournal(tempFile)); err == nil {
+		t.Fatalf("expected error opening a read-only DB with a journal")
+	}
+
+	type Other struct {
+		Name string
+	}
+	if _, err := OpenReadOnly(bytes.NewReader(contents), Other{}); err == nil {
+		t.Fatalf("expected error opening a read-only DB with the wrong schema")
+	}
+}
+
+func TestReaderAtSize(t *testing.T) {
+	for _, size := range []int{0, 1, 2, 3, 7, 8, 9, 1000, 1024} {
+		r := readerAtOnly{bytes.NewReader(make([]byte, size))}
+		if found, err := readerAtSize(r); err != nil {
+			t.Fatalf("Failed to find size: %s", err)
+		} else if found != int64(size) {
+			t.Errorf("expected size %d, got %d", size, found)
+		}
+	}
+}
@@ -0,0 +1,177 @@
+package simpledb
+
+import (
+	"fmt"
+	"io"
+	"os"
+)
+
+// tableWriter writes the rows of the given table to w, the first of which is written at the given
+// offset in the new DB source. It returns the new index of the rows it wrote.
+type tableWriter = func(table *DB, w io.Writer, offset int64) (map[uint64]int64, error)
+
+// rewrite replaces the contents of the DB source with a file header describing the given tables,
+// followed by the rows of each table written by writeRows. The new contents are first written to a
+// temporary file in os.TempDir(), so the source is not modified unless every row is written without
+// error. The tempfile is cleaned up even if a panic occurs. It assumes the caller is handling the mutex.
+//
+// The new contents are then copied over the DB source in place. If the DB has a journal, the new contents
+// are recorded in the journal first, so that a crash while copying them is recovered from by recoverJournal.
+// Otherwise, a crash while copying can leave the source holding a mix of its old and new contents.
+// If copying fails after the new contents are journaled, later writes return ErrRecoveryNeeded.
+func (file *dbFile) rewrite(tables []TableColumns, writeRows tableWriter) error {
+	if file.recoveryNeeded {
+		return ErrRecoveryNeeded
+	}
+
+	tempFile, err := os.CreateTemp(os.TempDir(), "simpledb-rewrite-")
+	if err != nil {
+		return fmt.Errorf("Failed to create temp file for rewrite: %s", err)
+	}
+
+	defer func() {
+		panicValue := recover()
+		tempFile.Close()
+		os.Remove(tempFile.Name())
+		if panicValue != nil {
+			panic(panicValue)
+		}
+	}()
+
+	header, bodySize, err := encodeFileHeader(tables, 0)
+	if err != nil {
+		return err
+	}
+	if _, err := tempFile.Write(header); err != nil {
+		return err
+	}
+
+	offset := int64(len(header))
+	newIndices := make([]map[uint64]int64, len(file.tables))
+	rowCount := 0
+
+	for i, table := range file.tables {
+		newIndices[i], err = writeRows(table, tempFile, offset)
+		if err != nil {
+			return err
+		}
+		rowCount += len(newIndices[i])
+
+		if offset, err = tempFile.Seek(0, io.SeekCurrent); err != nil {
+			return err
+		}
+	}
+
+	// An empty DB has zero size, without a file header.
+	if rowCount == 0 {
+		if err := tempFile.Truncate(0); err != nil {
+			return err
+		}
+	}
+
+	if err := file.invalidateIndexSnapshot(); err != nil {
+		return err
+	}
+
+	newSize, err := tempFile.Seek(0, io.SeekEnd)
+	if err != nil {
+		return err
+	}
+
+	if file.journal != nil {
+		if _, err = tempFile.Seek(0, io.SeekStart); err != nil {
+			return err
+		}
+		if err := file.journalRewrite(tempFile, newSize); err != nil {
+			return err
+		}
+	}
+
+	if err := file.replaceSource(tempFile, newSize); err != nil {
+		file.recoveryNeeded = file.journal != nil
+		return err
+	}
+
+	for i, table := range file.tables {
+		table.index = newIndices[i]
+	}
+
+	if rowCount == 0 {
+		file.version = 0
+		file.headerTables = 0
+		file.headerBodySize = 0
+	} else {
+		file.version = FormatVersion
+		file.headerTables = len(file.tables)
+		file.headerBodySize = bodySize
+	}
+
+	if file.journal != nil {
+		return file.clearJournal()
+	}
+	return nil
+}
+
+// replaceSource copies the given new contents of the DB source over it, and truncates it to newSize.
+// If the DB has a journal, the source is flushed to stable storage so that the journal can be cleared.
+func (file *dbFile) replaceSource(contents io.ReadSeeker, newSize int64) error {
+	if _, err := file.source.Seek(0, io.SeekStart); err != nil {
+		return err
+	}
+	if _, err := contents.Seek(0, io.SeekStart); err != nil {
+		return err
+	}
+	if _, err := io.Copy(file.source, contents); err != nil {
+		return err
+	}
+	if err := file.source.Truncate(newSize); err != nil {
+		return err
+	}
+
+	if file.journal != nil {
+		return syncSource(file.source)
+	}
+	return nil
+}
+
+// copyRows is a tableWriter which copies the rows of the given table to w unchanged.
+func (file *dbFile) copyRows(table *DB, w io.Writer, offset int64) (map[uint64]int64, error) {
+	newIndex := make(map[uint64]int64)
+
+	for id, cursor := range table.index {
+		if _, err := file.source.Seek(cursor, io.SeekStart); err != nil {
+			return nil, err
+		}
+
+		rowHeader, err := file.readRowHeader(file.source)
+		if err != nil {
+			return nil, err
+		}
+
+		newRowHeader := encodeTableRowHeader(id, table.number, rowHeader.size)
+		if _, err := w.Write(newRowHeader); err != nil {
+			return nil, err
+		}
+
+		if _, err := io.CopyN(w, file.source, int64(rowHeader.size)); err != nil {
+			return nil, err
+		}
+
+		newIndex[id] = offset
+		offset += int64(rowHeader.size) + int64(len(newRowHeader))
+	}
+
+	return newIndex, nil
+}
+
+// tableColumns returns the columns of every table in the DB source, indexed by table number.
+func (file *dbFile) tableColumns() []TableColumns {
+	tables := make([]TableColumns, len(file.tables))
+	for i, table := range file.tables {
+		tables[i] = TableColumns{
+			Name:    table.name,
+			Columns: table.columns,
+		}
+	}
+	return tables
+}
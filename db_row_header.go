@@ -0,0 +1,79 @@
+package simpledb
+
+import (
+	"bytes"
+	"encoding/binary"
+	"io"
+)
+
+// rowHeader is the decoded form of the header which precedes every row on-disk.
+type rowHeader struct {
+	id    uint64
+	table uint64
+	size  uint64
+
+	// length is the byte-size of the row header itself.
+	length int64
+}
+
+// encodeRowHeader encodes a row header in the format used by the DB source.
+func (file *dbFile) encodeRowHeader(id, table, size uint64) []byte {
+	if file.version < 2 {
+		return encodeRowHeader(id, size)
+	}
+	return encodeTableRowHeader(id, table, size)
+}
+
+// readRowHeader reads a row header from r in the format used by the DB source.
+// It returns io.EOF if r has no more rows.
+func (file *dbFile) readRowHeader(r io.Reader) (*rowHeader, error) {
+	id, err := decodeUint64(r)
+	if err != nil {
+		return nil, err
+	}
+
+	header := &rowHeader{id: id, length: 8}
+	byteReader := &wrappedByteReader{Reader: r}
+
+	if file.version >= 2 {
+		if header.table, err = binary.ReadUvarint(byteReader); err != nil {
+			return nil, err
+		}
+		header.length += int64(len(encodeUvarint(header.table)))
+	}
+
+	if header.size, err = binary.ReadUvarint(byteReader); err != nil {
+		return nil, err
+	}
+	header.length += int64(len(encodeUvarint(header.size)))
+
+	return header, nil
+}
+
+// maxRowHeaderLength is the greatest possible byte-size of a row header.
+const maxRowHeaderLength = 8 + 2*binary.MaxVarintLen64
+
+// readRowHeaderAt reads the row header at the given cursor in the DB source. If the source implements
+// io.ReaderAt, the source's cursor is not moved, and otherwise it is left at the end of the row header.
+func (file *dbFile) readRowHeaderAt(cursor int64) (*rowHeader, error) {
+	readerAt, ok := file.source.(io.ReaderAt)
+	if !ok {
+		if _, err := file.source.Seek(cursor, io.SeekStart); err != nil {
+			return nil, err
+		}
+		return file.readRowHeader(file.source)
+	}
+
+	// The row header may be shorter than maxRowHeaderLength, so io.EOF is expected at the end of the source.
+	buf := make([]byte, maxRowHeaderLength)
+	n, err := readerAt.ReadAt(buf, cursor)
+	if err != nil && !(err == io.EOF && n > 0) {
+		return nil, err
+	}
+
+	header, err := file.readRowHeader(bytes.NewReader(buf[:n]))
+	if err == io.EOF {
+		err = io.ErrUnexpectedEOF
+	}
+	return header, err
+}
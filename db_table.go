@@ -0,0 +1,99 @@
+package simpledb
+
+import (
+	"fmt"
+	"reflect"
+)
+
+// Table opens the table with the given name in the DB source, using exampleValue to define its schema in the same
+// way as NewDB. The returned DB shares its Source and mutex with db, but has its own schema and indices, so that
+// Insert, Find, Filter, Drop and other calls on it only affect rows of that table. Defrag and PopulateIndex calls
+// on any table apply to every table in the source.
+//
+// If the table is already recorded in the DB source's file header, Table validates its schema against that of
+// exampleValue, returning a *SchemaMismatchError if they differ. Otherwise, the table is added to the file
+// header when its first row is inserted, which overwrites the header in place if it has room for the table,
+// or otherwise rewrites the DB source like Defrag. The table opened by NewDB is named with an empty string.
+// Options which configure a single table, such as WithCodec, can be given to configure the new table. If the
+// table is already open, it is returned unchanged.
+//
+//  cars, err := db.Table("cars", Car{})
+//  if err != nil {
+//    panic(err)
+//  }
+func (db *DB) Table(name string, exampleValue interface{}, options ...Option) (*DB, error) {
+	db.mutex.Lock()
+	defer db.mutex.Unlock()
+
+	table := &DB{
+		dbFile: db.dbFile,
+		name:   name,
+		index:  make(map[uint64]int64),
+	}
+
+	if err := table.ReflectSchema(exampleValue); err != nil {
+		return nil, err
+	}
+
+	for _, option := range options {
+		if err := option(table); err != nil {
+			return nil, err
+		}
+	}
+
+	for i, existing := range db.tables {
+		if existing.name != name {
+			continue
+		}
+
+		if existing.schema != nil {
+			if existing.schema.dataType != table.schema.dataType {
+				return nil, fmt.Errorf("table %q is already open with struct type '%s'", name, existing.schema.dataType)
+			}
+			return existing, nil
+		}
+
+		// The table is recorded in the file header, and its rows have been indexed, but it has not been opened yet.
+		if existing.columns != nil {
+			if err := table.validateColumns(existing.columns); err != nil {
+				return nil, err
+			}
+		}
+
+		table.number = existing.number
+		if err := table.indexRows(existing.index); err != nil {
+			return nil, err
+		}
+
+		db.tables[i] = table
+		return table, nil
+	}
+
+	table.number = uint64(len(db.tables))
+	db.tables = append(db.tables, table)
+	return table, nil
+}
+
+// indexRows adds the rows at the given cursors to the table's index, decoding them if
+// the table has custom indices. It assumes the caller is handling db.mutex.
+func (db *DB) indexRows(index map[uint64]int64) error {
+	for id, cursor := range index {
+		decodeValue := func() (interface{}, error) {
+			destPtr := reflect.New(db.schema.dataType).Interface()
+			if err := db.decodeAt(cursor, destPtr); err != nil {
+				return nil, err
+			}
+			return destPtr, nil
+		}
+
+		if err := db.addToIndex(id, cursor, decodeValue); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Name returns the name of the DB table. The table opened by NewDB is named with an empty string.
+func (db *DB) Name() string {
+	return db.name
+}
@@ -0,0 +1,223 @@
+package simpledb
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestTables(t *testing.T) {
+	type User struct {
+		Name string
+	}
+	type Car struct {
+		Make string `simpledb:"indexed"`
+		Year uint16
+	}
+	type Session struct {
+		Token [4]byte
+	}
+
+	tempFile, err := os.CreateTemp(os.TempDir(), "simpledb-")
+	if err != nil {
+		t.Fatalf("Failed to create temp file: %s", err)
+	}
+
+	t.Cleanup(func() {
+		tempFile.Close()
+		os.Remove(tempFile.Name())
+	})
+
+	users, err := NewDB(tempFile, User{})
+	if err != nil {
+		t.Fatalf("failed to create DB: %s", err)
+	}
+
+	userID, err := users.Insert(User{Name: "bob"})
+	if err != nil {
+		t.Fatalf("Failed to insert user: %s", err)
+	}
+
+	cars, err := users.Table("cars", Car{})
+	if err != nil {
+		t.Fatalf("Failed to open cars table: %s", err)
+	}
+	if _, err := users.Table("cars", Session{}); err == nil {
+		t.Fatalf("expected error opening cars table again with a different struct type")
+	}
+	if sameCars, err := users.Table("cars", Car{}); err != nil || sameCars != cars {
+		t.Fatalf("expected opening cars table again to return the same table")
+	}
+
+	// inserting into a table not yet in the file header overwrites the header in place, without moving any rows
+	userCursor := users.index[userID]
+	carID, err := cars.Insert(Car{Make: "Mazda", Year: 2008})
+	if err != nil {
+		t.Fatalf("Failed to insert car: %s", err)
+	}
+	if users.index[userID] != userCursor {
+		t.Fatalf("expected recording cars table in file header not to move existing rows")
+	}
+	if _, err := cars.Insert(Car{Make: "Ford", Year: 1999}); err != nil {
+		t.Fatalf("Failed to insert car: %s", err)
+	}
+
+	sessions, err := cars.Table("sessions", Session{})
+	if err != nil {
+		t.Fatalf("Failed to open sessions table: %s", err)
+	}
+	sessionID, err := sessions.Insert(Session{Token: [4]byte{1, 2, 3, 4}})
+	if err != nil {
+		t.Fatalf("Failed to insert session: %s", err)
+	}
+
+	if users.RowCount() != 1 || cars.RowCount() != 2 || sessions.RowCount() != 1 {
+		t.Fatalf("unexpected row counts: %d, %d, %d", users.RowCount(), cars.RowCount(), sessions.RowCount())
+	}
+
+	var user User
+	if err := users.Find(userID, &user); err != nil || user.Name != "bob" {
+		t.Fatalf("Failed to find user after rewriting file header: %v", err)
+	}
+	if err := cars.Find(userID, new(Car)); err != ErrNotFound {
+		t.Fatalf("expected ErrNotFound when finding user ID in cars table, got: %v", err)
+	}
+
+	tempFile.Seek(0, io.SeekStart)
+	tableColumns, err := ReadTableColumns(tempFile)
+	if err != nil {
+		t.Fatalf("Failed to read table columns: %s", err)
+	}
+	expectedTableColumns := []TableColumns{
+		{Name: "", Columns: []Column{{Name: "Name", Type: "string"}}},
+		{Name: "cars", Columns: []Column{{Name: "Make", Type: "string"}, {Name: "Year", Type: "uint16"}}},
+		{Name: "sessions", Columns: []Column{{Name: "Token", Type: "[4]uint8"}}},
+	}
+	if !reflect.DeepEqual(tableColumns, expectedTableColumns) {
+		t.Fatalf("table columns do not match\nWanted %v\nGot    %v", expectedTableColumns, tableColumns)
+	}
+
+	// reopen without opening the sessions table; its rows must survive a defrag.
+	users, err = NewDB(tempFile, User{})
+	if err != nil {
+		t.Fatalf("Failed to reopen DB: %s", err)
+	}
+	cars, err = users.Table("cars", Car{})
+	if err != nil {
+		t.Fatalf("Failed to reopen cars table: %s", err)
+	}
+	if cars.RowCount() != 2 {
+		t.Fatalf("expected 2 cars after reopening, got %d", cars.RowCount())
+	}
+
+	rows, err := cars.Filter(FilterQuery{"Make": "Mazda"})
+	if err != nil {
+		t.Fatalf("Failed to filter cars: %s", err)
+	} else if len(rows) != 1 || rows[0].ID != carID {
+		t.Fatalf("unexpected filter results in reopened cars table")
+	}
+
+	if err := cars.Drop(carID); err != nil {
+		t.Fatalf("Failed to drop car: %s", err)
+	}
+	if err := users.Defrag(); err != nil {
+		t.Fatalf("Failed to defrag DB: %s", err)
+	}
+
+	var mismatch *SchemaMismatchError
+	if _, err := users.Table("sessions", Car{}); !errors.As(err, &mismatch) || mismatch.Table != "sessions" {
+		t.Fatalf("expected SchemaMismatchError opening sessions table with wrong type, got: %v", err)
+	}
+
+	sessions, err = users.Table("sessions", Session{})
+	if err != nil {
+		t.Fatalf("Failed to open sessions table after defrag: %s", err)
+	}
+
+	var session Session
+	if err := sessions.Find(sessionID, &session); err != nil || session.Token != [4]byte{1, 2, 3, 4} {
+		t.Fatalf("Failed to find session after defrag: %v", err)
+	}
+
+	if err := users.PopulateIndex(); err != nil {
+		t.Fatalf("Failed to populate index: %s", err)
+	}
+	if users.RowCount() != 1 || cars.RowCount() != 1 || sessions.RowCount() != 1 {
+		t.Fatalf("unexpected row counts after PopulateIndex: %d, %d, %d", users.RowCount(), cars.RowCount(), sessions.RowCount())
+	}
+}
+
+func TestTablesHeaderFull(t *testing.T) {
+	type Note struct {
+		Text string
+	}
+
+	tempFile, err := os.CreateTemp(os.TempDir(), "simpledb-")
+	if err != nil {
+		t.Fatalf("Failed to create temp file: %s", err)
+	}
+
+	t.Cleanup(func() {
+		tempFile.Close()
+		os.Remove(tempFile.Name())
+	})
+
+	db, err := NewDB(tempFile, Note{})
+	if err != nil {
+		t.Fatalf("failed to create DB: %s", err)
+	}
+	if _, err := db.Insert(Note{Text: "first"}); err != nil {
+		t.Fatalf("Failed to insert note: %s", err)
+	}
+
+	initialBodySize := db.headerBodySize
+	if initialBodySize != minHeaderBodySize {
+		t.Fatalf("expected file header body of %d bytes, got %d", minHeaderBodySize, initialBodySize)
+	}
+
+	// tables with long names soon fill the space left in the file header, which is then rewritten
+	ids := make(map[string]uint64)
+	for i := 0; i < 20; i++ {
+		name := fmt.Sprintf("%s%d", strings.Repeat("notes", 20), i)
+		table, err := db.Table(name, Note{})
+		if err != nil {
+			t.Fatalf("Failed to open table: %s", err)
+		}
+		if ids[name], err = table.Insert(Note{Text: name}); err != nil {
+			t.Fatalf("Failed to insert note: %s", err)
+		}
+	}
+
+	if db.headerBodySize <= initialBodySize {
+		t.Fatalf("expected file header to be rewritten with more space, got body of %d bytes", db.headerBodySize)
+	}
+
+	tempFile.Seek(0, io.SeekStart)
+	tables, err := ReadTableColumns(tempFile)
+	if err != nil {
+		t.Fatalf("Failed to read tables from file header: %s", err)
+	} else if len(tables) != 21 {
+		t.Fatalf("expected 21 tables in file header, got %d", len(tables))
+	}
+
+	db, err = NewDB(tempFile, Note{})
+	if err != nil {
+		t.Fatalf("Failed to reopen DB: %s", err)
+	}
+	for name, id := range ids {
+		table, err := db.Table(name, Note{})
+		if err != nil {
+			t.Fatalf("Failed to open table: %s", err)
+		}
+		var note Note
+		if err := table.Find(id, &note); err != nil {
+			t.Fatalf("Failed to find note in table %q: %s", name, err)
+		} else if note.Text != name {
+			t.Fatalf("found wrong note in table %q: %+v", name, note)
+		}
+	}
+}
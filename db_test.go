@@ -2,10 +2,14 @@ package simpledb
 
 import (
 	"bytes"
+	"errors"
+	"fmt"
 	"io"
 	"os"
+	"reflect"
 	"sync"
 	"testing"
+	"time"
 )
 
 func TestDB(t *testing.T) {
@@ -243,3 +247,889 @@ func TestDB(t *testing.T) {
 		}
 	})
 }
+
+func TestDroppedRowsAfterReopen(t *testing.T) {
+	type Session struct {
+		Email  string `simpledb:"indexed"`
+		Secret [8]byte
+	}
+
+	tempFile, err := os.CreateTemp(os.TempDir(), "simpledb-")
+	if err != nil {
+		t.Fatalf("Failed to create temp file: %s", err)
+	}
+
+	t.Cleanup(func() {
+		tempFile.Close()
+		os.Remove(tempFile.Name())
+	})
+
+	db, err := NewDB(tempFile, Session{})
+	if err != nil {
+		t.Fatalf("failed to create DB: %s", err)
+	}
+
+	ids := make([]uint64, 6)
+	for i := range ids {
+		ids[i], err = db.Insert(Session{Email: "foo@bar.com", Secret: [8]byte{byte(i + 1)}})
+		if err != nil {
+			t.Fatalf("Failed to insert session: %s", err)
+		}
+	}
+
+	for _, id := range ids[:3] {
+		if err := db.Drop(id); err != nil {
+			t.Fatalf("Failed to drop session: %s", err)
+		}
+	}
+	if err := db.Update(ids[3], Session{Email: "james@bond.com"}); err != nil {
+		t.Fatalf("Failed to update session: %s", err)
+	}
+
+	check := func(db *DB) {
+		if db.RowCount() != 3 {
+			t.Fatalf("expected 3 rows, got %d", db.RowCount())
+		}
+		if db.Has(DeletedID) {
+			t.Fatalf("dropped rows should not be indexed")
+		}
+		for _, id := range ids[:3] {
+			if db.Has(id) {
+				t.Fatalf("dropped row should not be present")
+			}
+		}
+
+		rows, err := db.Filter(map[string]interface{}{"Email": "foo@bar.com"})
+		if err != nil {
+			t.Fatalf("Failed to filter sessions: %s", err)
+		} else if len(rows) != 2 {
+			t.Fatalf("expected to filter 2 sessions, got %d", len(rows))
+		}
+
+		rows, err = db.Filter(map[string]interface{}{"Email": ""})
+		if err != nil {
+			t.Fatalf("Failed to filter sessions: %s", err)
+		} else if len(rows) != 0 {
+			t.Fatalf("expected no zeroed sessions to be filtered, got %d", len(rows))
+		}
+
+		iter := db.Iterate()
+		count := 0
+		for {
+			row, err := iter()
+			if err != nil {
+				t.Fatalf("failed to get next row: %s", err)
+			} else if row == nil {
+				break
+			}
+			if row.ID == DeletedID || row.Value.(*Session).Email == "" {
+				t.Fatalf("iterator returned dropped row")
+			}
+			count += 1
+		}
+		if count != 3 {
+			t.Fatalf("expected to iterate over 3 rows, got %d", count)
+		}
+	}
+
+	check(db)
+
+	db, err = NewDB(tempFile, Session{})
+	if err != nil {
+		t.Fatalf("Failed to reopen DB: %s", err)
+	}
+	check(db)
+
+	sizeBefore, _ := db.Size()
+	if err := db.Defrag(); err != nil {
+		t.Fatalf("Failed to defrag DB: %s", err)
+	}
+	sizeAfter, _ := db.Size()
+	if sizeAfter >= sizeBefore {
+		t.Fatalf("expected defrag to remove dropped rows")
+	}
+	check(db)
+
+	db, err = NewDB(tempFile, Session{})
+	if err != nil {
+		t.Fatalf("Failed to reopen DB after defrag: %s", err)
+	}
+	check(db)
+}
+
+func TestNestedStructColumns(t *testing.T) {
+	type Address struct {
+		Street string
+		City   string
+		Zip    uint32
+	}
+
+	type Audit struct {
+		Version uint8
+	}
+
+	type Person struct {
+		Audit
+		Name      string
+		Home      Address `simpledb:"indexed"`
+		Previous  []Address
+		Emergency [2]Address
+	}
+
+	tempFile, err := os.CreateTemp(os.TempDir(), "simpledb-")
+	if err != nil {
+		t.Fatalf("Failed to create temp file: %s", err)
+	}
+
+	t.Cleanup(func() {
+		tempFile.Close()
+		os.Remove(tempFile.Name())
+	})
+
+	db, err := NewDB(tempFile, Person{})
+	if err != nil {
+		t.Fatalf("failed to create DB: %s", err)
+	}
+
+	alice := Person{
+		Audit:     Audit{Version: 3},
+		Name:      "alice",
+		Home:      Address{Street: "1 Main St", City: "Springfield", Zip: 12345},
+		Previous:  []Address{{City: "Shelbyville"}, {City: "Ogdenville", Zip: 1}},
+		Emergency: [2]Address{{City: "Capital City"}},
+	}
+	aliceID, err := db.Insert(alice)
+	if err != nil {
+		t.Fatalf("Failed to insert person: %s", err)
+	}
+	if _, err := db.Insert(Person{Name: "bob", Home: Address{City: "Shelbyville"}, Previous: []Address{}}); err != nil {
+		t.Fatalf("Failed to insert person: %s", err)
+	}
+
+	tempFile.Seek(0, io.SeekStart)
+	columns, err := ReadColumns(tempFile)
+	if err != nil {
+		t.Fatalf("Failed to read columns: %s", err)
+	}
+	addressType := "struct{City string; Street string; Zip uint32}"
+	expectedColumns := []Column{
+		{Name: "Audit", Type: "struct{Version uint8}"},
+		{Name: "Emergency", Type: "[2]" + addressType},
+		{Name: "Home", Type: addressType},
+		{Name: "Name", Type: "string"},
+		{Name: "Previous", Type: "[]" + addressType},
+	}
+	if !reflect.DeepEqual(columns, expectedColumns) {
+		t.Fatalf("columns in file header do not match\nWanted %v\nGot    %v", expectedColumns, columns)
+	}
+
+	db, err = NewDB(tempFile, Person{})
+	if err != nil {
+		t.Fatalf("Failed to reopen DB: %s", err)
+	}
+
+	var found Person
+	if err := db.Find(aliceID, &found); err != nil {
+		t.Fatalf("Failed to find person: %s", err)
+	}
+	if !reflect.DeepEqual(found, alice) {
+		t.Fatalf("found person does not match\nWanted %+v\nGot    %+v", alice, found)
+	}
+
+	type Fixture struct {
+		query    FilterQuery
+		expected int
+	}
+
+	fixtures := []Fixture{
+		{FilterQuery{"Home.City": "Springfield"}, 1},
+		{FilterQuery{"Home.Zip": GreaterThan(10000)}, 1},
+		{FilterQuery{"Home": Address{City: "Shelbyville"}}, 1},
+		{FilterQuery{"Emergency": [2]Address{{City: "Capital City"}}}, 1},
+		{FilterQuery{"Version": uint8(3)}, 1},
+		{FilterQuery{"Audit.Version": uint8(0)}, 1},
+		{FilterQuery{"Home.Country": ""}, 0},
+		{FilterQuery{"Name.Length": 5}, 0},
+	}
+
+	for _, fixture := range fixtures {
+		rows, err := db.Filter(fixture.query)
+		if err != nil {
+			t.Fatalf("Failed to filter people: %s", err)
+		}
+		if len(rows) != fixture.expected {
+			t.Errorf("query %v returned %d rows, expected %d", fixture.query, len(rows), fixture.expected)
+		}
+	}
+
+	type WrongAddress struct {
+		City string
+		Zip  uint16
+	}
+	type WrongPerson struct {
+		Audit
+		Name      string
+		Home      WrongAddress
+		Previous  []Address
+		Emergency [2]Address
+	}
+
+	var mismatch *SchemaMismatchError
+	if _, err := NewDB(tempFile, WrongPerson{}); !errors.As(err, &mismatch) {
+		t.Fatalf("expected SchemaMismatchError opening DB with a different nested struct type, got: %v", err)
+	}
+}
+
+func TestMapColumns(t *testing.T) {
+	type Server struct {
+		Name   string
+		Labels map[string]string `simpledb:"indexed"`
+		Ports  map[uint16][]string
+	}
+
+	tempFile, err := os.CreateTemp(os.TempDir(), "simpledb-")
+	if err != nil {
+		t.Fatalf("Failed to create temp file: %s", err)
+	}
+
+	t.Cleanup(func() {
+		tempFile.Close()
+		os.Remove(tempFile.Name())
+	})
+
+	db, err := NewDB(tempFile, Server{})
+	if err != nil {
+		t.Fatalf("failed to create DB: %s", err)
+	}
+
+	web := Server{
+		Name:   "web",
+		Labels: map[string]string{"env": "prod", "tier": "frontend"},
+		Ports:  map[uint16][]string{80: {"http"}, 443: {"https", "h2"}},
+	}
+	webID, err := db.Insert(web)
+	if err != nil {
+		t.Fatalf("Failed to insert server: %s", err)
+	}
+	if _, err := db.Insert(Server{Name: "db", Labels: map[string]string{"env": "dev"}, Ports: map[uint16][]string{}}); err != nil {
+		t.Fatalf("Failed to insert server: %s", err)
+	}
+
+	tempFile.Seek(0, io.SeekStart)
+	columns, err := ReadColumns(tempFile)
+	if err != nil {
+		t.Fatalf("Failed to read columns: %s", err)
+	}
+	if columns[0].Type != "map[string]string" || columns[2].Type != "map[uint16][]string" {
+		t.Fatalf("unexpected column types in file header: %v", columns)
+	}
+
+	db, err = NewDB(tempFile, Server{})
+	if err != nil {
+		t.Fatalf("Failed to reopen DB: %s", err)
+	}
+
+	var found Server
+	if err := db.Find(webID, &found); err != nil {
+		t.Fatalf("Failed to find server: %s", err)
+	}
+	if !reflect.DeepEqual(found, web) {
+		t.Fatalf("found server does not match\nWanted %+v\nGot    %+v", web, found)
+	}
+
+	type Fixture struct {
+		query    FilterQuery
+		expected int
+	}
+
+	fixtures := []Fixture{
+		{FilterQuery{"Labels": HasKey("tier")}, 1},
+		{FilterQuery{"Labels": HasEntry("env", "dev")}, 1},
+		{FilterQuery{"Labels": HasEntry("env", In("dev", "prod"))}, 2},
+		{FilterQuery{"Labels.env": "prod"}, 1},
+		{FilterQuery{"Labels.tier": NotEqual("backend")}, 1},
+		{FilterQuery{"Labels": map[string]string{"env": "dev"}}, 1},
+		{FilterQuery{"Ports": HasKey(443)}, 1},
+		{FilterQuery{"Ports": HasEntry(443, []string{"https", "h2"})}, 1},
+		{FilterQuery{"Ports.443": []string{"https", "h2"}}, 0},
+	}
+
+	for _, fixture := range fixtures {
+		rows, err := db.Filter(fixture.query)
+		if err != nil {
+			t.Fatalf("Failed to filter servers: %s", err)
+		}
+		if len(rows) != fixture.expected {
+			t.Errorf("query %v returned %d rows, expected %d", fixture.query, len(rows), fixture.expected)
+		}
+	}
+
+	type InvalidServer struct {
+		Ports map[uint]string
+	}
+	if _, err := NewDB(tempFile, InvalidServer{}); err == nil {
+		t.Fatalf("expected error opening DB with a map column whose key type is not a valid column type")
+	}
+}
+
+func TestPointerColumns(t *testing.T) {
+	type Address struct {
+		City string
+	}
+
+	type Person struct {
+		Name    string
+		Age     *uint8 `simpledb:"indexed"`
+		Address *Address
+	}
+
+	tempFile, err := os.CreateTemp(os.TempDir(), "simpledb-")
+	if err != nil {
+		t.Fatalf("Failed to create temp file: %s", err)
+	}
+
+	t.Cleanup(func() {
+		tempFile.Close()
+		os.Remove(tempFile.Name())
+	})
+
+	db, err := NewDB(tempFile, Person{})
+	if err != nil {
+		t.Fatalf("failed to create DB: %s", err)
+	}
+
+	zero, thirty := uint8(0), uint8(30)
+	people := []Person{
+		{Name: "alice", Age: &thirty, Address: &Address{City: "Paris"}},
+		{Name: "bob", Age: &zero},
+		{Name: "carol"},
+	}
+	ids := make([]uint64, len(people))
+	for i, person := range people {
+		if ids[i], err = db.Insert(person); err != nil {
+			t.Fatalf("Failed to insert person: %s", err)
+		}
+	}
+
+	tempFile.Seek(0, io.SeekStart)
+	columns, err := ReadColumns(tempFile)
+	if err != nil {
+		t.Fatalf("Failed to read columns: %s", err)
+	}
+	if columns[0].Type != "*struct{City string}" || columns[1].Type != "*uint8" {
+		t.Fatalf("unexpected column types in file header: %v", columns)
+	}
+
+	db, err = NewDB(tempFile, Person{})
+	if err != nil {
+		t.Fatalf("Failed to reopen DB: %s", err)
+	}
+
+	for i, person := range people {
+		var found Person
+		if err := db.Find(ids[i], &found); err != nil {
+			t.Fatalf("Failed to find person: %s", err)
+		}
+		if !reflect.DeepEqual(found, person) {
+			t.Errorf("found person does not match\nWanted %+v\nGot    %+v", person, found)
+		}
+	}
+
+	type Fixture struct {
+		query    FilterQuery
+		expected int
+	}
+
+	fixtures := []Fixture{
+		{FilterQuery{"Age": IsNull()}, 1},
+		{FilterQuery{"Age": IsNotNull()}, 2},
+		{FilterQuery{"Age": Equal(0)}, 1},
+		{FilterQuery{"Age": In(0, 30)}, 2},
+		{FilterQuery{"Age": GreaterThan(18)}, 1},
+		{FilterQuery{"Age": &thirty}, 1},
+		{FilterQuery{"Address": IsNull()}, 2},
+		{FilterQuery{"Address.City": "Paris"}, 1},
+		{FilterQuery{"Name": IsNull()}, 0},
+	}
+
+	for _, fixture := range fixtures {
+		rows, err := db.Filter(fixture.query)
+		if err != nil {
+			t.Fatalf("Failed to filter people: %s", err)
+		}
+		if len(rows) != fixture.expected {
+			t.Errorf("query %v returned %d rows, expected %d", fixture.query, len(rows), fixture.expected)
+		}
+	}
+}
+
+func TestTimeColumns(t *testing.T) {
+	type Event struct {
+		Name     string
+		Start    time.Time `simpledb:"indexed,ordered"`
+		Duration time.Duration
+		End      *time.Time
+	}
+
+	tempFile, err := os.CreateTemp(os.TempDir(), "simpledb-")
+	if err != nil {
+		t.Fatalf("Failed to create temp file: %s", err)
+	}
+
+	t.Cleanup(func() {
+		tempFile.Close()
+		os.Remove(tempFile.Name())
+	})
+
+	db, err := NewDB(tempFile, Event{})
+	if err != nil {
+		t.Fatalf("failed to create DB: %s", err)
+	}
+
+	start := time.Date(2021, time.March, 4, 12, 30, 0, 500, time.FixedZone("UTC+2", 2*60*60))
+	end := start.Add(time.Hour)
+	events := []Event{
+		{Name: "standup", Start: start, Duration: 15 * time.Minute},
+		{Name: "lunch", Start: start.Add(time.Hour), Duration: time.Hour, End: &end},
+		{Name: "review", Start: start.Add(3 * time.Hour), Duration: 90 * time.Minute},
+	}
+	ids := make([]uint64, len(events))
+	for i, event := range events {
+		if ids[i], err = db.Insert(event); err != nil {
+			t.Fatalf("Failed to insert event: %s", err)
+		}
+	}
+
+	tempFile.Seek(0, io.SeekStart)
+	columns, err := ReadColumns(tempFile)
+	if err != nil {
+		t.Fatalf("Failed to read columns: %s", err)
+	}
+	if columns[0].Type != "int64" || columns[1].Type != "*time.Time" || columns[3].Type != "time.Time" {
+		t.Fatalf("unexpected column types in file header: %v", columns)
+	}
+
+	db, err = NewDB(tempFile, Event{})
+	if err != nil {
+		t.Fatalf("Failed to reopen DB: %s", err)
+	}
+
+	var found Event
+	if err := db.Find(ids[1], &found); err != nil {
+		t.Fatalf("Failed to find event: %s", err)
+	}
+	if !found.Start.Equal(events[1].Start) || !found.End.Equal(end) || found.Duration != time.Hour {
+		t.Fatalf("found event does not match\nWanted %+v\nGot    %+v", events[1], found)
+	}
+	if found.Start.Location() != time.UTC {
+		t.Errorf("expected times to be decoded in UTC, got %s", found.Start.Location())
+	}
+
+	type Fixture struct {
+		query    FilterQuery
+		expected int
+	}
+
+	fixtures := []Fixture{
+		{FilterQuery{"Start": Equal(start.UTC())}, 1},
+		{FilterQuery{"Start": GreaterThan(start)}, 2},
+		{FilterQuery{"Start": Between(start.Add(time.Minute), start.Add(2*time.Hour))}, 1},
+		{FilterQuery{"Duration": GreaterOrEqual(time.Hour)}, 2},
+		{FilterQuery{"Duration": LessThan(30 * time.Minute)}, 1},
+		{FilterQuery{"End": LessOrEqual(end)}, 1},
+		{FilterQuery{"End": IsNull()}, 2},
+	}
+
+	for _, fixture := range fixtures {
+		rows, err := db.Filter(fixture.query)
+		if err != nil {
+			t.Fatalf("Failed to filter events: %s", err)
+		}
+		if len(rows) != fixture.expected {
+			t.Errorf("query %v returned %d rows, expected %d", fixture.query, len(rows), fixture.expected)
+		}
+	}
+
+	rows, err := db.FilterSorted(Where{}, "Start", true)
+	if err != nil {
+		t.Fatalf("Failed to filter sorted events: %s", err)
+	}
+	for i, row := range rows {
+		if name := row.Value.(*Event).Name; name != events[len(events)-1-i].Name {
+			t.Errorf("expected events sorted by descending start time, got %s at position %d", name, i)
+		}
+	}
+}
+
+func TestBinaryMarshalerColumns(t *testing.T) {
+	type Account struct {
+		ID      testUUID `simpledb:"unique"`
+		Balance testAmount
+		Limit   *testAmount
+	}
+
+	tempFile, err := os.CreateTemp(os.TempDir(), "simpledb-")
+	if err != nil {
+		t.Fatalf("Failed to create temp file: %s", err)
+	}
+
+	t.Cleanup(func() {
+		tempFile.Close()
+		os.Remove(tempFile.Name())
+	})
+
+	db, err := NewDB(tempFile, Account{})
+	if err != nil {
+		t.Fatalf("failed to create DB: %s", err)
+	}
+
+	accounts := []Account{
+		{ID: testUUID{1}, Balance: testAmount{cents: 1050}, Limit: &testAmount{cents: 10000}},
+		{ID: testUUID{2}, Balance: testAmount{cents: 99}},
+	}
+	ids := make([]uint64, len(accounts))
+	for i, account := range accounts {
+		if ids[i], err = db.Insert(account); err != nil {
+			t.Fatalf("Failed to insert account: %s", err)
+		}
+	}
+
+	if _, err := db.Insert(Account{ID: testUUID{2}}); !errors.Is(err, ErrUniqueViolation) {
+		t.Fatalf("expected unique violation inserting a duplicate ID, got %v", err)
+	}
+
+	tempFile.Seek(0, io.SeekStart)
+	columns, err := ReadColumns(tempFile)
+	if err != nil {
+		t.Fatalf("Failed to read columns: %s", err)
+	}
+	if columns[0].Type != "binary" || columns[1].Type != "[4]uint8" || columns[2].Type != "*binary" {
+		t.Fatalf("unexpected column types in file header: %v", columns)
+	}
+
+	db, err = NewDB(tempFile, Account{})
+	if err != nil {
+		t.Fatalf("Failed to reopen DB: %s", err)
+	}
+
+	for i, account := range accounts {
+		var found Account
+		if err := db.Find(ids[i], &found); err != nil {
+			t.Fatalf("Failed to find account: %s", err)
+		}
+		if !reflect.DeepEqual(found, account) {
+			t.Errorf("found account does not match\nWanted %+v\nGot    %+v", account, found)
+		}
+	}
+
+	type Fixture struct {
+		query    FilterQuery
+		expected int
+	}
+
+	fixtures := []Fixture{
+		{FilterQuery{"ID": testUUID{2}}, 1},
+		{FilterQuery{"ID": In(testUUID{1}, testUUID{3})}, 1},
+		{FilterQuery{"Balance": Equal(testAmount{cents: 99})}, 1},
+		{FilterQuery{"Balance": NotEqual(testAmount{cents: 99})}, 1},
+		{FilterQuery{"Limit": Equal(testAmount{cents: 10000})}, 1},
+		{FilterQuery{"Limit": IsNull()}, 1},
+	}
+
+	for _, fixture := range fixtures {
+		rows, err := db.Filter(fixture.query)
+		if err != nil {
+			t.Fatalf("Failed to filter accounts: %s", err)
+		}
+		if len(rows) != fixture.expected {
+			t.Errorf("query %v returned %d rows, expected %d", fixture.query, len(rows), fixture.expected)
+		}
+	}
+}
+
+// seekingSource is a Source which hides the ReadAt and WriteAt methods of an *os.File,
+// so that the DB must move the file's cursor to read and write rows.
+type seekingSource struct {
+	Source
+}
+
+func TestConcurrentReads(t *testing.T) {
+	type Car struct {
+		Make string `simpledb:"indexed"`
+		Year uint16 `simpledb:"indexed,ordered"`
+	}
+
+	for _, seeking := range []bool{false, true} {
+		tempFile, err := os.CreateTemp(os.TempDir(), "simpledb-")
+		if err != nil {
+			t.Fatalf("Failed to create temp file: %s", err)
+		}
+
+		t.Cleanup(func() {
+			tempFile.Close()
+			os.Remove(tempFile.Name())
+		})
+
+		var source Source = tempFile
+		if seeking {
+			source = seekingSource{tempFile}
+		}
+
+		db, err := NewDB(source, Car{})
+		if err != nil {
+			t.Fatalf("failed to create DB: %s", err)
+		}
+
+		cars := make(map[uint64]Car)
+		for i := 0; i < 50; i++ {
+			car := Car{Make: []string{"Mazda", "Ford", "Toyota"}[i%3], Year: uint16(1990 + i)}
+			id, err := db.Insert(car)
+			if err != nil {
+				t.Fatalf("Failed to insert car: %s", err)
+			}
+			cars[id] = car
+		}
+
+		var wg sync.WaitGroup
+		errs := make(chan error, 20)
+
+		// rows are inserted and dropped while the readers run
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := 0; i < 50; i++ {
+				id, err := db.Insert(Car{Make: "Honda", Year: 3000})
+				if err == nil {
+					err = db.Drop(id)
+				}
+				if err != nil {
+					errs <- err
+					return
+				}
+			}
+		}()
+
+		for i := 0; i < 8; i++ {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				for id, expected := range cars {
+					var car Car
+					if err := db.Find(id, &car); err != nil {
+						errs <- err
+						return
+					} else if car != expected {
+						errs <- fmt.Errorf("found car does not match\nWanted %+v\nGot    %+v", expected, car)
+						return
+					}
+				}
+
+				rows, err := db.Filter(FilterQuery{"Make": "Ford", "Year": LessThan(uint16(3000))})
+				if err != nil {
+					errs <- err
+					return
+				} else if len(rows) != 17 {
+					errs <- fmt.Errorf("expected 17 cars, got %d", len(rows))
+					return
+				}
+
+				next := db.Iterate(Where{"Make": "Toyota"})
+				count := 0
+				for row, err := next(); row != nil || err != nil; row, err = next() {
+					if err != nil {
+						errs <- err
+						return
+					}
+					count += 1
+				}
+				if count != 16 {
+					errs <- fmt.Errorf("expected to iterate over 16 cars, got %d", count)
+					return
+				}
+			}()
+		}
+
+		wg.Wait()
+		close(errs)
+		for err := range errs {
+			t.Fatalf("concurrent read failed (seeking source: %v): %s", seeking, err)
+		}
+	}
+}
+
+func TestConcurrentUse(t *testing.T) {
+	type Car struct {
+		Make   string `simpledb:"indexed"`
+		Serial uint32 `simpledb:"unique,ordered"`
+		Check  uint32
+	}
+
+	newCar := func(serial uint32) Car {
+		return Car{Make: fmt.Sprint(serial % 5), Serial: serial, Check: serial * 7}
+	}
+	checkCar := func(value interface{}) error {
+		if car := value.(*Car); *car != newCar(car.Serial) {
+			return fmt.Errorf("corrupted car: %+v", car)
+		}
+		return nil
+	}
+
+	tempFile, err := os.CreateTemp(os.TempDir(), "simpledb-")
+	if err != nil {
+		t.Fatalf("Failed to create temp file: %s", err)
+	}
+
+	t.Cleanup(func() {
+		tempFile.Close()
+		os.Remove(tempFile.Name())
+	})
+
+	db, err := NewDB(tempFile, Car{})
+	if err != nil {
+		t.Fatalf("failed to create DB: %s", err)
+	}
+
+	rounds := 40
+	if testing.Short() {
+		rounds = 15
+	}
+
+	var (
+		wg      sync.WaitGroup
+		idMutex sync.Mutex
+		ids     = make(map[uint64]uint32)
+		errs    = make(chan error, 100)
+		done    = make(chan struct{})
+	)
+
+	// writers insert rows with distinct serials, and drop some of them again
+	for w := 0; w < 4; w++ {
+		wg.Add(1)
+		go func(w int) {
+			defer wg.Done()
+			for i := 0; i < rounds; i++ {
+				serial := uint32(w*rounds + i)
+				id, err := db.Insert(newCar(serial))
+				if err != nil {
+					errs <- err
+					return
+				}
+
+				if i%3 != 0 {
+					idMutex.Lock()
+					ids[id] = serial
+					idMutex.Unlock()
+				} else if err := db.Drop(id); err != nil {
+					errs <- err
+					return
+				}
+
+				if i%10 == 0 {
+					tx := db.Begin()
+					serial += 4 * uint32(rounds)
+					id, err := tx.Insert(newCar(serial))
+					if err == nil {
+						err = tx.Commit()
+					}
+					if err != nil {
+						errs <- err
+						return
+					}
+					idMutex.Lock()
+					ids[id] = serial
+					idMutex.Unlock()
+				}
+			}
+		}(w)
+	}
+
+	// readers and defrags run until the writers are finished
+	var readers sync.WaitGroup
+	reader := func(read func() error) {
+		readers.Add(1)
+		go func() {
+			defer readers.Done()
+			for {
+				select {
+				case <-done:
+					return
+				default:
+				}
+				if err := read(); err != nil {
+					errs <- err
+					return
+				}
+			}
+		}()
+	}
+
+	reader(func() error {
+		rows, err := db.Filter(FilterQuery{"Make": "3"})
+		if err != nil {
+			return err
+		}
+		for _, row := range rows {
+			if err := checkCar(row.Value); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	reader(func() error {
+		_, err := db.FilterSorted(Where{"Serial": LessThan(uint32(rounds))}, "Serial", true)
+		return err
+	})
+	reader(func() error {
+		next := db.Iterate()
+		for row, err := next(); row != nil || err != nil; row, err = next() {
+			if err != nil {
+				return err
+			}
+			if err := checkCar(row.Value); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	reader(func() error {
+		idMutex.Lock()
+		var id uint64
+		for id = range ids {
+			break
+		}
+		idMutex.Unlock()
+
+		var car Car
+		if !db.Has(id) || db.RowCount() == 0 {
+			return nil
+		} else if err := db.Find(id, &car); err != nil && err != ErrNotFound {
+			return err
+		}
+		_, err := db.Size()
+		return err
+	})
+	reader(func() error {
+		time.Sleep(10 * time.Millisecond)
+		return db.Defrag()
+	})
+
+	wg.Wait()
+	close(done)
+	readers.Wait()
+	close(errs)
+	for err := range errs {
+		t.Fatalf("concurrent use failed: %s", err)
+	}
+
+	if n := db.RowCount(); n != len(ids) {
+		t.Fatalf("expected %d rows, got %d", len(ids), n)
+	}
+	for id, serial := range ids {
+		var car Car
+		if err := db.Find(id, &car); err != nil {
+			t.Fatalf("Failed to find car %d: %s", serial, err)
+		} else if car != newCar(serial) {
+			t.Fatalf("found car does not match\nWanted %+v\nGot    %+v", newCar(serial), car)
+		}
+	}
+}
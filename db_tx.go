@@ -0,0 +1,266 @@
+package simpledb
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"reflect"
+)
+
+// ErrTxDone is returned by the methods of a Tx which has already been committed or rolled back.
+var ErrTxDone = errors.New("transaction has already been committed or rolled back")
+
+// Tx is a transaction on a DB table, which groups several Insert, Update and Drop calls so that they are
+// either all committed to the DB, or none of them are. Changes made in a Tx are buffered in memory, and
+// are visible to reads made through the same Tx, but not to the DB itself until Commit is called.
+// A Tx is not safe for concurrent use by multiple goroutines.
+type Tx struct {
+	db   *DB
+	done bool
+
+	// inserted holds the encoded values of rows inserted or updated in the transaction.
+	inserted map[uint64][]byte
+
+	// dropped holds the IDs of rows in the DB which have been dropped or updated in the transaction.
+	dropped map[uint64]bool
+}
+
+// Begin starts a new transaction on the DB table.
+func (db *DB) Begin() *Tx {
+	return &Tx{
+		db:       db,
+		inserted: make(map[uint64][]byte),
+		dropped:  make(map[uint64]bool),
+	}
+}
+
+// encode encodes the given value with the DB's Codec.
+func (tx *Tx) encode(value interface{}) ([]byte, error) {
+	buf := new(bytes.Buffer)
+	if _, err := tx.db.encodeRow(buf, value); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// decode decodes a value inserted in the transaction into destPtr.
+func (tx *Tx) decode(encoded []byte, destPtr interface{}) error {
+	_, err := tx.db.decodeRow(bytes.NewReader(encoded), uint64(len(encoded)), destPtr)
+	return err
+}
+
+// has returns true if the given ID is present in the DB as seen by the transaction.
+func (tx *Tx) has(id uint64) bool {
+	if _, ok := tx.inserted[id]; ok {
+		return true
+	}
+	return !tx.dropped[id] && tx.db.Has(id)
+}
+
+// Has returns true if the given ID is present in the DB, as seen by the transaction.
+func (tx *Tx) Has(id uint64) bool {
+	return !tx.done && tx.has(id)
+}
+
+// RowCount returns the number of rows in the DB table, as seen by the transaction. Rows dropped in the
+// transaction which have since been dropped from the DB are not counted twice. It returns 0 once the
+// transaction has been committed or rolled back.
+func (tx *Tx) RowCount() int {
+	if tx.done {
+		return 0
+	}
+
+	count := tx.db.RowCount() + len(tx.inserted)
+	for id := range tx.dropped {
+		if tx.db.Has(id) {
+			count--
+		}
+	}
+	return count
+}
+
+// Insert buffers the insertion of the given value in the transaction, and returns the ID
+// the row will have once the transaction is committed.
+func (tx *Tx) Insert(value interface{}) (uint64, error) {
+	if tx.done {
+		return DeletedID, ErrTxDone
+	}
+
+	encoded, err := tx.encode(value)
+	if err != nil {
+		return DeletedID, err
+	}
+
+	var id uint64
+	for id == DeletedID || tx.has(id) {
+		id = randUint64()
+	}
+
+	tx.inserted[id] = encoded
+	return id, nil
+}
+
+// Update buffers the replacement of the row with the given ID in the transaction.
+// If the row does not exist, it returns ErrNotFound.
+func (tx *Tx) Update(id uint64, value interface{}) error {
+	if tx.done {
+		return ErrTxDone
+	} else if !tx.has(id) {
+		return ErrNotFound
+	}
+
+	encoded, err := tx.encode(value)
+	if err != nil {
+		return err
+	}
+
+	if _, ok := tx.inserted[id]; !ok {
+		tx.dropped[id] = true
+	}
+	tx.inserted[id] = encoded
+	return nil
+}
+
+// Drop buffers the removal of the row with the given ID in the transaction.
+// If the row does not exist, it returns ErrNotFound.
+func (tx *Tx) Drop(id uint64) error {
+	if tx.done {
+		return ErrTxDone
+	} else if !tx.has(id) {
+		return ErrNotFound
+	}
+
+	delete(tx.inserted, id)
+	if tx.db.Has(id) {
+		tx.dropped[id] = true
+	}
+	return nil
+}
+
+// Find finds the row with the given ID, as seen by the transaction, and unmarshals it
+// into destPtr. If the row does not exist, it returns ErrNotFound.
+func (tx *Tx) Find(id uint64, destPtr interface{}) error {
+	if tx.done {
+		return ErrTxDone
+	}
+
+	if encoded, ok := tx.inserted[id]; ok {
+		return tx.decode(encoded, destPtr)
+	} else if tx.dropped[id] {
+		return ErrNotFound
+	}
+
+	return tx.db.Find(id, destPtr)
+}
+
+// Pop combines tx.Find and tx.Drop into one operation.
+func (tx *Tx) Pop(id uint64, destPtr interface{}) error {
+	if err := tx.Find(id, destPtr); err != nil {
+		return err
+	}
+	return tx.Drop(id)
+}
+
+// Filter searches the DB table for all rows which match the FilterQuery, as seen by the transaction.
+func (tx *Tx) Filter(query FilterQuery) ([]*Row, error) {
+	return tx.Select(Where(query))
+}
+
+// Select searches the DB table for all rows which match the Query, as seen by the transaction.
+func (tx *Tx) Select(query Query) ([]*Row, error) {
+	if tx.done {
+		return nil, ErrTxDone
+	}
+
+	rows, err := tx.db.Select(query)
+	if err != nil {
+		return nil, err
+	}
+
+	results := make([]*Row, 0, len(rows))
+	for _, row := range rows {
+		if !tx.dropped[row.ID] {
+			results = append(results, row)
+		}
+	}
+
+	for id, encoded := range tx.inserted {
+		destPtr := reflect.New(tx.db.schema.dataType).Interface()
+		if err := tx.decode(encoded, destPtr); err != nil {
+			return nil, err
+		}
+
+		if query.Match(destPtr) {
+			results = append(results, &Row{
+				Value: destPtr,
+				ID:    id,
+			})
+		}
+	}
+
+	return results, nil
+}
+
+// Commit applies every change made in the transaction to the DB as one operation. If any row dropped or
+// updated in the transaction has since been dropped from the DB, Commit returns ErrNotFound and the DB is
+// left unchanged. Likewise, if the changes would violate a unique constraint, Commit returns a
+// *UniqueViolationError. If the DB has a journal, the changes are committed atomically even in case of a crash.
+// The transaction cannot be used once it has been committed, even if Commit returns an error.
+func (tx *Tx) Commit() error {
+	if tx.done {
+		return ErrTxDone
+	}
+	tx.done = true
+
+	db := tx.db
+	if db.readOnly {
+		return ErrReadOnly
+	}
+
+	db.mutex.Lock()
+	defer db.mutex.Unlock()
+
+	if len(tx.dropped) == 0 && len(tx.inserted) == 0 {
+		return nil
+	}
+
+	for id := range tx.inserted {
+		if _, ok := db.index[id]; ok && !tx.dropped[id] {
+			return fmt.Errorf("row with ID %d was inserted into the DB during the transaction", id)
+		}
+	}
+
+	b, err := db.newBatch()
+	if err != nil {
+		return err
+	}
+
+	for id := range tx.dropped {
+		if err := db.dropInto(b, id); err != nil {
+			return err
+		}
+	}
+
+	for id, encoded := range tx.inserted {
+		valuePtr := reflect.New(db.schema.dataType).Interface()
+		if err := tx.decode(encoded, valuePtr); err != nil {
+			return err
+		}
+		if err := db.insertInto(b, valuePtr, id); err != nil {
+			return err
+		}
+	}
+
+	return db.commit(b)
+}
+
+// Rollback discards every change made in the transaction.
+func (tx *Tx) Rollback() error {
+	if tx.done {
+		return ErrTxDone
+	}
+	tx.done = true
+	tx.inserted = nil
+	tx.dropped = nil
+	return nil
+}
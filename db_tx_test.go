@@ -0,0 +1,140 @@
+package simpledb
+
+import (
+	"os"
+	"testing"
+)
+
+func TestTx(t *testing.T) {
+	type Item struct {
+		Name  string `simpledb:"indexed"`
+		Count uint32
+	}
+
+	tempFile, err := os.CreateTemp(os.TempDir(), "simpledb-")
+	if err != nil {
+		t.Fatalf("Failed to create temp file: %s", err)
+	}
+
+	t.Cleanup(func() {
+		tempFile.Close()
+		os.Remove(tempFile.Name())
+	})
+
+	db, err := NewDB(tempFile, Item{})
+	if err != nil {
+		t.Fatalf("failed to create DB: %s", err)
+	}
+
+	appleID, err := db.Insert(Item{Name: "apple", Count: 1})
+	if err != nil {
+		t.Fatalf("Failed to insert item: %s", err)
+	}
+	pearID, err := db.Insert(Item{Name: "pear", Count: 2})
+	if err != nil {
+		t.Fatalf("Failed to insert item: %s", err)
+	}
+
+	tx := db.Begin()
+
+	plumID, err := tx.Insert(&Item{Name: "plum", Count: 3})
+	if err != nil {
+		t.Fatalf("Failed to insert item in transaction: %s", err)
+	}
+	if err := tx.Update(appleID, Item{Name: "apple", Count: 10}); err != nil {
+		t.Fatalf("Failed to update item in transaction: %s", err)
+	}
+	if err := tx.Drop(pearID); err != nil {
+		t.Fatalf("Failed to drop item in transaction: %s", err)
+	}
+	if err := tx.Drop(pearID); err != ErrNotFound {
+		t.Fatalf("expected ErrNotFound dropping item twice in transaction, got: %v", err)
+	}
+
+	var item Item
+	if err := tx.Find(plumID, &item); err != nil || item.Count != 3 {
+		t.Fatalf("expected inserted item to be visible in transaction: %v", err)
+	}
+	if err := tx.Find(appleID, &item); err != nil || item.Count != 10 {
+		t.Fatalf("expected updated item to be visible in transaction: %v", err)
+	}
+	if tx.Has(pearID) || tx.RowCount() != 2 {
+		t.Fatalf("expected dropped item to be hidden in transaction")
+	}
+
+	rows, err := tx.Filter(FilterQuery{"Name": "plum"})
+	if err != nil || len(rows) != 1 || rows[0].ID != plumID {
+		t.Fatalf("expected to filter inserted item in transaction: %v", err)
+	}
+
+	// changes are not visible to the DB before commit
+	if db.Has(plumID) || !db.Has(pearID) {
+		t.Fatalf("transaction changes visible before commit")
+	}
+	if err := db.Find(appleID, &item); err != nil || item.Count != 1 {
+		t.Fatalf("transaction update visible before commit: %v", err)
+	}
+
+	if err := tx.Commit(); err != nil {
+		t.Fatalf("Failed to commit transaction: %s", err)
+	}
+	if err := tx.Commit(); err != ErrTxDone {
+		t.Fatalf("expected ErrTxDone committing twice, got: %v", err)
+	}
+
+	if !db.Has(plumID) || db.Has(pearID) || db.RowCount() != 2 {
+		t.Fatalf("transaction changes not applied after commit")
+	}
+	if err := db.Find(appleID, &item); err != nil || item.Count != 10 {
+		t.Fatalf("transaction update not applied after commit: %v", err)
+	}
+
+	tx = db.Begin()
+	if _, err := tx.Insert(Item{Name: "kiwi"}); err != nil {
+		t.Fatalf("Failed to insert item in transaction: %s", err)
+	}
+	if err := tx.Drop(appleID); err != nil {
+		t.Fatalf("Failed to drop item in transaction: %s", err)
+	}
+	if err := tx.Rollback(); err != nil {
+		t.Fatalf("Failed to roll back transaction: %s", err)
+	}
+	if _, err := tx.Insert(Item{}); err != ErrTxDone {
+		t.Fatalf("expected ErrTxDone using rolled back transaction, got: %v", err)
+	}
+	if !db.Has(appleID) || db.RowCount() != 2 {
+		t.Fatalf("rolled back transaction changed the DB")
+	}
+	if tx.RowCount() != 0 {
+		t.Fatalf("expected rolled back transaction to count 0 rows, got %d", tx.RowCount())
+	}
+
+	// a row dropped both in a transaction and directly from the DB is only counted once
+	tx = db.Begin()
+	if err := tx.Drop(appleID); err != nil {
+		t.Fatalf("Failed to drop item in transaction: %s", err)
+	}
+	if err := db.Drop(appleID); err != nil {
+		t.Fatalf("Failed to drop item: %s", err)
+	}
+	if tx.RowCount() != 1 {
+		t.Fatalf("expected 1 row in transaction after concurrent drop, got %d", tx.RowCount())
+	}
+	tx.Rollback()
+
+	// a transaction which conflicts with a concurrent drop is not applied at all
+	tx = db.Begin()
+	kiwiID, _ := tx.Insert(Item{Name: "kiwi"})
+	if err := tx.Update(plumID, Item{Name: "plum", Count: 30}); err != nil {
+		t.Fatalf("Failed to update item in transaction: %s", err)
+	}
+	if err := db.Drop(plumID); err != nil {
+		t.Fatalf("Failed to drop item: %s", err)
+	}
+	if err := tx.Commit(); err != ErrNotFound {
+		t.Fatalf("expected ErrNotFound committing conflicting transaction, got: %v", err)
+	}
+	if db.Has(kiwiID) {
+		t.Fatalf("conflicting transaction was partially applied")
+	}
+}
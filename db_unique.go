@@ -0,0 +1,87 @@
+package simpledb
+
+import (
+	"errors"
+	"fmt"
+	"reflect"
+)
+
+// ErrUniqueViolation is returned by db.Insert, db.Update and tx.Commit when a row would hold the same
+// value in a column tagged `simpledb:"unique"` as another row. The returned error is always
+// a *UniqueViolationError, which describes the violation.
+var ErrUniqueViolation = errors.New("unique constraint violated")
+
+// UniqueViolationError is returned when a row would hold the same value in a unique
+// column as another row in the same table.
+type UniqueViolationError struct {
+	// Table is the name of the table holding the column.
+	Table string
+
+	// Column is the name of the unique column.
+	Column string
+
+	// Value is the value which is already held by another row.
+	Value interface{}
+
+	// ID is the ID of the row which already holds the value.
+	ID uint64
+}
+
+func (err *UniqueViolationError) Error() string {
+	return fmt.Sprintf("%s: value %v of column %q in table %q is already held by row %d",
+		ErrUniqueViolation, err.Value, err.Column, err.Table, err.ID)
+}
+
+// Unwrap allows errors.Is(err, ErrUniqueViolation) to succeed.
+func (err *UniqueViolationError) Unwrap() error {
+	return ErrUniqueViolation
+}
+
+// checkUnique returns a *UniqueViolationError if inserting the given value as the row with the given ID
+// would violate a unique constraint, either against the rows in the DB which have not been dropped in the
+// batch, or against the other rows inserted by the batch. Nil pointers are not values, so any number of rows
+// can hold nil in a unique pointer column. It assumes the caller is handling the mutex.
+func (db *DB) checkUnique(b *batch, value interface{}, id uint64) error {
+	if len(db.uniqueFields) == 0 {
+		return nil
+	}
+
+	if b.unique == nil {
+		b.unique = make(map[string]map[string]uint64)
+	}
+
+	valueReflected := reflect.Indirect(reflect.ValueOf(value))
+	for _, fieldName := range db.uniqueFields {
+		fieldValue := valueReflected.FieldByName(fieldName)
+		if fieldValue.Kind() == reflect.Ptr && fieldValue.IsNil() {
+			continue
+		}
+		key := indexKey(fieldValue)
+
+		violation := &UniqueViolationError{
+			Table:  db.name,
+			Column: fieldName,
+			Value:  fieldValue.Interface(),
+		}
+
+		for otherID := range db.hashIndices[fieldName][key] {
+			if otherID != id && !b.dropped[otherID] {
+				violation.ID = otherID
+				return violation
+			}
+		}
+
+		inserted, ok := b.unique[fieldName]
+		if !ok {
+			inserted = make(map[string]uint64)
+			b.unique[fieldName] = inserted
+		}
+		if otherID, ok := inserted[key]; ok && otherID != id {
+			violation.ID = otherID
+			return violation
+		}
+		inserted[key] = id
+	}
+
+	return nil
+}
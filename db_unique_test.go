@@ -0,0 +1,189 @@
+package simpledb
+
+import (
+	"errors"
+	"os"
+	"sync"
+	"testing"
+)
+
+func TestUnique(t *testing.T) {
+	type User struct {
+		Name  string `simpledb:"unique"`
+		Email string `simpledb:"indexed,unique"`
+		Age   uint8
+	}
+
+	tempFile, err := os.CreateTemp(os.TempDir(), "simpledb-")
+	if err != nil {
+		t.Fatalf("Failed to create temp file: %s", err)
+	}
+
+	t.Cleanup(func() {
+		tempFile.Close()
+		os.Remove(tempFile.Name())
+	})
+
+	db, err := NewDB(tempFile, User{})
+	if err != nil {
+		t.Fatalf("failed to create DB: %s", err)
+	}
+
+	aliceID, err := db.Insert(User{Name: "alice", Email: "alice@x.com"})
+	if err != nil {
+		t.Fatalf("Failed to insert user: %s", err)
+	}
+	bobID, err := db.Insert(User{Name: "bob", Email: "bob@x.com"})
+	if err != nil {
+		t.Fatalf("Failed to insert user: %s", err)
+	}
+
+	_, err = db.Insert(User{Name: "carol", Email: "alice@x.com"})
+	var violation *UniqueViolationError
+	if !errors.Is(err, ErrUniqueViolation) || !errors.As(err, &violation) {
+		t.Fatalf("expected UniqueViolationError when inserting duplicate email, got: %v", err)
+	}
+	if violation.Column != "Email" || violation.Value != "alice@x.com" || violation.ID != aliceID {
+		t.Fatalf("UniqueViolationError does not describe the violation: %+v", violation)
+	}
+	if db.RowCount() != 2 {
+		t.Fatalf("expected failed insert to leave the DB unchanged")
+	}
+
+	if err := db.Update(aliceID, User{Name: "alice", Email: "alice@x.com", Age: 30}); err != nil {
+		t.Fatalf("Failed to update user without changing unique columns: %s", err)
+	}
+	if err := db.Update(bobID, User{Name: "alice", Email: "bob@x.com"}); !errors.Is(err, ErrUniqueViolation) {
+		t.Fatalf("expected ErrUniqueViolation when updating user to a duplicate name, got: %v", err)
+	}
+
+	var bob User
+	if err := db.Find(bobID, &bob); err != nil || bob.Name != "bob" {
+		t.Fatalf("failed update should leave the row unchanged: %v", err)
+	}
+
+	// swapping values in a transaction only violates the constraint part-way through
+	tx := db.Begin()
+	if err := tx.Update(aliceID, User{Name: "bob", Email: "alice@x.com"}); err != nil {
+		t.Fatalf("Failed to update user in transaction: %s", err)
+	}
+	if err := tx.Update(bobID, User{Name: "alice", Email: "bob@x.com"}); err != nil {
+		t.Fatalf("Failed to update user in transaction: %s", err)
+	}
+	if err := tx.Commit(); err != nil {
+		t.Fatalf("Failed to commit transaction swapping unique values: %s", err)
+	}
+
+	tx = db.Begin()
+	if _, err := tx.Insert(User{Name: "dave", Email: "dave@x.com"}); err != nil {
+		t.Fatalf("Failed to insert user in transaction: %s", err)
+	}
+	if _, err := tx.Insert(User{Name: "dave", Email: "david@x.com"}); err != nil {
+		t.Fatalf("Failed to insert user in transaction: %s", err)
+	}
+	if err := tx.Commit(); !errors.As(err, &violation) || violation.Column != "Name" {
+		t.Fatalf("expected UniqueViolationError when committing duplicate names, got: %v", err)
+	}
+	if db.RowCount() != 2 {
+		t.Fatalf("expected failed commit to leave the DB unchanged")
+	}
+
+	if err := db.Drop(aliceID); err != nil {
+		t.Fatalf("Failed to drop user: %s", err)
+	}
+	if _, err := db.Insert(User{Name: "bob", Email: "alice@x.com"}); err != nil {
+		t.Fatalf("Failed to insert value previously held by a dropped row: %s", err)
+	}
+
+	// concurrent inserts of the same value
+	var (
+		wg        sync.WaitGroup
+		mutex     sync.Mutex
+		succeeded int
+	)
+	for i := 0; i < 20; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			_, err := db.Insert(User{Name: "eve", Email: "eve@x.com"})
+			if err == nil {
+				mutex.Lock()
+				succeeded += 1
+				mutex.Unlock()
+			} else if !errors.Is(err, ErrUniqueViolation) {
+				t.Errorf("unexpected error inserting user concurrently: %s", err)
+			}
+		}()
+	}
+	wg.Wait()
+
+	if succeeded != 1 {
+		t.Fatalf("expected exactly 1 concurrent insert of a unique value to succeed, got %d", succeeded)
+	}
+
+	db, err = NewDB(tempFile, User{})
+	if err != nil {
+		t.Fatalf("Failed to reopen DB: %s", err)
+	}
+	if _, err := db.Insert(User{Name: "eve", Email: "eve2@x.com"}); !errors.Is(err, ErrUniqueViolation) {
+		t.Fatalf("expected ErrUniqueViolation after reopening DB, got: %v", err)
+	}
+}
+
+func TestUniqueNullPointers(t *testing.T) {
+	type User struct {
+		Name   string
+		Handle *string `simpledb:"unique"`
+	}
+
+	tempFile, err := os.CreateTemp(os.TempDir(), "simpledb-")
+	if err != nil {
+		t.Fatalf("Failed to create temp file: %s", err)
+	}
+
+	t.Cleanup(func() {
+		tempFile.Close()
+		os.Remove(tempFile.Name())
+	})
+
+	db, err := NewDB(tempFile, User{})
+	if err != nil {
+		t.Fatalf("failed to create DB: %s", err)
+	}
+
+	// rows without a handle never violate the constraint
+	for _, name := range []string{"alice", "bob"} {
+		if _, err := db.Insert(User{Name: name}); err != nil {
+			t.Fatalf("Failed to insert user without a handle: %s", err)
+		}
+	}
+
+	tx := db.Begin()
+	if _, err := tx.Insert(User{Name: "carol"}); err != nil {
+		t.Fatalf("Failed to insert user: %s", err)
+	}
+	if _, err := tx.Insert(User{Name: "dave"}); err != nil {
+		t.Fatalf("Failed to insert user: %s", err)
+	}
+	if err := tx.Commit(); err != nil {
+		t.Fatalf("Failed to commit users without handles: %s", err)
+	}
+
+	handle := "ace"
+	if _, err := db.Insert(User{Name: "erin", Handle: &handle}); err != nil {
+		t.Fatalf("Failed to insert user with a handle: %s", err)
+	}
+
+	sameHandle := "ace"
+	_, err = db.Insert(User{Name: "frank", Handle: &sameHandle})
+	var violation *UniqueViolationError
+	if !errors.As(err, &violation) {
+		t.Fatalf("expected UniqueViolationError when inserting duplicate handle, got: %v", err)
+	} else if violation.Column != "Handle" {
+		t.Fatalf("UniqueViolationError does not describe the violation: %+v", violation)
+	}
+
+	if db.RowCount() != 5 {
+		t.Fatalf("expected 5 rows, got %d", db.RowCount())
+	}
+}
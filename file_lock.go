@@ -0,0 +1,139 @@
+package simpledb
+
+import (
+	"fmt"
+	"os"
+	"sync"
+	"time"
+)
+
+// LockMode is the kind of advisory lock which NewDB takes on the DB source, if it is an *os.File, to
+// prevent other processes from opening the DB at the same time. The lock is released when the DB is
+// closed. Locks are only taken on Unix systems, where they are taken with flock, and they only
+// protect against other processes which also lock the file, such as other processes using simpledb.
+type LockMode int
+
+const (
+	// LockExclusive is the default LockMode, which prevents any other process from locking the DB source.
+	LockExclusive LockMode = iota
+
+	// LockShared allows other processes to take shared locks on the DB source at the same time, but
+	// not exclusive locks. A DB opened with LockShared is read-only, as other processes holding shared
+	// locks may be reading it, so calls which would modify it return ErrReadOnly.
+	LockShared
+
+	// LockNone disables locking, allowing any number of processes to open the DB source at once.
+	LockNone
+)
+
+func (mode LockMode) String() string {
+	switch mode {
+	case LockExclusive:
+		return "exclusive"
+	case LockShared:
+		return "shared"
+	case LockNone:
+		return "none"
+	}
+	return fmt.Sprintf("LockMode(%d)", int(mode))
+}
+
+// lockPollInterval is how often the lock on a DB source is retried while waiting for it.
+const lockPollInterval = 10 * time.Millisecond
+
+// LockedError is returned by NewDB if the DB source is locked by another process, or by another
+// *os.File open in the same process, so that the lock could not be taken.
+type LockedError struct {
+	// Name is the name of the locked DB source file.
+	Name string
+
+	// Mode is the LockMode which could not be taken.
+	Mode LockMode
+}
+
+func (err *LockedError) Error() string {
+	return fmt.Sprintf("DB source %q is locked by another process; failed to take %s lock", err.Name, err.Mode)
+}
+
+// WithLock is an Option which sets the LockMode used to lock the DB source, if it is an *os.File.
+// By default, the source is locked exclusively. WithLock can only be passed to NewDB.
+func WithLock(mode LockMode) Option {
+	return func(db *DB) error {
+		if db.tables != nil {
+			return errNewDBOption("WithLock")
+		}
+		db.lockMode = mode
+		return nil
+	}
+}
+
+// WithLockTimeout is an Option which makes NewDB wait for up to the given duration for the lock on the
+// DB source to be released, if it is locked by another process. By default, NewDB returns a *LockedError
+// immediately. WithLockTimeout can only be passed to NewDB.
+func WithLockTimeout(timeout time.Duration) Option {
+	return func(db *DB) error {
+		if db.tables != nil {
+			return errNewDBOption("WithLockTimeout")
+		}
+		db.lockTimeout = timeout
+		return nil
+	}
+}
+
+// lockedFiles holds every *os.File locked by lockSource which has not since been unlocked. A file can be
+// given to NewDB again while it is locked, in which case the lock is left to the DB which first took it.
+var lockedFiles = struct {
+	sync.Mutex
+	files map[*os.File]bool
+}{files: make(map[*os.File]bool)}
+
+// lockSource takes the advisory lock on the DB source given by the DB's LockMode, if the source is an
+// *os.File, waiting for up to the DB's lock timeout. If the source is still locked once the timeout has
+// passed, it returns a *LockedError.
+func (file *dbFile) lockSource() error {
+	var source interface{} = file.source
+	if wrapper, ok := source.(interface{ unwrapSource() interface{} }); ok {
+		source = wrapper.unwrapSource()
+	}
+
+	osFile, ok := source.(*os.File)
+	if !ok || file.lockMode == LockNone {
+		return nil
+	}
+
+	deadline := time.Now().Add(file.lockTimeout)
+	for {
+		locked, err := tryLockFile(osFile, file.lockMode == LockShared)
+		if err != nil {
+			return err
+		} else if locked {
+			lockedFiles.Lock()
+			defer lockedFiles.Unlock()
+			if !lockedFiles.files[osFile] {
+				lockedFiles.files[osFile] = true
+				file.lockedFile = osFile
+			}
+			return nil
+		}
+
+		if !time.Now().Before(deadline) {
+			return &LockedError{Name: osFile.Name(), Mode: file.lockMode}
+		}
+		time.Sleep(lockPollInterval)
+	}
+}
+
+// unlockSource releases the advisory lock taken on the DB source by lockSource, if it took one.
+func (file *dbFile) unlockSource() error {
+	if file.lockedFile == nil {
+		return nil
+	}
+
+	lockedFiles.Lock()
+	delete(lockedFiles.files, file.lockedFile)
+	lockedFiles.Unlock()
+
+	osFile := file.lockedFile
+	file.lockedFile = nil
+	return unlockFile(osFile)
+}
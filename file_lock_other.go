@@ -0,0 +1,18 @@
+//go:build !darwin && !dragonfly && !freebsd && !linux && !netbsd && !openbsd
+// +build !darwin,!dragonfly,!freebsd,!linux,!netbsd,!openbsd
+
+package simpledb
+
+import (
+	"os"
+)
+
+// tryLockFile does nothing on systems without flock, and always succeeds.
+func tryLockFile(file *os.File, shared bool) (bool, error) {
+	return true, nil
+}
+
+// unlockFile does nothing on systems without flock.
+func unlockFile(file *os.File) error {
+	return nil
+}
@@ -0,0 +1,189 @@
+//go:build darwin || dragonfly || freebsd || linux || netbsd || openbsd
+// +build darwin dragonfly freebsd linux netbsd openbsd
+
+package simpledb
+
+import (
+	"errors"
+	"os"
+	"testing"
+	"time"
+)
+
+func TestFileLock(t *testing.T) {
+	type Job struct {
+		Name string
+	}
+
+	tempFile, err := os.CreateTemp(os.TempDir(), "simpledb-")
+	if err != nil {
+		t.Fatalf("Failed to create temp file: %s", err)
+	}
+
+	t.Cleanup(func() {
+		tempFile.Close()
+		os.Remove(tempFile.Name())
+	})
+
+	// each call opens a new *os.File, as another process would
+	open := func(options ...Option) (*DB, error) {
+		source, err := os.OpenFile(tempFile.Name(), os.O_RDWR, 0)
+		if err != nil {
+			t.Fatalf("Failed to open DB file: %s", err)
+		}
+		t.Cleanup(func() { source.Close() })
+		return NewDB(source, Job{}, options...)
+	}
+
+	expectLocked := func(err error, mode LockMode) {
+		var lockedErr *LockedError
+		if !errors.As(err, &lockedErr) {
+			t.Fatalf("expected *LockedError, got %v", err)
+		} else if lockedErr.Name != tempFile.Name() || lockedErr.Mode != mode {
+			t.Fatalf("unexpected LockedError: %+v", lockedErr)
+		}
+	}
+
+	db, err := open()
+	if err != nil {
+		t.Fatalf("failed to create DB: %s", err)
+	}
+	if _, err := db.Insert(Job{Name: "backup"}); err != nil {
+		t.Fatalf("Failed to insert job: %s", err)
+	}
+
+	_, err = open()
+	expectLocked(err, LockExclusive)
+	_, err = open(WithLock(LockShared))
+	expectLocked(err, LockShared)
+
+	start := time.Now()
+	_, err = open(WithLockTimeout(50 * time.Millisecond))
+	expectLocked(err, LockExclusive)
+	if elapsed := time.Since(start); elapsed < 50*time.Millisecond {
+		t.Fatalf("expected NewDB to wait for the lock, returned after %s", elapsed)
+	}
+
+	if unlocked, err := open(WithLock(LockNone)); err != nil {
+		t.Fatalf("Failed to open DB without locking: %s", err)
+	} else if unlocked.RowCount() != 1 {
+		t.Fatalf("expected 1 row, got %d", unlocked.RowCount())
+	}
+
+	// the same *os.File can be reopened
+	if _, err := NewDB(db.source, Job{}); err != nil {
+		t.Fatalf("Failed to reopen DB on the same file: %s", err)
+	}
+
+	if _, err := db.Table("logs", Job{}, WithLock(LockShared)); err == nil {
+		t.Fatalf("expected error passing WithLock to db.Table")
+	}
+
+	go func(db *DB) {
+		time.Sleep(20 * time.Millisecond)
+		db.Close()
+	}(db)
+	db, err = open(WithLockTimeout(5 * time.Second))
+	if err != nil {
+		t.Fatalf("Failed to wait for lock: %s", err)
+	}
+	if err := db.Close(); err != nil {
+		t.Fatalf("Failed to close DB: %s", err)
+	}
+
+	// shared locks can be held by several DBs, but not along with an exclusive lock
+	for i := 0; i < 2; i++ {
+		if _, err := open(WithLock(LockShared)); err != nil {
+			t.Fatalf("Failed to take shared lock: %s", err)
+		}
+	}
+	_, err = open()
+	expectLocked(err, LockExclusive)
+
+	// DBs holding shared locks cannot write, as other DBs may hold the same lock
+	shared, err := open(WithLock(LockShared))
+	if err != nil {
+		t.Fatalf("Failed to take shared lock: %s", err)
+	}
+	if _, err := shared.Insert(Job{Name: "cleanup"}); !errors.Is(err, ErrReadOnly) {
+		t.Fatalf("expected ErrReadOnly inserting into DB with shared lock, got: %v", err)
+	}
+	if err := shared.Begin().Commit(); err != ErrReadOnly {
+		t.Fatalf("expected ErrReadOnly committing a Tx on DB with shared lock, got: %v", err)
+	}
+
+	// read-only DBs take shared locks by default
+	readOnlyFile, err := os.Open(tempFile.Name())
+	if err != nil {
+		t.Fatalf("Failed to open DB file: %s", err)
+	}
+	t.Cleanup(func() { readOnlyFile.Close() })
+	if _, err := OpenReadOnly(readOnlyFile, Job{}); err != nil {
+		t.Fatalf("Failed to open DB read-only: %s", err)
+	}
+
+	exclusiveFile, err := os.Open(tempFile.Name())
+	if err != nil {
+		t.Fatalf("Failed to open DB file: %s", err)
+	}
+	t.Cleanup(func() { exclusiveFile.Close() })
+	_, err = OpenReadOnly(exclusiveFile, Job{}, WithLock(LockExclusive))
+	expectLocked(err, LockExclusive)
+}
+
+func TestFileLockReleasedOnError(t *testing.T) {
+	type Job struct {
+		Name string
+	}
+	type Task struct {
+		Priority uint8
+	}
+
+	tempFile, err := os.CreateTemp(os.TempDir(), "simpledb-")
+	if err != nil {
+		t.Fatalf("Failed to create temp file: %s", err)
+	}
+
+	t.Cleanup(func() {
+		tempFile.Close()
+		os.Remove(tempFile.Name())
+	})
+
+	open := func(exampleValue interface{}) (*DB, error) {
+		source, err := os.OpenFile(tempFile.Name(), os.O_RDWR, 0)
+		if err != nil {
+			t.Fatalf("Failed to open DB file: %s", err)
+		}
+		t.Cleanup(func() { source.Close() })
+		return NewDB(source, exampleValue)
+	}
+
+	db, err := open(Job{})
+	if err != nil {
+		t.Fatalf("failed to create DB: %s", err)
+	}
+	if _, err := db.Insert(Job{Name: "backup"}); err != nil {
+		t.Fatalf("Failed to insert job: %s", err)
+	}
+
+	// a failed NewDB call on the same *os.File leaves the lock held by the open DB
+	var mismatchErr *SchemaMismatchError
+	if _, err := NewDB(db.source, Task{}); !errors.As(err, &mismatchErr) {
+		t.Fatalf("expected *SchemaMismatchError, got %v", err)
+	}
+	if _, err := open(Job{}); err == nil {
+		t.Fatalf("expected DB source to still be locked")
+	}
+
+	if err := db.Close(); err != nil {
+		t.Fatalf("Failed to close DB: %s", err)
+	}
+
+	// a failed NewDB call on another *os.File releases the lock it took
+	if _, err := open(Task{}); !errors.As(err, &mismatchErr) {
+		t.Fatalf("expected *SchemaMismatchError, got %v", err)
+	}
+	if _, err := open(Job{}); err != nil {
+		t.Fatalf("expected lock to be released after failing to open DB: %s", err)
+	}
+}
@@ -0,0 +1,67 @@
+//go:build darwin || dragonfly || freebsd || linux || netbsd || openbsd
+// +build darwin dragonfly freebsd linux netbsd openbsd
+
+package simpledb
+
+import (
+	"os"
+	"syscall"
+)
+
+// tryLockFile takes an exclusive or shared flock on the file without blocking. It returns false
+// if the file is already locked by another open file description.
+func tryLockFile(file *os.File, shared bool) (bool, error) {
+	how := syscall.LOCK_EX
+	if shared {
+		how = syscall.LOCK_SH
+	}
+
+	conn, err := file.SyscallConn()
+	if err != nil {
+		return false, err
+	}
+
+	var lockErr error
+	if err := conn.Control(func(fd uintptr) {
+		for {
+			lockErr = syscall.Flock(int(fd), how|syscall.LOCK_NB)
+			if lockErr != syscall.EINTR {
+				return
+			}
+		}
+	}); err != nil {
+		return false, err
+	}
+
+	if lockErr == syscall.EWOULDBLOCK {
+		return false, nil
+	} else if lockErr != nil {
+		return false, &os.PathError{Op: "flock", Path: file.Name(), Err: lockErr}
+	}
+	return true, nil
+}
+
+// unlockFile releases the flock taken on the file by tryLockFile.
+func unlockFile(file *os.File) error {
+	conn, err := file.SyscallConn()
+	if err != nil {
+		return err
+	}
+
+	var unlockErr error
+	if err := conn.Control(func(fd uintptr) {
+		for {
+			unlockErr = syscall.Flock(int(fd), syscall.LOCK_UN)
+			if unlockErr != syscall.EINTR {
+				return
+			}
+		}
+	}); err != nil {
+		return err
+	}
+
+	if unlockErr != nil {
+		return &os.PathError{Op: "flock", Path: file.Name(), Err: unlockErr}
+	}
+	return nil
+}
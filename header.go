@@ -0,0 +1,277 @@
+package simpledb
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"reflect"
+	"strings"
+)
+
+// FormatVersion is the version of the on-disk file format written by this package.
+//
+// Version 1 files store a single table. Version 2 files store any number of named tables,
+// and each row header records the number of the table which the row belongs to.
+const FormatVersion uint16 = 2
+
+// fileMagic is the sequence of bytes which every SimpleDB file header begins with.
+var fileMagic = [8]byte{'s', 'i', 'm', 'p', 'l', 'e', 'd', 'b'}
+
+// minHeaderBodySize is the smallest size of the body of a new file header. The body is padded with zeros to
+// this size, or to twice its encoded size if that is larger, so that tables opened later can be recorded in
+// the header by overwriting it in place.
+const minHeaderBodySize = 1024
+
+// errHeaderFull is returned by encodeFileHeader if the tables do not fit in the given body size.
+var errHeaderFull = errors.New("file header has no room for more tables")
+
+// ErrInvalidHeader is returned when opening a DB whose Source begins with a
+// corrupted file header, or a header written by an unsupported format version.
+var ErrInvalidHeader = errors.New("DB source has an invalid file header")
+
+// Column describes a single column of a DB table, as recorded in the file header.
+// Type is a description of the column's binary encoding, such as "uint16", "string",
+// "[16]uint8", "[][]string", "map[string]uint32", "*uint32", or "struct{City string; Zip uint32}".
+// Named types are described by their underlying type, except for time.Time, which is described
+// as "time.Time", and types encoded with their MarshalBinary method, which are described as "binary".
+type Column struct {
+	Name string
+	Type string
+}
+
+// TableColumns describes the columns of a named table, as recorded in the file header.
+// The table opened by NewDB is named with an empty string.
+type TableColumns struct {
+	Name    string
+	Columns []Column
+}
+
+// SchemaMismatchError is returned when opening a table with a struct type whose columns
+// do not match the columns recorded in the file header of the Source.
+type SchemaMismatchError struct {
+	// Table is the name of the table whose schema does not match.
+	Table string
+
+	// Expected is the set of columns described by the struct type given to the DB.
+	Expected []Column
+
+	// Found is the set of columns recorded in the DB source's file header.
+	Found []Column
+}
+
+func (err *SchemaMismatchError) Error() string {
+	return fmt.Sprintf("DB source schema %v for table %q does not match the given struct type schema %v", err.Found, err.Table, err.Expected)
+}
+
+// fileHeaderBody is the part of the file header which follows the magic bytes,
+// format version, and body size. It is encoded with encodeStructToBinary.
+type fileHeaderBody struct {
+	ColumnNames [][]string
+	ColumnTypes [][]string
+	TableNames  []string
+}
+
+// fileHeaderBodyV1 is the body of a version 1 file header, which describes a single unnamed table.
+type fileHeaderBodyV1 struct {
+	ColumnNames []string
+	ColumnTypes []string
+}
+
+// columnTypeSignature describes the binary encoding of the given column type.
+func columnTypeSignature(t reflect.Type) string {
+	if t == timeType {
+		return "time.Time"
+	} else if isBinaryMarshalerType(t) {
+		return "binary"
+	}
+
+	switch t.Kind() {
+	case reflect.String:
+		return "string"
+	case reflect.Array:
+		return fmt.Sprintf("[%d]%s", t.Len(), columnTypeSignature(t.Elem()))
+	case reflect.Slice:
+		return "[]" + columnTypeSignature(t.Elem())
+	case reflect.Map:
+		return "map[" + columnTypeSignature(t.Key()) + "]" + columnTypeSignature(t.Elem())
+	case reflect.Ptr:
+		return "*" + columnTypeSignature(t.Elem())
+	case reflect.Struct:
+		fields := getExportedFields(t)
+		signatures := make([]string, len(fields))
+		for i, field := range fields {
+			signatures[i] = field.Name + " " + columnTypeSignature(field.Type)
+		}
+		return "struct{" + strings.Join(signatures, "; ") + "}"
+	}
+	return t.Kind().String()
+}
+
+// encodeFileHeader returns the file header which describes the given tables, and the size of its body. The
+// body is padded with zeros to the given size, so that the header can replace an existing one in place, or
+// returns errHeaderFull if the tables do not fit. If bodySize is zero, the body is padded to leave room for
+// more tables (see minHeaderBodySize). Readers ignore the padding, as it follows the encoded tables.
+func encodeFileHeader(tables []TableColumns, bodySize uint64) ([]byte, uint64, error) {
+	var body fileHeaderBody
+	for _, table := range tables {
+		columnNames := make([]string, len(table.Columns))
+		columnTypes := make([]string, len(table.Columns))
+		for i, column := range table.Columns {
+			columnNames[i] = column.Name
+			columnTypes[i] = column.Type
+		}
+		body.TableNames = append(body.TableNames, table.Name)
+		body.ColumnNames = append(body.ColumnNames, columnNames)
+		body.ColumnTypes = append(body.ColumnTypes, columnTypes)
+	}
+
+	bodyBuf := new(bytes.Buffer)
+	if _, err := encodeStructToBinary(bodyBuf, reflect.ValueOf(body)); err != nil {
+		return nil, 0, err
+	}
+
+	if bodySize == 0 {
+		bodySize = minHeaderBodySize
+		if 2*uint64(bodyBuf.Len()) > bodySize {
+			bodySize = 2 * uint64(bodyBuf.Len())
+		}
+	} else if uint64(bodyBuf.Len()) > bodySize {
+		return nil, 0, errHeaderFull
+	}
+	bodyBuf.Write(make([]byte, bodySize-uint64(bodyBuf.Len())))
+
+	header := new(bytes.Buffer)
+	header.Write(fileMagic[:])
+	binary.Write(header, binary.BigEndian, FormatVersion)
+	header.Write(encodeUvarint(bodySize))
+	bodyBuf.WriteTo(header)
+
+	return header.Bytes(), bodySize, nil
+}
+
+// fileHeader is the decoded form of a file header.
+type fileHeader struct {
+	version  uint16
+	size     int64
+	bodySize uint64
+	tables   []TableColumns
+}
+
+// readFileHeader reads a file header from the start of r. If r is empty, or begins with a row
+// rather than a header (as is the case with files written before headers were introduced),
+// it returns a header with a version and size of zero, and no tables.
+func readFileHeader(r io.Reader) (*fileHeader, error) {
+	var magic [8]byte
+	if _, err := io.ReadFull(r, magic[:]); err != nil {
+		if err == io.EOF {
+			return new(fileHeader), nil
+		} else if err == io.ErrUnexpectedEOF {
+			return nil, ErrInvalidHeader
+		}
+		return nil, err
+	}
+
+	if magic != fileMagic {
+		return new(fileHeader), nil
+	}
+
+	header := new(fileHeader)
+	if err := binary.Read(r, binary.BigEndian, &header.version); err != nil {
+		return nil, ErrInvalidHeader
+	}
+	if header.version == 0 || header.version > FormatVersion {
+		return nil, fmt.Errorf("%w: unsupported format version %d", ErrInvalidHeader, header.version)
+	}
+
+	bodySize, err := binary.ReadUvarint(&wrappedByteReader{Reader: r})
+	if err != nil {
+		return nil, ErrInvalidHeader
+	}
+
+	bodyData := make([]byte, bodySize)
+	if _, err := io.ReadFull(r, bodyData); err != nil {
+		return nil, ErrInvalidHeader
+	}
+
+	var body fileHeaderBody
+	if header.version == 1 {
+		var bodyV1 fileHeaderBodyV1
+		if _, err := decodeStructFromBinary(bytes.NewReader(bodyData), reflect.ValueOf(&bodyV1)); err != nil {
+			return nil, ErrInvalidHeader
+		}
+		body.TableNames = []string{""}
+		body.ColumnNames = [][]string{bodyV1.ColumnNames}
+		body.ColumnTypes = [][]string{bodyV1.ColumnTypes}
+	} else if _, err := decodeStructFromBinary(bytes.NewReader(bodyData), reflect.ValueOf(&body)); err != nil {
+		return nil, ErrInvalidHeader
+	}
+
+	if len(body.ColumnNames) != len(body.TableNames) || len(body.ColumnTypes) != len(body.TableNames) {
+		return nil, ErrInvalidHeader
+	}
+
+	header.tables = make([]TableColumns, len(body.TableNames))
+	for i, tableName := range body.TableNames {
+		if len(body.ColumnNames[i]) != len(body.ColumnTypes[i]) {
+			return nil, ErrInvalidHeader
+		}
+
+		columns := make([]Column, len(body.ColumnNames[i]))
+		for j := range columns {
+			columns[j] = Column{
+				Name: body.ColumnNames[i][j],
+				Type: body.ColumnTypes[i][j],
+			}
+		}
+		header.tables[i] = TableColumns{Name: tableName, Columns: columns}
+	}
+
+	header.bodySize = bodySize
+	header.size = int64(len(fileMagic)) + 2 + int64(len(encodeUvarint(bodySize))) + int64(bodySize)
+	return header, nil
+}
+
+// ReadTableColumns reads the file header at the start of r, and returns the columns of every table
+// stored in it. This can be used to inspect a DB file without knowing its struct types.
+// Returns ErrInvalidHeader if r does not begin with a valid file header.
+func ReadTableColumns(r io.Reader) ([]TableColumns, error) {
+	header, err := readFileHeader(r)
+	if err != nil {
+		return nil, err
+	} else if header.size == 0 {
+		return nil, ErrInvalidHeader
+	}
+	return header.tables, nil
+}
+
+// ReadColumns reads the file header at the start of r, and returns the columns of the table opened by
+// NewDB. This can be used to inspect a DB file without knowing its struct type. Returns ErrInvalidHeader
+// if r does not begin with a valid file header, or ErrNotFound if the file has no such table.
+func ReadColumns(r io.Reader) ([]Column, error) {
+	tables, err := ReadTableColumns(r)
+	if err != nil {
+		return nil, err
+	}
+	for _, table := range tables {
+		if table.Name == "" {
+			return table.Columns, nil
+		}
+	}
+	return nil, ErrNotFound
+}
+
+// validateColumns returns a *SchemaMismatchError if the given
+// columns from a file header do not match the table's schema.
+func (db *DB) validateColumns(found []Column) error {
+	expected := db.schema.Columns()
+	if !reflect.DeepEqual(expected, found) {
+		return &SchemaMismatchError{
+			Table:    db.name,
+			Expected: expected,
+			Found:    found,
+		}
+	}
+	return nil
+}
@@ -0,0 +1,257 @@
+package simpledb
+
+import (
+	"errors"
+	"io"
+	"os"
+	"reflect"
+	"testing"
+)
+
+func TestFileHeader(t *testing.T) {
+	type Car struct {
+		Year   uint16
+		Make   string
+		Serial [4]byte
+		Owners []string
+	}
+
+	tempFile, err := os.CreateTemp(os.TempDir(), "simpledb-")
+	if err != nil {
+		t.Fatalf("Failed to create temp file: %s", err)
+	}
+
+	t.Cleanup(func() {
+		tempFile.Close()
+		os.Remove(tempFile.Name())
+	})
+
+	db, err := NewDB(tempFile, Car{})
+	if err != nil {
+		t.Fatalf("failed to create DB: %s", err)
+	}
+
+	if size, _ := db.Size(); size != 0 {
+		t.Fatalf("expected empty DB to have zero size, got %d", size)
+	}
+
+	id, err := db.Insert(Car{Year: 2008, Make: "Mazda", Owners: []string{"bob"}})
+	if err != nil {
+		t.Fatalf("Failed to insert car: %s", err)
+	}
+
+	tempFile.Seek(0, io.SeekStart)
+	columns, err := ReadColumns(tempFile)
+	if err != nil {
+		t.Fatalf("Failed to read columns from file header: %s", err)
+	}
+
+	expectedColumns := []Column{
+		{Name: "Make", Type: "string"},
+		{Name: "Owners", Type: "[]string"},
+		{Name: "Serial", Type: "[4]uint8"},
+		{Name: "Year", Type: "uint16"},
+	}
+	if !reflect.DeepEqual(columns, expectedColumns) {
+		t.Fatalf("columns in file header do not match\nWanted %v\nGot    %v", expectedColumns, columns)
+	}
+
+	db, err = NewDB(tempFile, Car{})
+	if err != nil {
+		t.Fatalf("Failed to reopen DB: %s", err)
+	}
+
+	var car Car
+	if err := db.Find(id, &car); err != nil {
+		t.Fatalf("Failed to find car in reopened DB: %s", err)
+	}
+	if car.Make != "Mazda" {
+		t.Fatalf("found car does not match")
+	}
+
+	type WrongCar struct {
+		Year   uint32
+		Make   string
+		Serial [4]byte
+		Owners []string
+	}
+
+	var mismatch *SchemaMismatchError
+	if _, err := NewDB(tempFile, WrongCar{}); !errors.As(err, &mismatch) {
+		t.Fatalf("expected SchemaMismatchError when opening DB with wrong struct type, got: %v", err)
+	}
+	if !reflect.DeepEqual(mismatch.Found, expectedColumns) {
+		t.Fatalf("SchemaMismatchError does not report columns found in file header")
+	}
+
+	if err := db.Defrag(); err != nil {
+		t.Fatalf("Failed to defrag DB: %s", err)
+	}
+	if _, err := NewDB(tempFile, WrongCar{}); !errors.As(err, &mismatch) {
+		t.Fatalf("expected SchemaMismatchError after defrag, got: %v", err)
+	}
+
+	tempFile.Seek(0, io.SeekStart)
+	tempFile.Write([]byte("simpledb\xff\xff"))
+	if _, err := NewDB(tempFile, Car{}); !errors.Is(err, ErrInvalidHeader) {
+		t.Fatalf("expected ErrInvalidHeader when opening DB with unknown format version, got: %v", err)
+	}
+}
+
+func TestFileHeaderLegacy(t *testing.T) {
+	type Car struct {
+		Year uint16
+		Make string
+	}
+
+	tempFile, err := os.CreateTemp(os.TempDir(), "simpledb-")
+	if err != nil {
+		t.Fatalf("Failed to create temp file: %s", err)
+	}
+
+	t.Cleanup(func() {
+		tempFile.Close()
+		os.Remove(tempFile.Name())
+	})
+
+	// A row written without any file header, as older versions of this package did.
+	var id uint64 = 0xabcdef
+	tempFile.Write(encodeRowHeader(id, 7))
+	tempFile.Write([]byte{4, 'F', 'o', 'r', 'd', 0x07, 0xd0})
+
+	db, err := NewDB(tempFile, Car{})
+	if err != nil {
+		t.Fatalf("Failed to open legacy DB: %s", err)
+	}
+
+	var car Car
+	if err := db.Find(id, &car); err != nil {
+		t.Fatalf("Failed to find car in legacy DB: %s", err)
+	}
+	if car.Make != "Ford" || car.Year != 2000 {
+		t.Fatalf("found car does not match: %+v", car)
+	}
+
+	if err := db.Defrag(); err != nil {
+		t.Fatalf("Failed to defrag DB: %s", err)
+	}
+
+	tempFile.Seek(0, io.SeekStart)
+	if _, err := ReadColumns(tempFile); err != nil {
+		t.Fatalf("expected defrag to write a file header: %s", err)
+	}
+
+	db, err = NewDB(tempFile, Car{})
+	if err != nil {
+		t.Fatalf("Failed to reopen upgraded DB: %s", err)
+	}
+
+	if err := db.Find(id, &car); err != nil {
+		t.Fatalf("Failed to find car after defrag: %s", err)
+	}
+}
+
+func TestFileHeaderLegacyBinaryMarshaler(t *testing.T) {
+	type Account struct {
+		ID   testUUID
+		Name string
+	}
+
+	tempFile, err := os.CreateTemp(os.TempDir(), "simpledb-")
+	if err != nil {
+		t.Fatalf("Failed to create temp file: %s", err)
+	}
+
+	t.Cleanup(func() {
+		tempFile.Close()
+		os.Remove(tempFile.Name())
+	})
+
+	// testUUID is a [4]byte, so rows written without a file header store it as a plain array.
+	var id uint64 = 0xabcdef
+	tempFile.Write(encodeRowHeader(id, 10))
+	tempFile.Write([]byte{1, 2, 3, 4, 5, 'a', 'l', 'i', 'c', 'e'})
+
+	db, err := NewDB(tempFile, Account{})
+	if err != nil {
+		t.Fatalf("Failed to open legacy DB: %s", err)
+	}
+
+	var account Account
+	if err := db.Find(id, &account); err != nil {
+		t.Fatalf("Failed to find account in legacy DB: %s", err)
+	}
+	if account.ID != (testUUID{1, 2, 3, 4}) || account.Name != "alice" {
+		t.Fatalf("found account does not match: %+v", account)
+	}
+
+	type Payment struct {
+		Amount testAmount
+		Name   string
+	}
+	if _, err := NewDB(tempFile, Payment{}); err != errHeaderlessBinaryMarshaler {
+		t.Fatalf("expected error opening legacy DB with a MarshalBinary column, got: %v", err)
+	}
+}
+
+func TestFileHeaderVersion1(t *testing.T) {
+	type Car struct {
+		Year uint16
+		Make string
+	}
+
+	tempFile, err := os.CreateTemp(os.TempDir(), "simpledb-")
+	if err != nil {
+		t.Fatalf("Failed to create temp file: %s", err)
+	}
+
+	t.Cleanup(func() {
+		tempFile.Close()
+		os.Remove(tempFile.Name())
+	})
+
+	// A version 1 file header, describing a single table, followed by a row without a table number.
+	tempFile.Write([]byte("simpledb\x00\x01\x1a\x02\x04Make\x04Year\x02\x06string\x06uint16"))
+	var id uint64 = 0xabcdef
+	tempFile.Write(encodeRowHeader(id, 7))
+	tempFile.Write([]byte{4, 'F', 'o', 'r', 'd', 0x07, 0xd0})
+
+	db, err := NewDB(tempFile, Car{})
+	if err != nil {
+		t.Fatalf("Failed to open version 1 DB: %s", err)
+	}
+
+	type Boat struct {
+		Name string
+	}
+
+	boats, err := db.Table("boats", Boat{})
+	if err != nil {
+		t.Fatalf("Failed to open boats table: %s", err)
+	}
+
+	// upgrades the file to the current format version
+	boatID, err := boats.Insert(Boat{Name: "Nautilus"})
+	if err != nil {
+		t.Fatalf("Failed to insert boat: %s", err)
+	}
+
+	db, err = NewDB(tempFile, Car{})
+	if err != nil {
+		t.Fatalf("Failed to reopen upgraded DB: %s", err)
+	}
+
+	var car Car
+	if err := db.Find(id, &car); err != nil || car.Make != "Ford" || car.Year != 2000 {
+		t.Fatalf("Failed to find car in upgraded DB: %v", err)
+	}
+
+	if boats, err = db.Table("boats", Boat{}); err != nil {
+		t.Fatalf("Failed to open boats table in upgraded DB: %s", err)
+	}
+
+	var boat Boat
+	if err := boats.Find(boatID, &boat); err != nil || boat.Name != "Nautilus" {
+		t.Fatalf("Failed to find boat in upgraded DB: %v", err)
+	}
+}
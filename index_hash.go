@@ -0,0 +1,196 @@
+package simpledb
+
+import (
+	"bytes"
+	"encoding/binary"
+	"math"
+	"reflect"
+)
+
+// hashIndex is an inverted index of an indexed column, mapping the key of each value held by the column
+// (see indexKey) to the set of IDs of the rows which hold it. It allows rows with a given value to be
+// found without checking every row in the table.
+type hashIndex map[string]map[uint64]struct{}
+
+// add records that the row with the given ID holds the given column value.
+func (index hashIndex) add(id uint64, value interface{}) {
+	key := indexKey(reflect.ValueOf(value))
+	ids, ok := index[key]
+	if !ok {
+		ids = make(map[uint64]struct{})
+		index[key] = ids
+	}
+	ids[id] = struct{}{}
+}
+
+// remove removes the entry for the row with the given ID, which holds the given column value.
+func (index hashIndex) remove(id uint64, value interface{}) {
+	key := indexKey(reflect.ValueOf(value))
+	if ids, ok := index[key]; ok {
+		delete(ids, id)
+		if len(ids) == 0 {
+			delete(index, key)
+		}
+	}
+}
+
+// indexKey encodes a column value as a string, such that two values of the same column type have the same
+// key if they are equal. Unlike the binary encoding of the value, negative and positive zero share a key.
+// Times share a key if they represent the same instant, like their binary encoding, and values of types
+// implementing encoding.BinaryMarshaler share a key if they marshal to the same bytes.
+func indexKey(v reflect.Value) string {
+	buf := new(bytes.Buffer)
+	writeIndexKey(buf, v)
+	return buf.String()
+}
+
+func writeIndexKey(buf *bytes.Buffer, v reflect.Value) {
+	switch kind := v.Kind(); {
+	case v.Type() == timeType:
+		encodeTimeToBinary(buf, v)
+	case isBinaryMarshalerType(v.Type()):
+		encodeBinaryMarshalerToBinary(buf, v)
+	case kind == reflect.Float32 || kind == reflect.Float64:
+		writeIndexKeyFloat(buf, v.Float())
+	case isComplexKind(kind):
+		writeIndexKeyFloat(buf, real(v.Complex()))
+		writeIndexKeyFloat(buf, imag(v.Complex()))
+	case kind == reflect.String:
+		buf.Write(encodeUvarint(uint64(v.Len())))
+		buf.WriteString(v.String())
+	case kind == reflect.Array || kind == reflect.Slice:
+		buf.Write(encodeUvarint(uint64(v.Len())))
+		for i := 0; i < v.Len(); i++ {
+			writeIndexKey(buf, v.Index(i))
+		}
+	case kind == reflect.Struct:
+		for _, fieldName := range getExportedFieldNames(v.Type()) {
+			writeIndexKey(buf, v.FieldByName(fieldName))
+		}
+	case kind == reflect.Ptr:
+		if v.IsNil() {
+			buf.WriteByte(0)
+		} else {
+			buf.WriteByte(1)
+			writeIndexKey(buf, v.Elem())
+		}
+	case kind == reflect.Map:
+		entries, _ := encodeMapEntries(v, func(buf *bytes.Buffer, v reflect.Value) error {
+			writeIndexKey(buf, v)
+			return nil
+		})
+		buf.Write(encodeUvarint(uint64(len(entries))))
+		for _, entry := range entries {
+			buf.Write(entry)
+		}
+	default:
+		encodeToBinary(buf, v)
+	}
+}
+
+func writeIndexKeyFloat(buf *bytes.Buffer, f float64) {
+	if f == 0 {
+		f = 0 // normalize negative zero
+	}
+	binary.Write(buf, binary.BigEndian, math.Float64bits(f))
+}
+
+// equalityKeys returns the index keys of the values of the given column type which could match the given
+// value from a Where, if it is a plain value, an Equal or In Condition, or an IsNull Condition. The
+// second return value is false if the matching values cannot be described as a set of keys.
+func equalityKeys(queryValue interface{}, columnType reflect.Type) ([]string, bool) {
+	if null, ok := queryValue.(*nullCondition); ok {
+		if !null.null {
+			return nil, false
+		} else if columnType.Kind() != reflect.Ptr {
+			return nil, true
+		}
+		return []string{indexKey(reflect.Zero(columnType))}, true
+	}
+
+	c, ok := queryValue.(*comparison)
+	if !ok {
+		if _, ok := queryValue.(Condition); ok {
+			return nil, false
+		}
+
+		// Plain values are compared with reflect.DeepEqual, which never matches values of a different type.
+		if reflect.TypeOf(queryValue) != columnType {
+			return nil, true
+		}
+		return []string{indexKey(reflect.ValueOf(queryValue))}, true
+	}
+
+	if c.operator != opEqual && c.operator != opIn {
+		return nil, false
+	}
+
+	keys := make([]string, 0, len(c.operands))
+	for _, operand := range c.operands {
+		value, ok, narrowed := convertOperand(reflect.ValueOf(operand), columnType)
+		if !narrowed {
+			return nil, false
+		} else if ok {
+			keys = append(keys, indexKey(value))
+		}
+	}
+	return keys, true
+}
+
+// convertOperand converts an operand of a Condition to the given column type, so that its index key can
+// be found. The second return value is false if no value of the column type can be equal to the operand.
+// The third return value is false if the values equal to the operand cannot be found by converting it.
+func convertOperand(operand reflect.Value, columnType reflect.Type) (reflect.Value, bool, bool) {
+	if !operand.IsValid() || isNaN(operand) {
+		return operand, false, true
+	} else if operand.Type() == columnType {
+		return operand, true, true
+	}
+
+	// Pointer operands and columns are compared by the values they point to.
+	if operand.Kind() == reflect.Ptr {
+		if operand.IsNil() {
+			return operand, false, true
+		}
+		return convertOperand(operand.Elem(), columnType)
+	} else if columnType.Kind() == reflect.Ptr {
+		converted, ok, narrowed := convertOperand(operand, columnType.Elem())
+		if ok {
+			pointer := reflect.New(columnType.Elem())
+			pointer.Elem().Set(converted)
+			converted = pointer
+		}
+		return converted, ok, narrowed
+	}
+
+	operandKind, columnKind := operand.Kind(), columnType.Kind()
+	switch {
+	case isRealKind(operandKind) && isRealKind(columnKind):
+		// Integers beyond the precision of a float64 can compare equal to several different float values.
+		if !isIntegerKind(operandKind) && isIntegerKind(columnKind) && math.Abs(operand.Float()) >= 1<<53 {
+			return operand, false, false
+		}
+
+		converted := operand.Convert(columnType)
+		if result, ok := compareValues(converted, operand); !ok || result != 0 {
+			return operand, false, true
+		}
+		return converted, true, true
+
+	case isComplexKind(operandKind) && isComplexKind(columnKind):
+		converted := operand.Convert(columnType)
+		if converted.Complex() != operand.Complex() {
+			return operand, false, true
+		}
+		return converted, true, true
+
+	case operandKind == reflect.String && columnKind == reflect.String,
+		operandKind == reflect.Bool && columnKind == reflect.Bool:
+		return operand.Convert(columnType), true, true
+
+	case !isComparableType(operand.Type(), columnType):
+		return operand, false, true
+	}
+
+	return operand, false, false
+}
@@ -0,0 +1,161 @@
+package simpledb
+
+import (
+	"math"
+	"os"
+	"reflect"
+	"sort"
+	"testing"
+)
+
+func TestIndexKey(t *testing.T) {
+	if indexKey(reflect.ValueOf(math.Copysign(0, -1))) != indexKey(reflect.ValueOf(0.0)) {
+		t.Errorf("expected negative and positive zero to share an index key")
+	}
+	if indexKey(reflect.ValueOf([]string{"ab", "c"})) == indexKey(reflect.ValueOf([]string{"a", "bc"})) {
+		t.Errorf("expected different string slices to have different index keys")
+	}
+
+	type Fixture struct {
+		queryValue interface{}
+		columnType reflect.Type
+		keys       []string
+		narrowed   bool
+	}
+
+	uint16Type := reflect.TypeOf(uint16(0))
+	fixtures := []Fixture{
+		{uint16(7), uint16Type, []string{indexKey(reflect.ValueOf(uint16(7)))}, true},
+		{7, uint16Type, nil, true},
+		{Equal(7), uint16Type, []string{indexKey(reflect.ValueOf(uint16(7)))}, true},
+		{Equal(7.0), uint16Type, []string{indexKey(reflect.ValueOf(uint16(7)))}, true},
+		{Equal(7.5), uint16Type, []string{}, true},
+		{Equal(-1), uint16Type, []string{}, true},
+		{Equal(70000), uint16Type, []string{}, true},
+		{Equal("7"), uint16Type, []string{}, true},
+		{Equal(math.NaN()), reflect.TypeOf(0.0), []string{}, true},
+		{Equal(float64(1 << 60)), reflect.TypeOf(int64(0)), nil, false},
+		{In(1, 2), uint16Type, []string{indexKey(reflect.ValueOf(uint16(1))), indexKey(reflect.ValueOf(uint16(2)))}, true},
+		{Equal([]byte{1}), reflect.TypeOf([1]byte{}), nil, false},
+		{GreaterThan(7), uint16Type, nil, false},
+	}
+
+	for i, fixture := range fixtures {
+		keys, narrowed := equalityKeys(fixture.queryValue, fixture.columnType)
+		if narrowed != fixture.narrowed || !reflect.DeepEqual(keys, fixture.keys) {
+			t.Errorf("fixture %d: unexpected equality keys %q (narrowed: %v)", i, keys, narrowed)
+		}
+	}
+}
+
+func TestHashIndex(t *testing.T) {
+	type User struct {
+		Email string  `simpledb:"indexed"`
+		Score float32 `simpledb:"indexed"`
+		Name  string
+	}
+
+	tempFile, err := os.CreateTemp(os.TempDir(), "simpledb-")
+	if err != nil {
+		t.Fatalf("Failed to create temp file: %s", err)
+	}
+	snapshotFile, err := os.CreateTemp(os.TempDir(), "simpledb-snapshot-")
+	if err != nil {
+		t.Fatalf("Failed to create snapshot file: %s", err)
+	}
+
+	t.Cleanup(func() {
+		tempFile.Close()
+		snapshotFile.Close()
+		os.Remove(tempFile.Name())
+		os.Remove(snapshotFile.Name())
+	})
+
+	db, err := NewDB(tempFile, User{}, WithIndexSnapshot(snapshotFile))
+	if err != nil {
+		t.Fatalf("failed to create DB: %s", err)
+	}
+
+	emails := []string{"a@x.com", "b@x.com", "c@x.com", "a@x.com"}
+	ids := make([]uint64, len(emails))
+	for i, email := range emails {
+		if ids[i], err = db.Insert(User{Email: email, Score: float32(i)}); err != nil {
+			t.Fatalf("Failed to insert user: %s", err)
+		}
+	}
+
+	if err := db.Update(ids[1], User{Email: "a@x.com", Score: 9}); err != nil {
+		t.Fatalf("Failed to update user: %s", err)
+	}
+	if err := db.Drop(ids[3]); err != nil {
+		t.Fatalf("Failed to drop user: %s", err)
+	}
+
+	sortedIDs := func(ids ...uint64) []uint64 {
+		sorted := append([]uint64{}, ids...)
+		sort.Slice(sorted, func(i, j int) bool { return sorted[i] < sorted[j] })
+		return sorted
+	}
+
+	check := func(db *DB) {
+		type Fixture struct {
+			query    Query
+			expected []uint64
+		}
+
+		fixtures := []Fixture{
+			{Where{"Email": "a@x.com"}, sortedIDs(ids[0], ids[1])},
+			{Where{"Email": "b@x.com"}, sortedIDs()},
+			{Where{"Email": In("c@x.com", "d@x.com")}, sortedIDs(ids[2])},
+			{Where{"Email": "a@x.com", "Score": Equal(9)}, sortedIDs(ids[1])},
+			{Or(Where{"Score": float32(0)}, Where{"Email": Equal("c@x.com")}), sortedIDs(ids[0], ids[2])},
+		}
+
+		for i, fixture := range fixtures {
+			candidates, ok := db.candidates(fixture.query)
+			if !ok {
+				t.Fatalf("expected query %d to be narrowed down using the hash index", i)
+			}
+			if actual := sortedIDs(candidates...); !reflect.DeepEqual(actual, fixture.expected) {
+				t.Errorf("query %d returned candidates %v, expected %v", i, actual, fixture.expected)
+			}
+
+			rows, err := db.Select(fixture.query)
+			if err != nil {
+				t.Fatalf("Failed to filter users: %s", err)
+			}
+			actual := make([]uint64, len(rows))
+			for j, row := range rows {
+				actual[j] = row.ID
+			}
+			if actual := sortedIDs(actual...); !reflect.DeepEqual(actual, fixture.expected) {
+				t.Errorf("query %d returned rows %v, expected %v", i, actual, fixture.expected)
+			}
+		}
+
+		if _, ok := db.candidates(Where{"Name": ""}); ok {
+			t.Errorf("expected query on unindexed column not to be narrowed down")
+		}
+	}
+
+	check(db)
+
+	if err := db.Close(); err != nil {
+		t.Fatalf("Failed to close DB: %s", err)
+	}
+
+	tempFile, err = os.OpenFile(tempFile.Name(), os.O_RDWR, 0)
+	if err != nil {
+		t.Fatalf("Failed to reopen temp file: %s", err)
+	}
+	snapshotFile, err = os.OpenFile(snapshotFile.Name(), os.O_RDWR, 0)
+	if err != nil {
+		t.Fatalf("Failed to reopen snapshot file: %s", err)
+	}
+
+	db, err = NewDB(tempFile, User{}, WithIndexSnapshot(snapshotFile))
+	if err != nil {
+		t.Fatalf("Failed to reopen DB: %s", err)
+	}
+	check(db)
+}
@@ -0,0 +1,377 @@
+package simpledb
+
+import (
+	"math"
+	"reflect"
+	"sort"
+)
+
+const (
+	// btreeDegree is the minimum degree of the B-tree backing an ordered index. Every node except
+	// the root holds between btreeDegree-1 and 2*btreeDegree-1 items.
+	btreeDegree   = 16
+	btreeMinItems = btreeDegree - 1
+	btreeMaxItems = 2*btreeDegree - 1
+)
+
+// orderedIndex is a B-tree of the values of an indexed column, ordered by value and then by row ID.
+// It is used by columns tagged with `simpledb:"indexed,ordered"`, so that equality and range lookups
+// only visit matching rows, and rows can be visited in the column's order.
+type orderedIndex struct {
+	root   *btreeNode
+	length int
+}
+
+// btreeItem is an entry in an orderedIndex, mapping a column value to the ID of a row holding it.
+type btreeItem struct {
+	key reflect.Value
+	id  uint64
+}
+
+type btreeNode struct {
+	items    []btreeItem
+	children []*btreeNode
+}
+
+// compareIndexKeys is a total order over values of an ordered column type. It agrees with compareValues
+// wherever compareValues succeeds, and orders NaN before every other floating point number.
+func compareIndexKeys(a, b reflect.Value) int {
+	if a.Kind() == reflect.Array || a.Kind() == reflect.Slice {
+		for i := 0; i < a.Len() && i < b.Len(); i++ {
+			if result := compareIndexKeys(a.Index(i), b.Index(i)); result != 0 {
+				return result
+			}
+		}
+		return compareIntegers(reflect.ValueOf(a.Len()), reflect.ValueOf(b.Len()))
+	}
+
+	aNaN, bNaN := isNaN(a), isNaN(b)
+	if aNaN || bNaN {
+		if aNaN && bNaN {
+			return 0
+		} else if aNaN {
+			return -1
+		}
+		return 1
+	}
+
+	result, _ := compareValues(a, b)
+	return result
+}
+
+func isNaN(v reflect.Value) bool {
+	return (v.Kind() == reflect.Float32 || v.Kind() == reflect.Float64) && math.IsNaN(v.Float())
+}
+
+func compareBtreeItems(a, b btreeItem) int {
+	if result := compareIndexKeys(a.key, b.key); result != 0 {
+		return result
+	}
+	if a.id < b.id {
+		return -1
+	} else if a.id > b.id {
+		return 1
+	}
+	return 0
+}
+
+// add records that the row with the given ID holds the given column value.
+func (index *orderedIndex) add(id uint64, value interface{}) {
+	item := btreeItem{reflect.ValueOf(value), id}
+
+	if index.root == nil {
+		index.root = &btreeNode{items: []btreeItem{item}}
+		index.length = 1
+		return
+	}
+
+	if len(index.root.items) >= btreeMaxItems {
+		middle, right := index.root.split(btreeMaxItems / 2)
+		index.root = &btreeNode{
+			items:    []btreeItem{middle},
+			children: []*btreeNode{index.root, right},
+		}
+	}
+
+	if index.root.insert(item) {
+		index.length += 1
+	}
+}
+
+// remove removes the entry for the row with the given ID, which holds the given column value.
+func (index *orderedIndex) remove(id uint64, value interface{}) {
+	if index.root == nil {
+		return
+	}
+
+	if index.root.remove(btreeItem{reflect.ValueOf(value), id}, false) {
+		index.length -= 1
+	}
+
+	if len(index.root.items) == 0 {
+		if len(index.root.children) > 0 {
+			index.root = index.root.children[0]
+		} else {
+			index.root = nil
+		}
+	}
+}
+
+// scan calls visit with the ID of every row whose column value is in the given range, in ascending
+// order of value, or descending order if reverse is true. Rows holding the same value are visited
+// in order of their IDs. Scanning stops early if visit returns false.
+func (index *orderedIndex) scan(r keyRange, reverse bool, visit func(id uint64) bool) {
+	if index.root == nil {
+		return
+	}
+
+	if reverse {
+		index.root.descend(r.belowMax, func(item btreeItem) bool {
+			if !r.aboveMin(item.key) {
+				return false
+			}
+			return visit(item.id)
+		})
+	} else {
+		index.root.ascend(r.aboveMin, func(item btreeItem) bool {
+			if !r.belowMax(item.key) {
+				return false
+			}
+			return visit(item.id)
+		})
+	}
+}
+
+// find returns the index at which the item is stored in the node, or should be inserted if it is not
+// stored in the node. The second return value is true if the item is stored in the node.
+func (node *btreeNode) find(item btreeItem) (int, bool) {
+	i := sort.Search(len(node.items), func(i int) bool {
+		return compareBtreeItems(item, node.items[i]) < 0
+	})
+	if i > 0 && compareBtreeItems(node.items[i-1], item) == 0 {
+		return i - 1, true
+	}
+	return i, false
+}
+
+// split splits the node at the given item index, returning that item and a new node holding every
+// item and child after it.
+func (node *btreeNode) split(i int) (btreeItem, *btreeNode) {
+	middle := node.items[i]
+	next := &btreeNode{
+		items: append([]btreeItem(nil), node.items[i+1:]...),
+	}
+	node.items = node.items[:i]
+	if len(node.children) > 0 {
+		next.children = append([]*btreeNode(nil), node.children[i+1:]...)
+		node.children = node.children[:i+1]
+	}
+	return middle, next
+}
+
+// insert inserts the item into the subtree, which must not be full. Returns false
+// if the item was already stored in the subtree.
+func (node *btreeNode) insert(item btreeItem) bool {
+	i, found := node.find(item)
+	if found {
+		return false
+	}
+
+	if len(node.children) == 0 {
+		node.items = insertBtreeItem(node.items, i, item)
+		return true
+	}
+
+	if len(node.children[i].items) >= btreeMaxItems {
+		middle, right := node.children[i].split(btreeMaxItems / 2)
+		node.items = insertBtreeItem(node.items, i, middle)
+		node.children = insertBtreeNode(node.children, i+1, right)
+
+		switch result := compareBtreeItems(item, middle); {
+		case result == 0:
+			return false
+		case result > 0:
+			i += 1
+		}
+	}
+
+	return node.children[i].insert(item)
+}
+
+// remove removes the item from the subtree, or the greatest item in the subtree if max is true.
+// Returns false if the item was not found. The caller must ensure the node has more than the
+// minimum number of items, unless it is the root.
+func (node *btreeNode) remove(item btreeItem, max bool) bool {
+	var (
+		i     int
+		found bool
+	)
+
+	if max {
+		i = len(node.items)
+		if len(node.children) == 0 {
+			node.items = node.items[:i-1]
+			return true
+		}
+	} else {
+		i, found = node.find(item)
+		if len(node.children) == 0 {
+			if found {
+				node.items = removeBtreeItem(node.items, i)
+			}
+			return found
+		}
+	}
+
+	// Make sure the child we descend into can lose an item.
+	if len(node.children[i].items) <= btreeMinItems {
+		node.growChild(i)
+		return node.remove(item, max)
+	}
+
+	child := node.children[i]
+	if found {
+		// replace the item with its predecessor
+		node.items[i] = child.max()
+		return child.remove(btreeItem{}, true)
+	}
+	return child.remove(item, max)
+}
+
+// max returns the greatest item in the subtree.
+func (node *btreeNode) max() btreeItem {
+	for len(node.children) > 0 {
+		node = node.children[len(node.children)-1]
+	}
+	return node.items[len(node.items)-1]
+}
+
+// growChild ensures the child at index i has more than the minimum number of items, by taking
+// an item from one of its siblings, or merging it with one of its siblings.
+func (node *btreeNode) growChild(i int) {
+	if i > 0 && len(node.children[i-1].items) > btreeMinItems {
+		child, left := node.children[i], node.children[i-1]
+
+		last := len(left.items) - 1
+		child.items = insertBtreeItem(child.items, 0, node.items[i-1])
+		node.items[i-1] = left.items[last]
+		left.items = left.items[:last]
+
+		if len(left.children) > 0 {
+			child.children = insertBtreeNode(child.children, 0, left.children[last+1])
+			left.children = left.children[:last+1]
+		}
+		return
+	}
+
+	if i < len(node.items) && len(node.children[i+1].items) > btreeMinItems {
+		child, right := node.children[i], node.children[i+1]
+
+		child.items = append(child.items, node.items[i])
+		node.items[i] = right.items[0]
+		right.items = removeBtreeItem(right.items, 0)
+
+		if len(right.children) > 0 {
+			child.children = append(child.children, right.children[0])
+			right.children = append(right.children[:0], right.children[1:]...)
+		}
+		return
+	}
+
+	if i >= len(node.items) {
+		i -= 1
+	}
+	child, right := node.children[i], node.children[i+1]
+
+	child.items = append(child.items, node.items[i])
+	child.items = append(child.items, right.items...)
+	child.children = append(child.children, right.children...)
+
+	node.items = removeBtreeItem(node.items, i)
+	node.children = append(node.children[:i+1], node.children[i+2:]...)
+}
+
+// ascend calls visit for every item in the subtree for which aboveMin returns true, in ascending
+// order. Returns false if visit returned false, ending iteration early.
+func (node *btreeNode) ascend(aboveMin func(reflect.Value) bool, visit func(btreeItem) bool) bool {
+	i := sort.Search(len(node.items), func(i int) bool {
+		return aboveMin(node.items[i].key)
+	})
+
+	for ; i <= len(node.items); i++ {
+		if len(node.children) > 0 && !node.children[i].ascend(aboveMin, visit) {
+			return false
+		}
+		if i < len(node.items) && !visit(node.items[i]) {
+			return false
+		}
+	}
+	return true
+}
+
+// descend calls visit for every item in the subtree for which belowMax returns true, in descending
+// order. Returns false if visit returned false, ending iteration early.
+func (node *btreeNode) descend(belowMax func(reflect.Value) bool, visit func(btreeItem) bool) bool {
+	i := sort.Search(len(node.items), func(i int) bool {
+		return !belowMax(node.items[i].key)
+	})
+
+	for ; i >= 0; i-- {
+		if len(node.children) > 0 && !node.children[i].descend(belowMax, visit) {
+			return false
+		}
+		if i > 0 && !visit(node.items[i-1]) {
+			return false
+		}
+	}
+	return true
+}
+
+func insertBtreeItem(items []btreeItem, i int, item btreeItem) []btreeItem {
+	items = append(items, btreeItem{})
+	copy(items[i+1:], items[i:])
+	items[i] = item
+	return items
+}
+
+func removeBtreeItem(items []btreeItem, i int) []btreeItem {
+	copy(items[i:], items[i+1:])
+	items[len(items)-1] = btreeItem{}
+	return items[:len(items)-1]
+}
+
+func insertBtreeNode(nodes []*btreeNode, i int, node *btreeNode) []*btreeNode {
+	nodes = append(nodes, nil)
+	copy(nodes[i+1:], nodes[i:])
+	nodes[i] = node
+	return nodes
+}
+
+// keyRange is a range of values of an ordered column. An invalid min or max value leaves
+// the range unbounded in that direction.
+type keyRange struct {
+	min, max                   reflect.Value
+	minInclusive, maxInclusive bool
+}
+
+// pointRange returns a keyRange which holds only the given value.
+func pointRange(value interface{}) keyRange {
+	v := reflect.ValueOf(value)
+	return keyRange{min: v, max: v, minInclusive: true, maxInclusive: true}
+}
+
+func (r keyRange) aboveMin(key reflect.Value) bool {
+	if !r.min.IsValid() {
+		return true
+	}
+	result := compareIndexKeys(key, r.min)
+	return result > 0 || (r.minInclusive && result == 0)
+}
+
+func (r keyRange) belowMax(key reflect.Value) bool {
+	if !r.max.IsValid() {
+		return true
+	}
+	result := compareIndexKeys(key, r.max)
+	return result < 0 || (r.maxInclusive && result == 0)
+}
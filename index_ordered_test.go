@@ -0,0 +1,236 @@
+package simpledb
+
+import (
+	"math/rand"
+	"os"
+	"reflect"
+	"sort"
+	"testing"
+)
+
+func TestOrderedIndexTree(t *testing.T) {
+	rng := rand.New(rand.NewSource(1))
+	index := new(orderedIndex)
+	values := make(map[uint64]int)
+
+	expectedIDs := func(r keyRange, reverse bool) []uint64 {
+		ids := make([]uint64, 0)
+		for id, value := range values {
+			if r.aboveMin(reflect.ValueOf(value)) && r.belowMax(reflect.ValueOf(value)) {
+				ids = append(ids, id)
+			}
+		}
+		sort.Slice(ids, func(i, j int) bool {
+			a, b := values[ids[i]], values[ids[j]]
+			if a != b {
+				return (a < b) != reverse
+			}
+			return (ids[i] < ids[j]) != reverse
+		})
+		return ids
+	}
+
+	check := func() {
+		if index.length != len(values) {
+			t.Fatalf("expected ordered index to hold %d items, got %d", len(values), index.length)
+		}
+
+		ranges := []keyRange{
+			{},
+			pointRange(rng.Intn(100)),
+			{min: reflect.ValueOf(rng.Intn(100)), minInclusive: true},
+			{max: reflect.ValueOf(rng.Intn(100))},
+			{min: reflect.ValueOf(rng.Intn(50)), max: reflect.ValueOf(50 + rng.Intn(50)), maxInclusive: true},
+		}
+		for _, r := range ranges {
+			for _, reverse := range []bool{false, true} {
+				ids := make([]uint64, 0)
+				index.scan(r, reverse, func(id uint64) bool {
+					ids = append(ids, id)
+					return true
+				})
+				if expected := expectedIDs(r, reverse); !reflect.DeepEqual(ids, expected) {
+					t.Fatalf("scan of range %+v (reverse %v) does not match\nWanted %v\nGot    %v", r, reverse, expected, ids)
+				}
+			}
+		}
+	}
+
+	for i := 0; i < 5000; i++ {
+		id := uint64(rng.Intn(2000))
+		if value, ok := values[id]; ok && rng.Intn(3) > 0 {
+			index.remove(id, value)
+			delete(values, id)
+		} else if !ok {
+			value := rng.Intn(100)
+			index.add(id, value)
+			values[id] = value
+		}
+
+		if i%500 == 0 {
+			check()
+		}
+	}
+	check()
+
+	for id, value := range values {
+		index.remove(id, value)
+		delete(values, id)
+	}
+	check()
+	if index.root != nil {
+		t.Fatalf("expected empty ordered index to have no root node")
+	}
+
+	stopped := 0
+	index.add(1, 1)
+	index.add(2, 2)
+	index.scan(keyRange{}, false, func(id uint64) bool {
+		stopped += 1
+		return false
+	})
+	if stopped != 1 {
+		t.Fatalf("expected scan to stop when visit returns false")
+	}
+}
+
+func TestOrderedIndex(t *testing.T) {
+	type Car struct {
+		Year  uint16  `simpledb:"indexed,ordered"`
+		Make  string  `simpledb:"indexed,ordered"`
+		Price float64 `simpledb:"indexed,ordered"`
+		Notes string
+	}
+
+	tempFile, err := os.CreateTemp(os.TempDir(), "simpledb-")
+	if err != nil {
+		t.Fatalf("Failed to create temp file: %s", err)
+	}
+
+	t.Cleanup(func() {
+		tempFile.Close()
+		os.Remove(tempFile.Name())
+	})
+
+	db, err := NewDB(tempFile, Car{})
+	if err != nil {
+		t.Fatalf("failed to create DB: %s", err)
+	}
+
+	ids := make(map[uint16]uint64)
+	for year := uint16(1990); year < 2020; year++ {
+		carMake := []string{"Ford", "Mazda", "Toyota"}[year%3]
+		id, err := db.Insert(Car{Year: year, Make: carMake, Price: float64(year - 1980)})
+		if err != nil {
+			t.Fatalf("Failed to insert car: %s", err)
+		}
+		ids[year] = id
+	}
+
+	if err := db.Drop(ids[2000]); err != nil {
+		t.Fatalf("Failed to drop car: %s", err)
+	}
+	if err := db.Update(ids[2001], Car{Year: 1901, Make: "Ford", Price: 1}); err != nil {
+		t.Fatalf("Failed to update car: %s", err)
+	}
+
+	years := func(rows []*Row) []uint16 {
+		result := make([]uint16, len(rows))
+		for i, row := range rows {
+			result[i] = row.Value.(*Car).Year
+		}
+		return result
+	}
+
+	check := func(db *DB) {
+		type Fixture struct {
+			query    Query
+			column   string
+			reverse  bool
+			expected []uint16
+		}
+
+		fixtures := []Fixture{
+			{Where{"Year": Between(1998, 2003)}, "Year", false, []uint16{1998, 1999, 2002, 2003}},
+			{Where{"Year": GreaterThan(2015)}, "Year", true, []uint16{2019, 2018, 2017, 2016}},
+			{Where{"Year": LessThan(1992)}, "Year", false, []uint16{1901, 1990, 1991}},
+			{Where{"Year": uint16(2005)}, "Year", false, []uint16{2005}},
+			{Where{"Year": 2005}, "Year", false, []uint16{}},
+			{Where{"Year": In(1995, 2000, 2010, 1995)}, "Year", false, []uint16{1995, 2010}},
+			{Where{"Year": "2005"}, "Year", false, []uint16{}},
+			{Where{"Make": "Mazda", "Year": LessOrEqual(1998)}, "Year", false, []uint16{1990, 1993, 1996}},
+			{Where{"Price": GreaterOrEqual(37.5)}, "Price", false, []uint16{2018, 2019}},
+			{Where{"Price": LessThan(2)}, "Price", false, []uint16{1901}},
+			{
+				Or(Where{"Year": LessThan(1991)}, Where{"Price": GreaterThan(38)}),
+				"Year", false, []uint16{1901, 1990, 2019},
+			},
+			{
+				And(Where{"Make": "Toyota"}, Not(Where{"Year": LessThan(2010)})),
+				"Price", true, []uint16{2018, 2015, 2012},
+			},
+			{Where{"Notes": ""}, "Make", false, nil},
+		}
+
+		for i, fixture := range fixtures {
+			rows, err := db.FilterSorted(fixture.query, fixture.column, fixture.reverse)
+			if err != nil {
+				t.Fatalf("Failed to filter cars: %s", err)
+			}
+
+			actual := years(rows)
+			if fixture.expected == nil {
+				// sorted by Make, then by ID
+				if len(rows) != 29 {
+					t.Fatalf("expected query %d to return 29 cars, got %d", i, len(rows))
+				}
+				for j := 1; j < len(rows); j++ {
+					prev, next := rows[j-1].Value.(*Car), rows[j].Value.(*Car)
+					if prev.Make > next.Make || (prev.Make == next.Make && rows[j-1].ID > rows[j].ID) {
+						t.Fatalf("expected query %d to return cars sorted by make", i)
+					}
+				}
+			} else if !reflect.DeepEqual(actual, fixture.expected) {
+				t.Errorf("query %d returned cars from %v, expected %v", i, actual, fixture.expected)
+			}
+
+			rows, err = db.Select(fixture.query)
+			if err != nil {
+				t.Fatalf("Failed to filter cars: %s", err)
+			}
+			if fixture.expected != nil && len(rows) != len(fixture.expected) {
+				t.Errorf("unsorted query %d returned %d cars, expected %d", i, len(rows), len(fixture.expected))
+			}
+		}
+
+		if ids, ok := db.candidates(Where{"Year": Between(1998, 2003), "Notes": ""}); !ok || len(ids) != 4 {
+			t.Fatalf("expected range query to be narrowed down to 4 rows using the ordered index, got %d", len(ids))
+		}
+
+		if _, err := db.FilterSorted(Where{}, "Notes", false); err == nil {
+			t.Fatalf("expected error when sorting by a column without an ordered index")
+		}
+	}
+
+	check(db)
+
+	db, err = NewDB(tempFile, Car{})
+	if err != nil {
+		t.Fatalf("Failed to reopen DB: %s", err)
+	}
+	check(db)
+
+	type Unindexed struct {
+		Year uint16 `simpledb:"ordered"`
+	}
+	if _, err := NewDB(tempFile, Unindexed{}); err == nil {
+		t.Fatalf("expected error when opening DB with an ordered field which is not indexed")
+	}
+
+	type Unordered struct {
+		Value complex64 `simpledb:"indexed,ordered"`
+	}
+	if _, err := NewDB(tempFile, Unordered{}); err == nil {
+		t.Fatalf("expected error when opening DB with an ordered field of a type which cannot be ordered")
+	}
+}
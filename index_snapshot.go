@@ -0,0 +1,221 @@
+package simpledb
+
+import (
+	"bytes"
+	"encoding/binary"
+	"hash/crc32"
+	"io"
+	"reflect"
+)
+
+// indexSnapshot is a snapshot of the in-memory indices of every table in a DB source, which can be
+// loaded upon opening the DB instead of scanning the whole source. It is encoded with encodeStructToBinary,
+// followed by a big-endian CRC-32 checksum of the encoded snapshot. Each field holds one entry per table,
+// indexed by table number.
+type indexSnapshot struct {
+	// Cursors holds the cursor of each row in the table, in the same order as IDs.
+	Cursors [][]int64
+
+	// FieldNames holds the names of the table's indexed fields.
+	FieldNames [][]string
+
+	// FieldValues holds the encoded values of each indexed field, for every row in the same order as IDs.
+	FieldValues [][][]byte
+
+	IDs [][]uint64
+
+	// SourceSize is the size of the DB source when the snapshot was taken.
+	SourceSize int64
+
+	TableNames []string
+}
+
+// writeIndexSnapshot writes a snapshot of the indices of every table to the DB's index snapshot
+// Source. It assumes the caller is handling the mutex.
+func (file *dbFile) writeIndexSnapshot() error {
+	sourceSize, err := file.source.Seek(0, io.SeekEnd)
+	if err != nil {
+		return err
+	}
+
+	snapshot := indexSnapshot{SourceSize: sourceSize}
+
+	// tables which are not recorded in the file header have no rows
+	for _, table := range file.tables[:file.headerTables] {
+		ids := make([]uint64, 0, len(table.index))
+		cursors := make([]int64, 0, len(table.index))
+		for id, cursor := range table.index {
+			ids = append(ids, id)
+			cursors = append(cursors, cursor)
+		}
+
+		fieldNames := make([]string, 0, len(table.customIndices))
+		fieldValues := make([][]byte, 0, len(table.customIndices))
+		for fieldName, customIndex := range table.customIndices {
+			buf := new(bytes.Buffer)
+			for _, id := range ids {
+				if _, err := encodeToBinary(buf, reflect.ValueOf(customIndex[id])); err != nil {
+					return err
+				}
+			}
+			fieldNames = append(fieldNames, fieldName)
+			fieldValues = append(fieldValues, buf.Bytes())
+		}
+
+		snapshot.TableNames = append(snapshot.TableNames, table.name)
+		snapshot.IDs = append(snapshot.IDs, ids)
+		snapshot.Cursors = append(snapshot.Cursors, cursors)
+		snapshot.FieldNames = append(snapshot.FieldNames, fieldNames)
+		snapshot.FieldValues = append(snapshot.FieldValues, fieldValues)
+	}
+
+	buf := new(bytes.Buffer)
+	if _, err := encodeStructToBinary(buf, reflect.ValueOf(snapshot)); err != nil {
+		return err
+	}
+	checksum := crc32.ChecksumIEEE(buf.Bytes())
+	binary.Write(buf, binary.BigEndian, checksum)
+
+	if err := file.indexSnapshot.Truncate(0); err != nil {
+		return err
+	}
+	if err := applyWrites(file.indexSnapshot, []sourceWrite{{offset: 0, data: buf.Bytes()}}); err != nil {
+		return err
+	}
+	if err := syncSource(file.indexSnapshot); err != nil {
+		return err
+	}
+
+	file.indexSnapshotValid = true
+	return nil
+}
+
+// invalidateIndexSnapshot clears the DB's index snapshot Source before the DB source is modified,
+// so that a stale snapshot is never loaded if the DB is not closed cleanly.
+func (file *dbFile) invalidateIndexSnapshot() error {
+	if file.indexSnapshot == nil || !file.indexSnapshotValid {
+		return nil
+	}
+
+	if err := file.indexSnapshot.Truncate(0); err != nil {
+		return err
+	}
+	if err := syncSource(file.indexSnapshot); err != nil {
+		return err
+	}
+
+	file.indexSnapshotValid = false
+	return nil
+}
+
+// loadIndexSnapshot populates the indices of every table from the DB's index snapshot Source. It returns
+// false if the snapshot is missing, corrupted, or stale, in which case the indices must be populated by
+// scanning the DB source. The snapshot is stale if the size of the DB source has changed, or if the
+// row header at any of its cursors does not hold the expected ID. It assumes the tables have already been reconciled with the file header.
+func (file *dbFile) loadIndexSnapshot() (bool, error) {
+	if _, err := file.indexSnapshot.Seek(0, io.SeekStart); err != nil {
+		return false, err
+	}
+
+	data, err := io.ReadAll(file.indexSnapshot)
+	if err != nil {
+		return false, err
+	} else if len(data) < 4 {
+		return false, nil
+	}
+
+	encoded, checksum := data[:len(data)-4], data[len(data)-4:]
+	if crc32.ChecksumIEEE(encoded) != binary.BigEndian.Uint32(checksum) {
+		return false, nil
+	}
+
+	var snapshot indexSnapshot
+	if _, err := decodeStructFromBinary(bytes.NewReader(encoded), reflect.ValueOf(&snapshot)); err != nil {
+		return false, nil
+	}
+
+	sourceSize, err := file.source.Seek(0, io.SeekEnd)
+	if err != nil {
+		return false, err
+	}
+
+	if snapshot.SourceSize != sourceSize || len(snapshot.TableNames) != file.headerTables {
+		return false, nil
+	}
+
+	for i, table := range file.tables[:file.headerTables] {
+		if snapshot.TableNames[i] != table.name || len(snapshot.Cursors[i]) != len(snapshot.IDs[i]) {
+			return false, nil
+		}
+	}
+
+	// Dropping a row does not change the size of the DB source, so the row header at each cursor is
+	// checked to make sure none of the rows have been dropped while the snapshot was not in use.
+	for i, table := range file.tables[:file.headerTables] {
+		for j, id := range snapshot.IDs[i] {
+			rowHeader, err := file.readRowHeaderAt(snapshot.Cursors[i][j])
+			if err != nil || rowHeader.id != id || rowHeader.table != table.number {
+				return false, nil
+			}
+		}
+	}
+
+	for i, table := range file.tables[:file.headerTables] {
+		err := table.loadIndexSnapshot(snapshot.IDs[i], snapshot.Cursors[i], snapshot.FieldNames[i], snapshot.FieldValues[i])
+		if err != nil {
+			return false, err
+		}
+	}
+
+	file.indexSnapshotValid = true
+	return true, nil
+}
+
+// loadIndexSnapshot populates the table's indices from a snapshot of its rows' IDs and cursors, and the
+// encoded values of its indexed fields. If the snapshot does not include every indexed field of the table,
+// the rows are decoded from the DB source instead.
+func (db *DB) loadIndexSnapshot(ids []uint64, cursors []int64, fieldNames []string, fieldValues [][]byte) error {
+	db.index = make(map[uint64]int64, len(ids))
+
+	if len(db.customIndices) == 0 {
+		for i, id := range ids {
+			db.index[id] = cursors[i]
+		}
+		return nil
+	}
+
+	readers := make(map[string]*bytes.Reader)
+	for i, fieldName := range fieldNames {
+		readers[fieldName] = bytes.NewReader(fieldValues[i])
+	}
+
+	for fieldName := range db.customIndices {
+		if _, ok := readers[fieldName]; !ok {
+			index := make(map[uint64]int64, len(ids))
+			for i, id := range ids {
+				index[id] = cursors[i]
+			}
+			return db.indexRows(index)
+		}
+	}
+
+	for i, id := range ids {
+		// only the indexed fields of the value are populated
+		valuePtr := reflect.New(db.schema.dataType)
+		for fieldName := range db.customIndices {
+			fieldValue := reflect.Indirect(valuePtr).FieldByName(fieldName)
+			if _, err := decodeFromBinary(readers[fieldName], fieldValue.Addr()); err != nil {
+				return err
+			}
+		}
+
+		err := db.addToIndex(id, cursors[i], func() (interface{}, error) {
+			return valuePtr.Interface(), nil
+		})
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
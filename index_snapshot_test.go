@@ -0,0 +1,205 @@
+package simpledb
+
+import (
+	"errors"
+	"os"
+	"testing"
+)
+
+func TestIndexSnapshot(t *testing.T) {
+	type Book struct {
+		Title  string `simpledb:"indexed"`
+		Author string
+	}
+
+	tempFile, err := os.CreateTemp(os.TempDir(), "simpledb-")
+	if err != nil {
+		t.Fatalf("Failed to create temp file: %s", err)
+	}
+	snapshotFile, err := os.CreateTemp(os.TempDir(), "simpledb-index-")
+	if err != nil {
+		t.Fatalf("Failed to create snapshot file: %s", err)
+	}
+
+	t.Cleanup(func() {
+		os.Remove(tempFile.Name())
+		os.Remove(snapshotFile.Name())
+	})
+
+	open := func() *DB {
+		source, err := os.OpenFile(tempFile.Name(), os.O_RDWR, 0)
+		if err != nil {
+			t.Fatalf("Failed to open DB file: %s", err)
+		}
+		snapshot, err := os.OpenFile(snapshotFile.Name(), os.O_RDWR, 0)
+		if err != nil {
+			t.Fatalf("Failed to open snapshot file: %s", err)
+		}
+
+		// The DB is reopened without being closed to simulate crashes, so the source is not locked.
+		db, err := NewDB(source, Book{}, WithIndexSnapshot(snapshot), WithLock(LockNone))
+		if err != nil {
+			t.Fatalf("failed to open DB: %s", err)
+		}
+		t.Cleanup(func() { db.Close() })
+		return db
+	}
+
+	snapshotSize := func() int64 {
+		info, err := os.Stat(snapshotFile.Name())
+		if err != nil {
+			t.Fatalf("Failed to stat snapshot file: %s", err)
+		}
+		return info.Size()
+	}
+
+	db := open()
+	if db.indexSnapshotValid {
+		t.Fatalf("expected empty snapshot to be invalid")
+	}
+
+	duneID, err := db.Insert(Book{Title: "Dune", Author: "Herbert"})
+	if err != nil {
+		t.Fatalf("Failed to insert book: %s", err)
+	}
+	for i := 0; i < 10; i++ {
+		if _, err := db.Insert(Book{Title: "Emma", Author: "Austen"}); err != nil {
+			t.Fatalf("Failed to insert book: %s", err)
+		}
+	}
+
+	if err := db.Close(); err != nil {
+		t.Fatalf("Failed to close DB: %s", err)
+	}
+	if snapshotSize() == 0 {
+		t.Fatalf("expected index snapshot to be written on close")
+	}
+
+	check := func(db *DB, rowCount int) {
+		if db.RowCount() != rowCount {
+			t.Fatalf("expected %d rows, got %d", rowCount, db.RowCount())
+		}
+
+		var book Book
+		if err := db.Find(duneID, &book); err != nil || book.Author != "Herbert" {
+			t.Fatalf("Failed to find book: %v", err)
+		}
+
+		rows, err := db.Filter(FilterQuery{"Title": "Emma"})
+		if err != nil {
+			t.Fatalf("Failed to filter books: %s", err)
+		} else if len(rows) != 10 {
+			t.Fatalf("expected to filter 10 books, got %d", len(rows))
+		}
+	}
+
+	db = open()
+	if !db.indexSnapshotValid {
+		t.Fatalf("expected index snapshot to be loaded")
+	}
+	check(db, 11)
+
+	// modifying the DB clears the snapshot, so it is not loaded if the DB is not closed cleanly
+	if _, err := db.Insert(Book{Title: "Ulysses", Author: "Joyce"}); err != nil {
+		t.Fatalf("Failed to insert book: %s", err)
+	}
+	if snapshotSize() != 0 {
+		t.Fatalf("expected index snapshot to be cleared upon modifying the DB")
+	}
+
+	db = open()
+	if db.indexSnapshotValid {
+		t.Fatalf("expected cleared index snapshot not to be loaded")
+	}
+	check(db, 12)
+
+	if err := db.Defrag(); err != nil {
+		t.Fatalf("Failed to defrag DB: %s", err)
+	}
+	if snapshotSize() == 0 {
+		t.Fatalf("expected index snapshot to be written on defrag")
+	}
+
+	// a corrupted snapshot is not loaded
+	snapshot, _ := os.OpenFile(snapshotFile.Name(), os.O_RDWR, 0)
+	snapshot.WriteAt([]byte{0xff}, 3)
+	snapshot.Close()
+
+	db = open()
+	if db.indexSnapshotValid {
+		t.Fatalf("expected corrupted index snapshot not to be loaded")
+	}
+	check(db, 12)
+
+	// dropping a row without the snapshot does not change the size of the DB source
+	if err := db.Close(); err != nil {
+		t.Fatalf("Failed to close DB: %s", err)
+	}
+	source, err := os.OpenFile(tempFile.Name(), os.O_RDWR, 0)
+	if err != nil {
+		t.Fatalf("Failed to open DB file: %s", err)
+	}
+	db, err = NewDB(source, Book{})
+	if err != nil {
+		t.Fatalf("failed to open DB without snapshot: %s", err)
+	}
+	ulysses, err := db.Filter(FilterQuery{"Title": "Ulysses"})
+	if err != nil || len(ulysses) != 1 {
+		t.Fatalf("Failed to filter books: %v", err)
+	}
+	if err := db.Drop(ulysses[0].ID); err != nil {
+		t.Fatalf("Failed to drop book: %s", err)
+	}
+	if err := db.Close(); err != nil {
+		t.Fatalf("Failed to close DB: %s", err)
+	}
+
+	db = open()
+	if db.indexSnapshotValid {
+		t.Fatalf("expected index snapshot with a dropped row not to be loaded")
+	}
+	if db.Has(ulysses[0].ID) {
+		t.Fatalf("expected dropped book not to be found")
+	}
+	check(db, 11)
+}
+
+func TestIndexSnapshotCloseError(t *testing.T) {
+	type Book struct {
+		Title string
+	}
+
+	tempFile, err := os.CreateTemp(os.TempDir(), "simpledb-")
+	if err != nil {
+		t.Fatalf("Failed to create temp file: %s", err)
+	}
+	snapshotFile, err := os.CreateTemp(os.TempDir(), "simpledb-index-")
+	if err != nil {
+		t.Fatalf("Failed to create snapshot file: %s", err)
+	}
+
+	t.Cleanup(func() {
+		tempFile.Close()
+		snapshotFile.Close()
+		os.Remove(tempFile.Name())
+		os.Remove(snapshotFile.Name())
+	})
+
+	// the snapshot cannot be written, as it is opened read-only
+	snapshot, err := os.Open(snapshotFile.Name())
+	if err != nil {
+		t.Fatalf("Failed to open snapshot file: %s", err)
+	}
+
+	db, err := NewDB(tempFile, Book{}, WithIndexSnapshot(snapshot))
+	if err != nil {
+		t.Fatalf("failed to open DB: %s", err)
+	}
+	if err := db.Close(); err == nil {
+		t.Fatalf("expected error writing index snapshot on close")
+	}
+
+	if _, err := tempFile.Stat(); !errors.Is(err, os.ErrClosed) {
+		t.Fatalf("expected DB source to be closed even though writing the snapshot failed, got: %v", err)
+	}
+}
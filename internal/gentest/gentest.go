@@ -0,0 +1,143 @@
+// Package gentest holds struct types whose simpledb encoders are generated by simpledb-gen, so
+// that tests can check the generated encoders against simpledb's reflection-based encoding.
+package gentest
+
+//go:generate go run ../../cmd/simpledb-gen -type Everything,Event -output gentest_simpledb.go
+
+import (
+	"fmt"
+	"time"
+)
+
+// UUID implements encoding.BinaryMarshaler with a value receiver, but is encoded as an array.
+type UUID [4]byte
+
+func (id UUID) MarshalBinary() ([]byte, error) {
+	return id[:], nil
+}
+
+func (id *UUID) UnmarshalBinary(data []byte) error {
+	if len(data) != len(id) {
+		return fmt.Errorf("invalid UUID length %d", len(data))
+	}
+	copy(id[:], data)
+	return nil
+}
+
+// Amount implements encoding.BinaryMarshaler with a pointer receiver, and has no exported fields.
+type Amount struct {
+	cents int64
+}
+
+// NewAmount returns an Amount holding the given number of cents.
+func NewAmount(cents int64) Amount {
+	return Amount{cents}
+}
+
+func (amount *Amount) MarshalBinary() ([]byte, error) {
+	return []byte(fmt.Sprint(amount.cents)), nil
+}
+
+func (amount *Amount) UnmarshalBinary(data []byte) error {
+	_, err := fmt.Sscan(string(data), &amount.cents)
+	return err
+}
+
+type (
+	Make  string
+	Year  uint16
+	Flag  bool
+	Level int8
+	Ratio float32
+	Point complex64
+	Bytes []byte
+)
+
+type Address struct {
+	Zip    uint16
+	City   string
+	hidden bool
+}
+
+type Base struct {
+	Created int64
+}
+
+type common struct {
+	Shared string
+	Base
+}
+
+// Everything has a column of each kind of type supported by simpledb.
+type Everything struct {
+	Bool       bool
+	Int8       int8
+	Int16      int16
+	Int32      int32
+	Int64      int64
+	Uint8      uint8
+	Uint16     uint16
+	Uint32     uint32
+	Uint64     uint64
+	Float32    float32
+	Float64    float64
+	Complex64  complex64
+	Complex128 complex128
+	String     string
+
+	Make  Make
+	Year  Year
+	Flag  Flag
+	Level Level
+	Ratio Ratio
+	Point Point
+	Bytes Bytes
+
+	ByteArray   [4]byte
+	Int16Array  [2]int16
+	StringArray [2]string
+	ByteSlice   []byte
+	Uint32Slice []uint32
+	Strings     [][]string
+	Years       []Year
+
+	Address   Address
+	Addresses []Address
+	Anonymous struct {
+		B []int8
+		A *string
+	}
+
+	Pointer        *uint16
+	AddressPointer *Address
+	PointerSlice   []*Address
+
+	Labels      map[string]uint8
+	Ports       map[int8][]string
+	Weights     map[float64]bool
+	Coordinates map[[2]float32]string
+	Complex     map[complex64]bool
+	ByAddress   map[Address]int32
+	ByUUID      map[UUID]*Amount
+	Nested      map[string]map[Year]string
+
+	Time     time.Time
+	TimePtr  *time.Time
+	Duration time.Duration
+	Times    map[time.Time]uint8
+
+	UUID    UUID
+	Amount  Amount
+	Amounts []Amount
+
+	common
+	private int32
+}
+
+// Event is a small row type, as might be stored in a real table.
+type Event struct {
+	ID    UUID `simpledb:"unique"`
+	Name  string
+	Start time.Time `simpledb:"indexed,ordered"`
+	Tags  []string
+}
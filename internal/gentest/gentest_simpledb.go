@@ -0,0 +1,1300 @@
+// Code generated by "simpledb-gen -type Everything,Event -output gentest_simpledb.go"; DO NOT EDIT.
+
+package gentest
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"math"
+	"sort"
+	"time"
+)
+
+// EncodeSimpleDB appends the simpledb encoding of v to buf, without using reflection.
+func (v Everything) EncodeSimpleDB(buf []byte) ([]byte, error) {
+	var scratch [binary.MaxVarintLen64]byte
+	buf = append(buf, scratch[:binary.PutUvarint(scratch[:], uint64(len(v.Address.City)))]...)
+	buf = append(buf, v.Address.City...)
+	binary.BigEndian.PutUint16(scratch[:], uint16(v.Address.Zip))
+	buf = append(buf, scratch[:2]...)
+	if v.AddressPointer == nil {
+		buf = append(buf, 0)
+	} else {
+		buf = append(buf, 1)
+		buf = append(buf, scratch[:binary.PutUvarint(scratch[:], uint64(len((*v.AddressPointer).City)))]...)
+		buf = append(buf, (*v.AddressPointer).City...)
+		binary.BigEndian.PutUint16(scratch[:], uint16((*v.AddressPointer).Zip))
+		buf = append(buf, scratch[:2]...)
+	}
+	buf = append(buf, scratch[:binary.PutUvarint(scratch[:], uint64(len(v.Addresses)))]...)
+	for i1 := range v.Addresses {
+		buf = append(buf, scratch[:binary.PutUvarint(scratch[:], uint64(len(v.Addresses[i1].City)))]...)
+		buf = append(buf, v.Addresses[i1].City...)
+		binary.BigEndian.PutUint16(scratch[:], uint16(v.Addresses[i1].Zip))
+		buf = append(buf, scratch[:2]...)
+	}
+	data2, err := v.Amount.MarshalBinary()
+	if err != nil {
+		return nil, err
+	}
+	buf = append(buf, scratch[:binary.PutUvarint(scratch[:], uint64(len(data2)))]...)
+	buf = append(buf, data2...)
+	buf = append(buf, scratch[:binary.PutUvarint(scratch[:], uint64(len(v.Amounts)))]...)
+	for i3 := range v.Amounts {
+		data4, err := v.Amounts[i3].MarshalBinary()
+		if err != nil {
+			return nil, err
+		}
+		buf = append(buf, scratch[:binary.PutUvarint(scratch[:], uint64(len(data4)))]...)
+		buf = append(buf, data4...)
+	}
+	if v.Anonymous.A == nil {
+		buf = append(buf, 0)
+	} else {
+		buf = append(buf, 1)
+		buf = append(buf, scratch[:binary.PutUvarint(scratch[:], uint64(len((*v.Anonymous.A))))]...)
+		buf = append(buf, (*v.Anonymous.A)...)
+	}
+	buf = append(buf, scratch[:binary.PutUvarint(scratch[:], uint64(len(v.Anonymous.B)))]...)
+	for i5 := range v.Anonymous.B {
+		buf = append(buf, byte(v.Anonymous.B[i5]))
+	}
+	binary.BigEndian.PutUint64(scratch[:], uint64(v.Base.Created))
+	buf = append(buf, scratch[:8]...)
+	if v.Bool {
+		buf = append(buf, 1)
+	} else {
+		buf = append(buf, 0)
+	}
+	entries7 := make([][]byte, 0, len(v.ByAddress))
+	for key9, value10 := range v.ByAddress {
+		var entry11 []byte
+		entry11 = append(entry11, scratch[:binary.PutUvarint(scratch[:], uint64(len(key9.City)))]...)
+		entry11 = append(entry11, key9.City...)
+		binary.BigEndian.PutUint16(scratch[:], uint16(key9.Zip))
+		entry11 = append(entry11, scratch[:2]...)
+		binary.BigEndian.PutUint32(scratch[:], uint32(value10))
+		entry11 = append(entry11, scratch[:4]...)
+		entries7 = append(entries7, entry11)
+	}
+	order8 := make([]int, len(entries7))
+	for i := range order8 {
+		order8[i] = i
+	}
+	sort.Slice(order8, func(i, j int) bool {
+		a, b := order8[i], order8[j]
+		return bytes.Compare(entries7[a], entries7[b]) < 0
+	})
+	buf = append(buf, scratch[:binary.PutUvarint(scratch[:], uint64(len(entries7)))]...)
+	for _, i := range order8 {
+		buf = append(buf, entries7[i]...)
+	}
+	keys12 := make([]UUID, 0, len(v.ByUUID))
+	entries13 := make([][]byte, 0, len(v.ByUUID))
+	for key15, value16 := range v.ByUUID {
+		var entry17 []byte
+		entry17 = append(entry17, key15[:]...)
+		if value16 == nil {
+			entry17 = append(entry17, 0)
+		} else {
+			entry17 = append(entry17, 1)
+			data18, err := (*value16).MarshalBinary()
+			if err != nil {
+				return nil, err
+			}
+			entry17 = append(entry17, scratch[:binary.PutUvarint(scratch[:], uint64(len(data18)))]...)
+			entry17 = append(entry17, data18...)
+		}
+		keys12 = append(keys12, key15)
+		entries13 = append(entries13, entry17)
+	}
+	order14 := make([]int, len(entries13))
+	for i := range order14 {
+		order14[i] = i
+	}
+	sort.Slice(order14, func(i, j int) bool {
+		a, b := order14[i], order14[j]
+		for i19 := range keys12[a] {
+			if keys12[a][i19] != keys12[b][i19] {
+				return keys12[a][i19] < keys12[b][i19]
+			}
+		}
+		return bytes.Compare(entries13[a], entries13[b]) < 0
+	})
+	buf = append(buf, scratch[:binary.PutUvarint(scratch[:], uint64(len(entries13)))]...)
+	for _, i := range order14 {
+		buf = append(buf, entries13[i]...)
+	}
+	buf = append(buf, v.ByteArray[:]...)
+	buf = append(buf, scratch[:binary.PutUvarint(scratch[:], uint64(len(v.ByteSlice)))]...)
+	buf = append(buf, v.ByteSlice...)
+	buf = append(buf, scratch[:binary.PutUvarint(scratch[:], uint64(len(v.Bytes)))]...)
+	buf = append(buf, v.Bytes...)
+	entries21 := make([][]byte, 0, len(v.Complex))
+	for key23, value24 := range v.Complex {
+		var entry25 []byte
+		binary.BigEndian.PutUint32(scratch[:], uint32(math.Float32bits(real(key23))))
+		entry25 = append(entry25, scratch[:4]...)
+		binary.BigEndian.PutUint32(scratch[:], uint32(math.Float32bits(imag(key23))))
+		entry25 = append(entry25, scratch[:4]...)
+		if value24 {
+			entry25 = append(entry25, 1)
+		} else {
+			entry25 = append(entry25, 0)
+		}
+		entries21 = append(entries21, entry25)
+	}
+	order22 := make([]int, len(entries21))
+	for i := range order22 {
+		order22[i] = i
+	}
+	sort.Slice(order22, func(i, j int) bool {
+		a, b := order22[i], order22[j]
+		return bytes.Compare(entries21[a], entries21[b]) < 0
+	})
+	buf = append(buf, scratch[:binary.PutUvarint(scratch[:], uint64(len(entries21)))]...)
+	for _, i := range order22 {
+		buf = append(buf, entries21[i]...)
+	}
+	binary.BigEndian.PutUint64(scratch[:], uint64(math.Float64bits(real(v.Complex128))))
+	buf = append(buf, scratch[:8]...)
+	binary.BigEndian.PutUint64(scratch[:], uint64(math.Float64bits(imag(v.Complex128))))
+	buf = append(buf, scratch[:8]...)
+	binary.BigEndian.PutUint32(scratch[:], uint32(math.Float32bits(real(v.Complex64))))
+	buf = append(buf, scratch[:4]...)
+	binary.BigEndian.PutUint32(scratch[:], uint32(math.Float32bits(imag(v.Complex64))))
+	buf = append(buf, scratch[:4]...)
+	keys26 := make([][2]float32, 0, len(v.Coordinates))
+	entries27 := make([][]byte, 0, len(v.Coordinates))
+	for key29, value30 := range v.Coordinates {
+		var entry31 []byte
+		for i32 := range key29 {
+			binary.BigEndian.PutUint32(scratch[:], uint32(math.Float32bits(float32(key29[i32]))))
+			entry31 = append(entry31, scratch[:4]...)
+		}
+		entry31 = append(entry31, scratch[:binary.PutUvarint(scratch[:], uint64(len(value30)))]...)
+		entry31 = append(entry31, value30...)
+		keys26 = append(keys26, key29)
+		entries27 = append(entries27, entry31)
+	}
+	order28 := make([]int, len(entries27))
+	for i := range order28 {
+		order28[i] = i
+	}
+	sort.Slice(order28, func(i, j int) bool {
+		a, b := order28[i], order28[j]
+		for i33 := range keys26[a] {
+			if keys26[a][i33] != keys26[b][i33] && (keys26[a][i33] == keys26[a][i33] || keys26[b][i33] == keys26[b][i33]) {
+				return keys26[a][i33] != keys26[a][i33] || keys26[a][i33] < keys26[b][i33]
+			}
+		}
+		return bytes.Compare(entries27[a], entries27[b]) < 0
+	})
+	buf = append(buf, scratch[:binary.PutUvarint(scratch[:], uint64(len(entries27)))]...)
+	for _, i := range order28 {
+		buf = append(buf, entries27[i]...)
+	}
+	binary.BigEndian.PutUint64(scratch[:], uint64(v.Duration))
+	buf = append(buf, scratch[:8]...)
+	if v.Flag {
+		buf = append(buf, 1)
+	} else {
+		buf = append(buf, 0)
+	}
+	binary.BigEndian.PutUint32(scratch[:], uint32(math.Float32bits(float32(v.Float32))))
+	buf = append(buf, scratch[:4]...)
+	binary.BigEndian.PutUint64(scratch[:], uint64(math.Float64bits(float64(v.Float64))))
+	buf = append(buf, scratch[:8]...)
+	binary.BigEndian.PutUint16(scratch[:], uint16(v.Int16))
+	buf = append(buf, scratch[:2]...)
+	for i34 := range v.Int16Array {
+		binary.BigEndian.PutUint16(scratch[:], uint16(v.Int16Array[i34]))
+		buf = append(buf, scratch[:2]...)
+	}
+	binary.BigEndian.PutUint32(scratch[:], uint32(v.Int32))
+	buf = append(buf, scratch[:4]...)
+	binary.BigEndian.PutUint64(scratch[:], uint64(v.Int64))
+	buf = append(buf, scratch[:8]...)
+	buf = append(buf, byte(v.Int8))
+	keys35 := make([]string, 0, len(v.Labels))
+	entries36 := make([][]byte, 0, len(v.Labels))
+	for key38, value39 := range v.Labels {
+		var entry40 []byte
+		entry40 = append(entry40, scratch[:binary.PutUvarint(scratch[:], uint64(len(key38)))]...)
+		entry40 = append(entry40, key38...)
+		entry40 = append(entry40, byte(value39))
+		keys35 = append(keys35, key38)
+		entries36 = append(entries36, entry40)
+	}
+	order37 := make([]int, len(entries36))
+	for i := range order37 {
+		order37[i] = i
+	}
+	sort.Slice(order37, func(i, j int) bool {
+		a, b := order37[i], order37[j]
+		if keys35[a] != keys35[b] {
+			return keys35[a] < keys35[b]
+		}
+		return bytes.Compare(entries36[a], entries36[b]) < 0
+	})
+	buf = append(buf, scratch[:binary.PutUvarint(scratch[:], uint64(len(entries36)))]...)
+	for _, i := range order37 {
+		buf = append(buf, entries36[i]...)
+	}
+	buf = append(buf, byte(v.Level))
+	buf = append(buf, scratch[:binary.PutUvarint(scratch[:], uint64(len(v.Make)))]...)
+	buf = append(buf, string(v.Make)...)
+	keys41 := make([]string, 0, len(v.Nested))
+	entries42 := make([][]byte, 0, len(v.Nested))
+	for key44, value45 := range v.Nested {
+		var entry46 []byte
+		entry46 = append(entry46, scratch[:binary.PutUvarint(scratch[:], uint64(len(key44)))]...)
+		entry46 = append(entry46, key44...)
+		keys47 := make([]Year, 0, len(value45))
+		entries48 := make([][]byte, 0, len(value45))
+		for key50, value51 := range value45 {
+			var entry52 []byte
+			binary.BigEndian.PutUint16(scratch[:], uint16(key50))
+			entry52 = append(entry52, scratch[:2]...)
+			entry52 = append(entry52, scratch[:binary.PutUvarint(scratch[:], uint64(len(value51)))]...)
+			entry52 = append(entry52, value51...)
+			keys47 = append(keys47, key50)
+			entries48 = append(entries48, entry52)
+		}
+		order49 := make([]int, len(entries48))
+		for i := range order49 {
+			order49[i] = i
+		}
+		sort.Slice(order49, func(i, j int) bool {
+			a, b := order49[i], order49[j]
+			if keys47[a] != keys47[b] {
+				return keys47[a] < keys47[b]
+			}
+			return bytes.Compare(entries48[a], entries48[b]) < 0
+		})
+		entry46 = append(entry46, scratch[:binary.PutUvarint(scratch[:], uint64(len(entries48)))]...)
+		for _, i := range order49 {
+			entry46 = append(entry46, entries48[i]...)
+		}
+		keys41 = append(keys41, key44)
+		entries42 = append(entries42, entry46)
+	}
+	order43 := make([]int, len(entries42))
+	for i := range order43 {
+		order43[i] = i
+	}
+	sort.Slice(order43, func(i, j int) bool {
+		a, b := order43[i], order43[j]
+		if keys41[a] != keys41[b] {
+			return keys41[a] < keys41[b]
+		}
+		return bytes.Compare(entries42[a], entries42[b]) < 0
+	})
+	buf = append(buf, scratch[:binary.PutUvarint(scratch[:], uint64(len(entries42)))]...)
+	for _, i := range order43 {
+		buf = append(buf, entries42[i]...)
+	}
+	binary.BigEndian.PutUint32(scratch[:], uint32(math.Float32bits(real(v.Point))))
+	buf = append(buf, scratch[:4]...)
+	binary.BigEndian.PutUint32(scratch[:], uint32(math.Float32bits(imag(v.Point))))
+	buf = append(buf, scratch[:4]...)
+	if v.Pointer == nil {
+		buf = append(buf, 0)
+	} else {
+		buf = append(buf, 1)
+		binary.BigEndian.PutUint16(scratch[:], uint16((*v.Pointer)))
+		buf = append(buf, scratch[:2]...)
+	}
+	buf = append(buf, scratch[:binary.PutUvarint(scratch[:], uint64(len(v.PointerSlice)))]...)
+	for i53 := range v.PointerSlice {
+		if v.PointerSlice[i53] == nil {
+			buf = append(buf, 0)
+		} else {
+			buf = append(buf, 1)
+			buf = append(buf, scratch[:binary.PutUvarint(scratch[:], uint64(len((*v.PointerSlice[i53]).City)))]...)
+			buf = append(buf, (*v.PointerSlice[i53]).City...)
+			binary.BigEndian.PutUint16(scratch[:], uint16((*v.PointerSlice[i53]).Zip))
+			buf = append(buf, scratch[:2]...)
+		}
+	}
+	keys54 := make([]int8, 0, len(v.Ports))
+	entries55 := make([][]byte, 0, len(v.Ports))
+	for key57, value58 := range v.Ports {
+		var entry59 []byte
+		entry59 = append(entry59, byte(key57))
+		entry59 = append(entry59, scratch[:binary.PutUvarint(scratch[:], uint64(len(value58)))]...)
+		for i60 := range value58 {
+			entry59 = append(entry59, scratch[:binary.PutUvarint(scratch[:], uint64(len(value58[i60])))]...)
+			entry59 = append(entry59, value58[i60]...)
+		}
+		keys54 = append(keys54, key57)
+		entries55 = append(entries55, entry59)
+	}
+	order56 := make([]int, len(entries55))
+	for i := range order56 {
+		order56[i] = i
+	}
+	sort.Slice(order56, func(i, j int) bool {
+		a, b := order56[i], order56[j]
+		if keys54[a] != keys54[b] {
+			return keys54[a] < keys54[b]
+		}
+		return bytes.Compare(entries55[a], entries55[b]) < 0
+	})
+	buf = append(buf, scratch[:binary.PutUvarint(scratch[:], uint64(len(entries55)))]...)
+	for _, i := range order56 {
+		buf = append(buf, entries55[i]...)
+	}
+	binary.BigEndian.PutUint32(scratch[:], uint32(math.Float32bits(float32(v.Ratio))))
+	buf = append(buf, scratch[:4]...)
+	buf = append(buf, scratch[:binary.PutUvarint(scratch[:], uint64(len(v.Shared)))]...)
+	buf = append(buf, v.Shared...)
+	buf = append(buf, scratch[:binary.PutUvarint(scratch[:], uint64(len(v.String)))]...)
+	buf = append(buf, v.String...)
+	for i61 := range v.StringArray {
+		buf = append(buf, scratch[:binary.PutUvarint(scratch[:], uint64(len(v.StringArray[i61])))]...)
+		buf = append(buf, v.StringArray[i61]...)
+	}
+	buf = append(buf, scratch[:binary.PutUvarint(scratch[:], uint64(len(v.Strings)))]...)
+	for i62 := range v.Strings {
+		buf = append(buf, scratch[:binary.PutUvarint(scratch[:], uint64(len(v.Strings[i62])))]...)
+		for i63 := range v.Strings[i62] {
+			buf = append(buf, scratch[:binary.PutUvarint(scratch[:], uint64(len(v.Strings[i62][i63])))]...)
+			buf = append(buf, v.Strings[i62][i63]...)
+		}
+	}
+	binary.BigEndian.PutUint64(scratch[:], uint64(v.Time.Unix()))
+	buf = append(buf, scratch[:8]...)
+	binary.BigEndian.PutUint32(scratch[:], uint32(v.Time.Nanosecond()))
+	buf = append(buf, scratch[:4]...)
+	if v.TimePtr == nil {
+		buf = append(buf, 0)
+	} else {
+		buf = append(buf, 1)
+		binary.BigEndian.PutUint64(scratch[:], uint64((*v.TimePtr).Unix()))
+		buf = append(buf, scratch[:8]...)
+		binary.BigEndian.PutUint32(scratch[:], uint32((*v.TimePtr).Nanosecond()))
+		buf = append(buf, scratch[:4]...)
+	}
+	keys64 := make([]time.Time, 0, len(v.Times))
+	entries65 := make([][]byte, 0, len(v.Times))
+	for key67, value68 := range v.Times {
+		var entry69 []byte
+		binary.BigEndian.PutUint64(scratch[:], uint64(key67.Unix()))
+		entry69 = append(entry69, scratch[:8]...)
+		binary.BigEndian.PutUint32(scratch[:], uint32(key67.Nanosecond()))
+		entry69 = append(entry69, scratch[:4]...)
+		entry69 = append(entry69, byte(value68))
+		keys64 = append(keys64, key67)
+		entries65 = append(entries65, entry69)
+	}
+	order66 := make([]int, len(entries65))
+	for i := range order66 {
+		order66[i] = i
+	}
+	sort.Slice(order66, func(i, j int) bool {
+		a, b := order66[i], order66[j]
+		if !keys64[a].Equal(keys64[b]) {
+			return keys64[a].Before(keys64[b])
+		}
+		return bytes.Compare(entries65[a], entries65[b]) < 0
+	})
+	buf = append(buf, scratch[:binary.PutUvarint(scratch[:], uint64(len(entries65)))]...)
+	for _, i := range order66 {
+		buf = append(buf, entries65[i]...)
+	}
+	buf = append(buf, v.UUID[:]...)
+	binary.BigEndian.PutUint16(scratch[:], uint16(v.Uint16))
+	buf = append(buf, scratch[:2]...)
+	binary.BigEndian.PutUint32(scratch[:], uint32(v.Uint32))
+	buf = append(buf, scratch[:4]...)
+	buf = append(buf, scratch[:binary.PutUvarint(scratch[:], uint64(len(v.Uint32Slice)))]...)
+	for i70 := range v.Uint32Slice {
+		binary.BigEndian.PutUint32(scratch[:], uint32(v.Uint32Slice[i70]))
+		buf = append(buf, scratch[:4]...)
+	}
+	binary.BigEndian.PutUint64(scratch[:], uint64(v.Uint64))
+	buf = append(buf, scratch[:8]...)
+	buf = append(buf, byte(v.Uint8))
+	keys71 := make([]float64, 0, len(v.Weights))
+	entries72 := make([][]byte, 0, len(v.Weights))
+	for key74, value75 := range v.Weights {
+		var entry76 []byte
+		binary.BigEndian.PutUint64(scratch[:], uint64(math.Float64bits(float64(key74))))
+		entry76 = append(entry76, scratch[:8]...)
+		if value75 {
+			entry76 = append(entry76, 1)
+		} else {
+			entry76 = append(entry76, 0)
+		}
+		keys71 = append(keys71, key74)
+		entries72 = append(entries72, entry76)
+	}
+	order73 := make([]int, len(entries72))
+	for i := range order73 {
+		order73[i] = i
+	}
+	sort.Slice(order73, func(i, j int) bool {
+		a, b := order73[i], order73[j]
+		if keys71[a] != keys71[b] && (keys71[a] == keys71[a] || keys71[b] == keys71[b]) {
+			return keys71[a] != keys71[a] || keys71[a] < keys71[b]
+		}
+		return bytes.Compare(entries72[a], entries72[b]) < 0
+	})
+	buf = append(buf, scratch[:binary.PutUvarint(scratch[:], uint64(len(entries72)))]...)
+	for _, i := range order73 {
+		buf = append(buf, entries72[i]...)
+	}
+	binary.BigEndian.PutUint16(scratch[:], uint16(v.Year))
+	buf = append(buf, scratch[:2]...)
+	buf = append(buf, scratch[:binary.PutUvarint(scratch[:], uint64(len(v.Years)))]...)
+	for i77 := range v.Years {
+		binary.BigEndian.PutUint16(scratch[:], uint16(v.Years[i77]))
+		buf = append(buf, scratch[:2]...)
+	}
+	return buf, nil
+}
+
+// DecodeSimpleDB decodes the simpledb encoding of a Everything from the start of data into v,
+// returning the number of bytes read.
+func (v *Everything) DecodeSimpleDB(data []byte) (int, error) {
+	n := 0
+	length1, size2 := binary.Uvarint(data[n:])
+	if size2 <= 0 {
+		return n, io.ErrUnexpectedEOF
+	}
+	n += size2
+	if length1 > uint64(len(data)-n)/1 {
+		return n, io.ErrUnexpectedEOF
+	}
+	v.Address.City = string(data[n : n+int(length1)])
+	n += int(length1)
+	if len(data)-n < 2 {
+		return n, io.ErrUnexpectedEOF
+	}
+	v.Address.Zip = binary.BigEndian.Uint16(data[n:])
+	n += 2
+	if len(data)-n < 1 {
+		return n, io.ErrUnexpectedEOF
+	}
+	switch data[n] {
+	case 0:
+		n++
+		v.AddressPointer = nil
+	case 1:
+		n++
+		v.AddressPointer = new(Address)
+		length3, size4 := binary.Uvarint(data[n:])
+		if size4 <= 0 {
+			return n, io.ErrUnexpectedEOF
+		}
+		n += size4
+		if length3 > uint64(len(data)-n)/1 {
+			return n, io.ErrUnexpectedEOF
+		}
+		(*v.AddressPointer).City = string(data[n : n+int(length3)])
+		n += int(length3)
+		if len(data)-n < 2 {
+			return n, io.ErrUnexpectedEOF
+		}
+		(*v.AddressPointer).Zip = binary.BigEndian.Uint16(data[n:])
+		n += 2
+	default:
+		return n, fmt.Errorf("invalid pointer presence byte %#x", data[n])
+	}
+	length5, size6 := binary.Uvarint(data[n:])
+	if size6 <= 0 {
+		return n, io.ErrUnexpectedEOF
+	}
+	n += size6
+	if length5 > uint64(len(data)-n)/3 {
+		return n, io.ErrUnexpectedEOF
+	}
+	v.Addresses = make([]Address, length5)
+	for i7 := range v.Addresses {
+		length8, size9 := binary.Uvarint(data[n:])
+		if size9 <= 0 {
+			return n, io.ErrUnexpectedEOF
+		}
+		n += size9
+		if length8 > uint64(len(data)-n)/1 {
+			return n, io.ErrUnexpectedEOF
+		}
+		v.Addresses[i7].City = string(data[n : n+int(length8)])
+		n += int(length8)
+		if len(data)-n < 2 {
+			return n, io.ErrUnexpectedEOF
+		}
+		v.Addresses[i7].Zip = binary.BigEndian.Uint16(data[n:])
+		n += 2
+	}
+	length10, size11 := binary.Uvarint(data[n:])
+	if size11 <= 0 {
+		return n, io.ErrUnexpectedEOF
+	}
+	n += size11
+	if length10 > uint64(len(data)-n)/1 {
+		return n, io.ErrUnexpectedEOF
+	}
+	if err := v.Amount.UnmarshalBinary(data[n : n+int(length10)]); err != nil {
+		return n, err
+	}
+	n += int(length10)
+	length12, size13 := binary.Uvarint(data[n:])
+	if size13 <= 0 {
+		return n, io.ErrUnexpectedEOF
+	}
+	n += size13
+	if length12 > uint64(len(data)-n)/1 {
+		return n, io.ErrUnexpectedEOF
+	}
+	v.Amounts = make([]Amount, length12)
+	for i14 := range v.Amounts {
+		length15, size16 := binary.Uvarint(data[n:])
+		if size16 <= 0 {
+			return n, io.ErrUnexpectedEOF
+		}
+		n += size16
+		if length15 > uint64(len(data)-n)/1 {
+			return n, io.ErrUnexpectedEOF
+		}
+		if err := v.Amounts[i14].UnmarshalBinary(data[n : n+int(length15)]); err != nil {
+			return n, err
+		}
+		n += int(length15)
+	}
+	if len(data)-n < 1 {
+		return n, io.ErrUnexpectedEOF
+	}
+	switch data[n] {
+	case 0:
+		n++
+		v.Anonymous.A = nil
+	case 1:
+		n++
+		v.Anonymous.A = new(string)
+		length17, size18 := binary.Uvarint(data[n:])
+		if size18 <= 0 {
+			return n, io.ErrUnexpectedEOF
+		}
+		n += size18
+		if length17 > uint64(len(data)-n)/1 {
+			return n, io.ErrUnexpectedEOF
+		}
+		(*v.Anonymous.A) = string(data[n : n+int(length17)])
+		n += int(length17)
+	default:
+		return n, fmt.Errorf("invalid pointer presence byte %#x", data[n])
+	}
+	length19, size20 := binary.Uvarint(data[n:])
+	if size20 <= 0 {
+		return n, io.ErrUnexpectedEOF
+	}
+	n += size20
+	if length19 > uint64(len(data)-n)/1 {
+		return n, io.ErrUnexpectedEOF
+	}
+	v.Anonymous.B = make([]int8, length19)
+	for i21 := range v.Anonymous.B {
+		if len(data)-n < 1 {
+			return n, io.ErrUnexpectedEOF
+		}
+		v.Anonymous.B[i21] = int8(data[n])
+		n += 1
+	}
+	if len(data)-n < 8 {
+		return n, io.ErrUnexpectedEOF
+	}
+	v.Base.Created = int64(binary.BigEndian.Uint64(data[n:]))
+	n += 8
+	if len(data)-n < 1 {
+		return n, io.ErrUnexpectedEOF
+	}
+	v.Bool = data[n] != 0
+	n++
+	length22, size23 := binary.Uvarint(data[n:])
+	if size23 <= 0 {
+		return n, io.ErrUnexpectedEOF
+	}
+	n += size23
+	if length22 > uint64(len(data)-n)/7 {
+		return n, io.ErrUnexpectedEOF
+	}
+	v.ByAddress = make(map[Address]int32, length22)
+	for i := uint64(0); i < length22; i++ {
+		var key24 Address
+		var value25 int32
+		length26, size27 := binary.Uvarint(data[n:])
+		if size27 <= 0 {
+			return n, io.ErrUnexpectedEOF
+		}
+		n += size27
+		if length26 > uint64(len(data)-n)/1 {
+			return n, io.ErrUnexpectedEOF
+		}
+		key24.City = string(data[n : n+int(length26)])
+		n += int(length26)
+		if len(data)-n < 2 {
+			return n, io.ErrUnexpectedEOF
+		}
+		key24.Zip = binary.BigEndian.Uint16(data[n:])
+		n += 2
+		if len(data)-n < 4 {
+			return n, io.ErrUnexpectedEOF
+		}
+		value25 = int32(binary.BigEndian.Uint32(data[n:]))
+		n += 4
+		v.ByAddress[key24] = value25
+	}
+	length28, size29 := binary.Uvarint(data[n:])
+	if size29 <= 0 {
+		return n, io.ErrUnexpectedEOF
+	}
+	n += size29
+	if length28 > uint64(len(data)-n)/5 {
+		return n, io.ErrUnexpectedEOF
+	}
+	v.ByUUID = make(map[UUID]*Amount, length28)
+	for i := uint64(0); i < length28; i++ {
+		var key30 UUID
+		var value31 *Amount
+		if len(data)-n < 4 {
+			return n, io.ErrUnexpectedEOF
+		}
+		copy(key30[:], data[n:])
+		n += 4
+		if len(data)-n < 1 {
+			return n, io.ErrUnexpectedEOF
+		}
+		switch data[n] {
+		case 0:
+			n++
+			value31 = nil
+		case 1:
+			n++
+			value31 = new(Amount)
+			length32, size33 := binary.Uvarint(data[n:])
+			if size33 <= 0 {
+				return n, io.ErrUnexpectedEOF
+			}
+			n += size33
+			if length32 > uint64(len(data)-n)/1 {
+				return n, io.ErrUnexpectedEOF
+			}
+			if err := (*value31).UnmarshalBinary(data[n : n+int(length32)]); err != nil {
+				return n, err
+			}
+			n += int(length32)
+		default:
+			return n, fmt.Errorf("invalid pointer presence byte %#x", data[n])
+		}
+		v.ByUUID[key30] = value31
+	}
+	if len(data)-n < 4 {
+		return n, io.ErrUnexpectedEOF
+	}
+	copy(v.ByteArray[:], data[n:])
+	n += 4
+	length34, size35 := binary.Uvarint(data[n:])
+	if size35 <= 0 {
+		return n, io.ErrUnexpectedEOF
+	}
+	n += size35
+	if length34 > uint64(len(data)-n)/1 {
+		return n, io.ErrUnexpectedEOF
+	}
+	v.ByteSlice = make([]byte, length34)
+	n += copy(v.ByteSlice, data[n:])
+	length36, size37 := binary.Uvarint(data[n:])
+	if size37 <= 0 {
+		return n, io.ErrUnexpectedEOF
+	}
+	n += size37
+	if length36 > uint64(len(data)-n)/1 {
+		return n, io.ErrUnexpectedEOF
+	}
+	v.Bytes = make(Bytes, length36)
+	n += copy(v.Bytes, data[n:])
+	length38, size39 := binary.Uvarint(data[n:])
+	if size39 <= 0 {
+		return n, io.ErrUnexpectedEOF
+	}
+	n += size39
+	if length38 > uint64(len(data)-n)/9 {
+		return n, io.ErrUnexpectedEOF
+	}
+	v.Complex = make(map[complex64]bool, length38)
+	for i := uint64(0); i < length38; i++ {
+		var key40 complex64
+		var value41 bool
+		if len(data)-n < 8 {
+			return n, io.ErrUnexpectedEOF
+		}
+		key40 = complex(math.Float32frombits(binary.BigEndian.Uint32(data[n:])), math.Float32frombits(binary.BigEndian.Uint32(data[n+4:])))
+		n += 8
+		if len(data)-n < 1 {
+			return n, io.ErrUnexpectedEOF
+		}
+		value41 = data[n] != 0
+		n++
+		v.Complex[key40] = value41
+	}
+	if len(data)-n < 16 {
+		return n, io.ErrUnexpectedEOF
+	}
+	v.Complex128 = complex(math.Float64frombits(binary.BigEndian.Uint64(data[n:])), math.Float64frombits(binary.BigEndian.Uint64(data[n+8:])))
+	n += 16
+	if len(data)-n < 8 {
+		return n, io.ErrUnexpectedEOF
+	}
+	v.Complex64 = complex(math.Float32frombits(binary.BigEndian.Uint32(data[n:])), math.Float32frombits(binary.BigEndian.Uint32(data[n+4:])))
+	n += 8
+	length42, size43 := binary.Uvarint(data[n:])
+	if size43 <= 0 {
+		return n, io.ErrUnexpectedEOF
+	}
+	n += size43
+	if length42 > uint64(len(data)-n)/9 {
+		return n, io.ErrUnexpectedEOF
+	}
+	v.Coordinates = make(map[[2]float32]string, length42)
+	for i := uint64(0); i < length42; i++ {
+		var key44 [2]float32
+		var value45 string
+		for i46 := range key44 {
+			if len(data)-n < 4 {
+				return n, io.ErrUnexpectedEOF
+			}
+			key44[i46] = math.Float32frombits(binary.BigEndian.Uint32(data[n:]))
+			n += 4
+		}
+		length47, size48 := binary.Uvarint(data[n:])
+		if size48 <= 0 {
+			return n, io.ErrUnexpectedEOF
+		}
+		n += size48
+		if length47 > uint64(len(data)-n)/1 {
+			return n, io.ErrUnexpectedEOF
+		}
+		value45 = string(data[n : n+int(length47)])
+		n += int(length47)
+		v.Coordinates[key44] = value45
+	}
+	if len(data)-n < 8 {
+		return n, io.ErrUnexpectedEOF
+	}
+	v.Duration = time.Duration(binary.BigEndian.Uint64(data[n:]))
+	n += 8
+	if len(data)-n < 1 {
+		return n, io.ErrUnexpectedEOF
+	}
+	v.Flag = Flag(data[n] != 0)
+	n++
+	if len(data)-n < 4 {
+		return n, io.ErrUnexpectedEOF
+	}
+	v.Float32 = math.Float32frombits(binary.BigEndian.Uint32(data[n:]))
+	n += 4
+	if len(data)-n < 8 {
+		return n, io.ErrUnexpectedEOF
+	}
+	v.Float64 = math.Float64frombits(binary.BigEndian.Uint64(data[n:]))
+	n += 8
+	if len(data)-n < 2 {
+		return n, io.ErrUnexpectedEOF
+	}
+	v.Int16 = int16(binary.BigEndian.Uint16(data[n:]))
+	n += 2
+	for i49 := range v.Int16Array {
+		if len(data)-n < 2 {
+			return n, io.ErrUnexpectedEOF
+		}
+		v.Int16Array[i49] = int16(binary.BigEndian.Uint16(data[n:]))
+		n += 2
+	}
+	if len(data)-n < 4 {
+		return n, io.ErrUnexpectedEOF
+	}
+	v.Int32 = int32(binary.BigEndian.Uint32(data[n:]))
+	n += 4
+	if len(data)-n < 8 {
+		return n, io.ErrUnexpectedEOF
+	}
+	v.Int64 = int64(binary.BigEndian.Uint64(data[n:]))
+	n += 8
+	if len(data)-n < 1 {
+		return n, io.ErrUnexpectedEOF
+	}
+	v.Int8 = int8(data[n])
+	n += 1
+	length50, size51 := binary.Uvarint(data[n:])
+	if size51 <= 0 {
+		return n, io.ErrUnexpectedEOF
+	}
+	n += size51
+	if length50 > uint64(len(data)-n)/2 {
+		return n, io.ErrUnexpectedEOF
+	}
+	v.Labels = make(map[string]uint8, length50)
+	for i := uint64(0); i < length50; i++ {
+		var key52 string
+		var value53 uint8
+		length54, size55 := binary.Uvarint(data[n:])
+		if size55 <= 0 {
+			return n, io.ErrUnexpectedEOF
+		}
+		n += size55
+		if length54 > uint64(len(data)-n)/1 {
+			return n, io.ErrUnexpectedEOF
+		}
+		key52 = string(data[n : n+int(length54)])
+		n += int(length54)
+		if len(data)-n < 1 {
+			return n, io.ErrUnexpectedEOF
+		}
+		value53 = data[n]
+		n += 1
+		v.Labels[key52] = value53
+	}
+	if len(data)-n < 1 {
+		return n, io.ErrUnexpectedEOF
+	}
+	v.Level = Level(data[n])
+	n += 1
+	length56, size57 := binary.Uvarint(data[n:])
+	if size57 <= 0 {
+		return n, io.ErrUnexpectedEOF
+	}
+	n += size57
+	if length56 > uint64(len(data)-n)/1 {
+		return n, io.ErrUnexpectedEOF
+	}
+	v.Make = Make(data[n : n+int(length56)])
+	n += int(length56)
+	length58, size59 := binary.Uvarint(data[n:])
+	if size59 <= 0 {
+		return n, io.ErrUnexpectedEOF
+	}
+	n += size59
+	if length58 > uint64(len(data)-n)/2 {
+		return n, io.ErrUnexpectedEOF
+	}
+	v.Nested = make(map[string]map[Year]string, length58)
+	for i := uint64(0); i < length58; i++ {
+		var key60 string
+		var value61 map[Year]string
+		length62, size63 := binary.Uvarint(data[n:])
+		if size63 <= 0 {
+			return n, io.ErrUnexpectedEOF
+		}
+		n += size63
+		if length62 > uint64(len(data)-n)/1 {
+			return n, io.ErrUnexpectedEOF
+		}
+		key60 = string(data[n : n+int(length62)])
+		n += int(length62)
+		length64, size65 := binary.Uvarint(data[n:])
+		if size65 <= 0 {
+			return n, io.ErrUnexpectedEOF
+		}
+		n += size65
+		if length64 > uint64(len(data)-n)/3 {
+			return n, io.ErrUnexpectedEOF
+		}
+		value61 = make(map[Year]string, length64)
+		for i := uint64(0); i < length64; i++ {
+			var key66 Year
+			var value67 string
+			if len(data)-n < 2 {
+				return n, io.ErrUnexpectedEOF
+			}
+			key66 = Year(binary.BigEndian.Uint16(data[n:]))
+			n += 2
+			length68, size69 := binary.Uvarint(data[n:])
+			if size69 <= 0 {
+				return n, io.ErrUnexpectedEOF
+			}
+			n += size69
+			if length68 > uint64(len(data)-n)/1 {
+				return n, io.ErrUnexpectedEOF
+			}
+			value67 = string(data[n : n+int(length68)])
+			n += int(length68)
+			value61[key66] = value67
+		}
+		v.Nested[key60] = value61
+	}
+	if len(data)-n < 8 {
+		return n, io.ErrUnexpectedEOF
+	}
+	v.Point = Point(complex(math.Float32frombits(binary.BigEndian.Uint32(data[n:])), math.Float32frombits(binary.BigEndian.Uint32(data[n+4:]))))
+	n += 8
+	if len(data)-n < 1 {
+		return n, io.ErrUnexpectedEOF
+	}
+	switch data[n] {
+	case 0:
+		n++
+		v.Pointer = nil
+	case 1:
+		n++
+		v.Pointer = new(uint16)
+		if len(data)-n < 2 {
+			return n, io.ErrUnexpectedEOF
+		}
+		(*v.Pointer) = binary.BigEndian.Uint16(data[n:])
+		n += 2
+	default:
+		return n, fmt.Errorf("invalid pointer presence byte %#x", data[n])
+	}
+	length70, size71 := binary.Uvarint(data[n:])
+	if size71 <= 0 {
+		return n, io.ErrUnexpectedEOF
+	}
+	n += size71
+	if length70 > uint64(len(data)-n)/1 {
+		return n, io.ErrUnexpectedEOF
+	}
+	v.PointerSlice = make([]*Address, length70)
+	for i72 := range v.PointerSlice {
+		if len(data)-n < 1 {
+			return n, io.ErrUnexpectedEOF
+		}
+		switch data[n] {
+		case 0:
+			n++
+			v.PointerSlice[i72] = nil
+		case 1:
+			n++
+			v.PointerSlice[i72] = new(Address)
+			length73, size74 := binary.Uvarint(data[n:])
+			if size74 <= 0 {
+				return n, io.ErrUnexpectedEOF
+			}
+			n += size74
+			if length73 > uint64(len(data)-n)/1 {
+				return n, io.ErrUnexpectedEOF
+			}
+			(*v.PointerSlice[i72]).City = string(data[n : n+int(length73)])
+			n += int(length73)
+			if len(data)-n < 2 {
+				return n, io.ErrUnexpectedEOF
+			}
+			(*v.PointerSlice[i72]).Zip = binary.BigEndian.Uint16(data[n:])
+			n += 2
+		default:
+			return n, fmt.Errorf("invalid pointer presence byte %#x", data[n])
+		}
+	}
+	length75, size76 := binary.Uvarint(data[n:])
+	if size76 <= 0 {
+		return n, io.ErrUnexpectedEOF
+	}
+	n += size76
+	if length75 > uint64(len(data)-n)/2 {
+		return n, io.ErrUnexpectedEOF
+	}
+	v.Ports = make(map[int8][]string, length75)
+	for i := uint64(0); i < length75; i++ {
+		var key77 int8
+		var value78 []string
+		if len(data)-n < 1 {
+			return n, io.ErrUnexpectedEOF
+		}
+		key77 = int8(data[n])
+		n += 1
+		length79, size80 := binary.Uvarint(data[n:])
+		if size80 <= 0 {
+			return n, io.ErrUnexpectedEOF
+		}
+		n += size80
+		if length79 > uint64(len(data)-n)/1 {
+			return n, io.ErrUnexpectedEOF
+		}
+		value78 = make([]string, length79)
+		for i81 := range value78 {
+			length82, size83 := binary.Uvarint(data[n:])
+			if size83 <= 0 {
+				return n, io.ErrUnexpectedEOF
+			}
+			n += size83
+			if length82 > uint64(len(data)-n)/1 {
+				return n, io.ErrUnexpectedEOF
+			}
+			value78[i81] = string(data[n : n+int(length82)])
+			n += int(length82)
+		}
+		v.Ports[key77] = value78
+	}
+	if len(data)-n < 4 {
+		return n, io.ErrUnexpectedEOF
+	}
+	v.Ratio = Ratio(math.Float32frombits(binary.BigEndian.Uint32(data[n:])))
+	n += 4
+	length84, size85 := binary.Uvarint(data[n:])
+	if size85 <= 0 {
+		return n, io.ErrUnexpectedEOF
+	}
+	n += size85
+	if length84 > uint64(len(data)-n)/1 {
+		return n, io.ErrUnexpectedEOF
+	}
+	v.Shared = string(data[n : n+int(length84)])
+	n += int(length84)
+	length86, size87 := binary.Uvarint(data[n:])
+	if size87 <= 0 {
+		return n, io.ErrUnexpectedEOF
+	}
+	n += size87
+	if length86 > uint64(len(data)-n)/1 {
+		return n, io.ErrUnexpectedEOF
+	}
+	v.String = string(data[n : n+int(length86)])
+	n += int(length86)
+	for i88 := range v.StringArray {
+		length89, size90 := binary.Uvarint(data[n:])
+		if size90 <= 0 {
+			return n, io.ErrUnexpectedEOF
+		}
+		n += size90
+		if length89 > uint64(len(data)-n)/1 {
+			return n, io.ErrUnexpectedEOF
+		}
+		v.StringArray[i88] = string(data[n : n+int(length89)])
+		n += int(length89)
+	}
+	length91, size92 := binary.Uvarint(data[n:])
+	if size92 <= 0 {
+		return n, io.ErrUnexpectedEOF
+	}
+	n += size92
+	if length91 > uint64(len(data)-n)/1 {
+		return n, io.ErrUnexpectedEOF
+	}
+	v.Strings = make([][]string, length91)
+	for i93 := range v.Strings {
+		length94, size95 := binary.Uvarint(data[n:])
+		if size95 <= 0 {
+			return n, io.ErrUnexpectedEOF
+		}
+		n += size95
+		if length94 > uint64(len(data)-n)/1 {
+			return n, io.ErrUnexpectedEOF
+		}
+		v.Strings[i93] = make([]string, length94)
+		for i96 := range v.Strings[i93] {
+			length97, size98 := binary.Uvarint(data[n:])
+			if size98 <= 0 {
+				return n, io.ErrUnexpectedEOF
+			}
+			n += size98
+			if length97 > uint64(len(data)-n)/1 {
+				return n, io.ErrUnexpectedEOF
+			}
+			v.Strings[i93][i96] = string(data[n : n+int(length97)])
+			n += int(length97)
+		}
+	}
+	if len(data)-n < 12 {
+		return n, io.ErrUnexpectedEOF
+	}
+	v.Time = time.Unix(int64(binary.BigEndian.Uint64(data[n:])), int64(binary.BigEndian.Uint32(data[n+8:]))).UTC()
+	n += 12
+	if len(data)-n < 1 {
+		return n, io.ErrUnexpectedEOF
+	}
+	switch data[n] {
+	case 0:
+		n++
+		v.TimePtr = nil
+	case 1:
+		n++
+		v.TimePtr = new(time.Time)
+		if len(data)-n < 12 {
+			return n, io.ErrUnexpectedEOF
+		}
+		(*v.TimePtr) = time.Unix(int64(binary.BigEndian.Uint64(data[n:])), int64(binary.BigEndian.Uint32(data[n+8:]))).UTC()
+		n += 12
+	default:
+		return n, fmt.Errorf("invalid pointer presence byte %#x", data[n])
+	}
+	length99, size100 := binary.Uvarint(data[n:])
+	if size100 <= 0 {
+		return n, io.ErrUnexpectedEOF
+	}
+	n += size100
+	if length99 > uint64(len(data)-n)/13 {
+		return n, io.ErrUnexpectedEOF
+	}
+	v.Times = make(map[time.Time]uint8, length99)
+	for i := uint64(0); i < length99; i++ {
+		var key101 time.Time
+		var value102 uint8
+		if len(data)-n < 12 {
+			return n, io.ErrUnexpectedEOF
+		}
+		key101 = time.Unix(int64(binary.BigEndian.Uint64(data[n:])), int64(binary.BigEndian.Uint32(data[n+8:]))).UTC()
+		n += 12
+		if len(data)-n < 1 {
+			return n, io.ErrUnexpectedEOF
+		}
+		value102 = data[n]
+		n += 1
+		v.Times[key101] = value102
+	}
+	if len(data)-n < 4 {
+		return n, io.ErrUnexpectedEOF
+	}
+	copy(v.UUID[:], data[n:])
+	n += 4
+	if len(data)-n < 2 {
+		return n, io.ErrUnexpectedEOF
+	}
+	v.Uint16 = binary.BigEndian.Uint16(data[n:])
+	n += 2
+	if len(data)-n < 4 {
+		return n, io.ErrUnexpectedEOF
+	}
+	v.Uint32 = binary.BigEndian.Uint32(data[n:])
+	n += 4
+	length103, size104 := binary.Uvarint(data[n:])
+	if size104 <= 0 {
+		return n, io.ErrUnexpectedEOF
+	}
+	n += size104
+	if length103 > uint64(len(data)-n)/4 {
+		return n, io.ErrUnexpectedEOF
+	}
+	v.Uint32Slice = make([]uint32, length103)
+	for i105 := range v.Uint32Slice {
+		if len(data)-n < 4 {
+			return n, io.ErrUnexpectedEOF
+		}
+		v.Uint32Slice[i105] = binary.BigEndian.Uint32(data[n:])
+		n += 4
+	}
+	if len(data)-n < 8 {
+		return n, io.ErrUnexpectedEOF
+	}
+	v.Uint64 = binary.BigEndian.Uint64(data[n:])
+	n += 8
+	if len(data)-n < 1 {
+		return n, io.ErrUnexpectedEOF
+	}
+	v.Uint8 = data[n]
+	n += 1
+	length106, size107 := binary.Uvarint(data[n:])
+	if size107 <= 0 {
+		return n, io.ErrUnexpectedEOF
+	}
+	n += size107
+	if length106 > uint64(len(data)-n)/9 {
+		return n, io.ErrUnexpectedEOF
+	}
+	v.Weights = make(map[float64]bool, length106)
+	for i := uint64(0); i < length106; i++ {
+		var key108 float64
+		var value109 bool
+		if len(data)-n < 8 {
+			return n, io.ErrUnexpectedEOF
+		}
+		key108 = math.Float64frombits(binary.BigEndian.Uint64(data[n:]))
+		n += 8
+		if len(data)-n < 1 {
+			return n, io.ErrUnexpectedEOF
+		}
+		value109 = data[n] != 0
+		n++
+		v.Weights[key108] = value109
+	}
+	if len(data)-n < 2 {
+		return n, io.ErrUnexpectedEOF
+	}
+	v.Year = Year(binary.BigEndian.Uint16(data[n:]))
+	n += 2
+	length110, size111 := binary.Uvarint(data[n:])
+	if size111 <= 0 {
+		return n, io.ErrUnexpectedEOF
+	}
+	n += size111
+	if length110 > uint64(len(data)-n)/2 {
+		return n, io.ErrUnexpectedEOF
+	}
+	v.Years = make([]Year, length110)
+	for i112 := range v.Years {
+		if len(data)-n < 2 {
+			return n, io.ErrUnexpectedEOF
+		}
+		v.Years[i112] = Year(binary.BigEndian.Uint16(data[n:]))
+		n += 2
+	}
+	return n, nil
+}
+
+// EncodeSimpleDB appends the simpledb encoding of v to buf, without using reflection.
+func (v Event) EncodeSimpleDB(buf []byte) ([]byte, error) {
+	var scratch [binary.MaxVarintLen64]byte
+	buf = append(buf, v.ID[:]...)
+	buf = append(buf, scratch[:binary.PutUvarint(scratch[:], uint64(len(v.Name)))]...)
+	buf = append(buf, v.Name...)
+	binary.BigEndian.PutUint64(scratch[:], uint64(v.Start.Unix()))
+	buf = append(buf, scratch[:8]...)
+	binary.BigEndian.PutUint32(scratch[:], uint32(v.Start.Nanosecond()))
+	buf = append(buf, scratch[:4]...)
+	buf = append(buf, scratch[:binary.PutUvarint(scratch[:], uint64(len(v.Tags)))]...)
+	for i1 := range v.Tags {
+		buf = append(buf, scratch[:binary.PutUvarint(scratch[:], uint64(len(v.Tags[i1])))]...)
+		buf = append(buf, v.Tags[i1]...)
+	}
+	return buf, nil
+}
+
+// DecodeSimpleDB decodes the simpledb encoding of a Event from the start of data into v,
+// returning the number of bytes read.
+func (v *Event) DecodeSimpleDB(data []byte) (int, error) {
+	n := 0
+	if len(data)-n < 4 {
+		return n, io.ErrUnexpectedEOF
+	}
+	copy(v.ID[:], data[n:])
+	n += 4
+	length1, size2 := binary.Uvarint(data[n:])
+	if size2 <= 0 {
+		return n, io.ErrUnexpectedEOF
+	}
+	n += size2
+	if length1 > uint64(len(data)-n)/1 {
+		return n, io.ErrUnexpectedEOF
+	}
+	v.Name = string(data[n : n+int(length1)])
+	n += int(length1)
+	if len(data)-n < 12 {
+		return n, io.ErrUnexpectedEOF
+	}
+	v.Start = time.Unix(int64(binary.BigEndian.Uint64(data[n:])), int64(binary.BigEndian.Uint32(data[n+8:]))).UTC()
+	n += 12
+	length3, size4 := binary.Uvarint(data[n:])
+	if size4 <= 0 {
+		return n, io.ErrUnexpectedEOF
+	}
+	n += size4
+	if length3 > uint64(len(data)-n)/1 {
+		return n, io.ErrUnexpectedEOF
+	}
+	v.Tags = make([]string, length3)
+	for i5 := range v.Tags {
+		length6, size7 := binary.Uvarint(data[n:])
+		if size7 <= 0 {
+			return n, io.ErrUnexpectedEOF
+		}
+		n += size7
+		if length6 > uint64(len(data)-n)/1 {
+			return n, io.ErrUnexpectedEOF
+		}
+		v.Tags[i5] = string(data[n : n+int(length6)])
+		n += int(length6)
+	}
+	return n, nil
+}
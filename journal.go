@@ -0,0 +1,193 @@
+package simpledb
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"hash/crc32"
+	"io"
+	"math"
+)
+
+// ErrRecoveryNeeded is returned by calls which would modify a DB after a commit failed part-way through
+// writing to the DB source. The interrupted commit is kept in the journal, and is replayed when the DB
+// is next opened, so the DB must be closed and opened again before it can be modified.
+var ErrRecoveryNeeded = errors.New("DB source write was interrupted, and must be recovered from the journal by reopening the DB")
+
+// truncateOffset is the offset of a sourceWrite which truncates the DB source to the size held in
+// its data as a big-endian uint64. It is used in journal records which replace the whole DB source.
+const truncateOffset int64 = math.MaxInt64
+
+// encodeJournalRecord encodes a set of writes to the DB source as a journal record. The record consists of
+// the number of writes as an unsigned varint, then each write's uint64 offset, the length of its data as an
+// unsigned varint, and the data itself. The record ends with a big-endian CRC-32 checksum of all preceding
+// bytes, so that a record which was only partially written to the journal can be detected.
+func encodeJournalRecord(writes []sourceWrite) []byte {
+	buf := new(bytes.Buffer)
+	buf.Write(encodeUvarint(uint64(len(writes))))
+	for _, write := range writes {
+		buf.Write(encodeUint64(uint64(write.offset)))
+		buf.Write(encodeUvarint(uint64(len(write.data))))
+		buf.Write(write.data)
+	}
+
+	checksum := make([]byte, 4)
+	binary.BigEndian.PutUint32(checksum, crc32.ChecksumIEEE(buf.Bytes()))
+	buf.Write(checksum)
+
+	return buf.Bytes()
+}
+
+// decodeJournalRecord decodes a journal record. It returns false if the record is incomplete or corrupted.
+func decodeJournalRecord(record []byte) ([]sourceWrite, bool) {
+	r := bytes.NewReader(record)
+
+	count, err := binary.ReadUvarint(r)
+	if err != nil {
+		return nil, false
+	}
+
+	writes := make([]sourceWrite, 0)
+	for i := uint64(0); i < count; i++ {
+		offset, err := decodeUint64(r)
+		if err != nil {
+			return nil, false
+		}
+
+		length, err := binary.ReadUvarint(r)
+		if err != nil || length > uint64(r.Len()) {
+			return nil, false
+		}
+
+		data := make([]byte, length)
+		if _, err := io.ReadFull(r, data); err != nil {
+			return nil, false
+		}
+
+		writes = append(writes, sourceWrite{offset: int64(offset), data: data})
+	}
+
+	checksumOffset := len(record) - r.Len()
+	var checksum uint32
+	if err := binary.Read(r, binary.BigEndian, &checksum); err != nil {
+		return nil, false
+	}
+
+	if crc32.ChecksumIEEE(record[:checksumOffset]) != checksum {
+		return nil, false
+	}
+
+	return writes, true
+}
+
+// commitWrites applies the given writes to the DB source. If the DB has a journal, the writes are first
+// recorded in the journal, so that they can be replayed by recoverJournal if they are interrupted. The
+// journal and the DB source are each flushed to stable storage before moving on, and the journal is
+// cleared once the writes have been applied. Without a journal, the writes are not flushed, as there
+// is no ordering between them to preserve.
+//
+// If the writes to the DB source fail, the journal record is kept, and every later commit returns
+// ErrRecoveryNeeded rather than overwriting it, until the record is replayed by reopening the DB.
+func (file *dbFile) commitWrites(writes []sourceWrite) error {
+	if file.recoveryNeeded {
+		return ErrRecoveryNeeded
+	}
+
+	if err := file.invalidateIndexSnapshot(); err != nil {
+		return err
+	}
+
+	if file.journal == nil {
+		return applyWrites(file.source, writes)
+	}
+
+	journalWrites := []sourceWrite{{offset: 0, data: encodeJournalRecord(writes)}}
+	if err := applyWrites(file.journal, journalWrites); err != nil {
+		return err
+	}
+	if err := syncSource(file.journal); err != nil {
+		return err
+	}
+
+	if err := applyWrites(file.source, writes); err != nil {
+		file.recoveryNeeded = true
+		return err
+	}
+	if err := syncSource(file.source); err != nil {
+		file.recoveryNeeded = true
+		return err
+	}
+
+	return file.clearJournal()
+}
+
+// journalRewrite records the replacement of the whole DB source with the first size bytes read from contents
+// in the journal, and flushes it to stable storage. The record is the same as that returned by encodeJournalRecord
+// for a write of the contents at offset zero followed by a truncation to size, but the contents are streamed
+// to the journal rather than held in memory.
+func (file *dbFile) journalRewrite(contents io.Reader, size int64) error {
+	if _, err := file.journal.Seek(0, io.SeekStart); err != nil {
+		return err
+	}
+
+	checksum := crc32.NewIEEE()
+	w := bufio.NewWriter(io.MultiWriter(file.journal, checksum))
+
+	w.Write(encodeUvarint(2))
+	w.Write(encodeUint64(0))
+	w.Write(encodeUvarint(uint64(size)))
+	if _, err := io.CopyN(w, contents, size); err != nil {
+		return err
+	}
+
+	w.Write(encodeUint64(uint64(truncateOffset)))
+	w.Write(encodeUvarint(8))
+	w.Write(encodeUint64(uint64(size)))
+
+	if err := w.Flush(); err != nil {
+		return err
+	}
+	if err := binary.Write(file.journal, binary.BigEndian, checksum.Sum32()); err != nil {
+		return err
+	}
+
+	return syncSource(file.journal)
+}
+
+// recoverJournal replays the journal record left behind by an interrupted commit, if any. If the record
+// was only partially written to the journal, the DB source was never modified, so the record is discarded.
+func (file *dbFile) recoverJournal() error {
+	if _, err := file.journal.Seek(0, io.SeekStart); err != nil {
+		return err
+	}
+
+	record, err := io.ReadAll(file.journal)
+	if err != nil {
+		return err
+	} else if len(record) == 0 {
+		return nil
+	}
+
+	if writes, ok := decodeJournalRecord(record); ok {
+		if err := file.invalidateIndexSnapshot(); err != nil {
+			return err
+		}
+		if err := applyWrites(file.source, writes); err != nil {
+			return err
+		}
+		if err := syncSource(file.source); err != nil {
+			return err
+		}
+	}
+
+	return file.clearJournal()
+}
+
+// clearJournal truncates the journal to zero size.
+func (file *dbFile) clearJournal() error {
+	if err := file.journal.Truncate(0); err != nil {
+		return err
+	}
+	return syncSource(file.journal)
+}
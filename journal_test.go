@@ -0,0 +1,228 @@
+package simpledb
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"testing"
+)
+
+// crashingSource is a Source which fails every write after a given number of writes have succeeded.
+type crashingSource struct {
+	*os.File
+	writesLeft int
+}
+
+func (source *crashingSource) Write(p []byte) (int, error) {
+	if source.writesLeft <= 0 {
+		return 0, errors.New("simulated crash")
+	}
+	source.writesLeft -= 1
+	return source.File.Write(p)
+}
+
+func (source *crashingSource) WriteAt(p []byte, offset int64) (int, error) {
+	if source.writesLeft <= 0 {
+		return 0, errors.New("simulated crash")
+	}
+	source.writesLeft -= 1
+	return source.File.WriteAt(p, offset)
+}
+
+// ReadFrom hides the ReadFrom method of *os.File, so that io.Copy writes to the source with Write.
+func (source *crashingSource) ReadFrom(r io.Reader) (int64, error) {
+	return io.Copy(struct{ io.Writer }{source}, r)
+}
+
+func TestJournal(t *testing.T) {
+	type Account struct {
+		Owner   string `simpledb:"indexed"`
+		Balance int64
+	}
+
+	tempFile, err := os.CreateTemp(os.TempDir(), "simpledb-")
+	if err != nil {
+		t.Fatalf("Failed to create temp file: %s", err)
+	}
+	journalFile, err := os.CreateTemp(os.TempDir(), "simpledb-journal-")
+	if err != nil {
+		t.Fatalf("Failed to create journal file: %s", err)
+	}
+
+	t.Cleanup(func() {
+		tempFile.Close()
+		journalFile.Close()
+		os.Remove(tempFile.Name())
+		os.Remove(journalFile.Name())
+	})
+
+	source := &crashingSource{File: tempFile, writesLeft: 1000}
+	db, err := NewDB(source, Account{}, WithJournal(journalFile))
+	if err != nil {
+		t.Fatalf("failed to create DB: %s", err)
+	}
+
+	id, err := db.Insert(Account{Owner: "alice", Balance: 100})
+	if err != nil {
+		t.Fatalf("Failed to insert account: %s", err)
+	}
+
+	if info, _ := journalFile.Stat(); info.Size() != 0 {
+		t.Fatalf("expected journal to be cleared after commit")
+	}
+
+	// crash after zeroing the row's ID, but before the updated row is written
+	source.writesLeft = 1
+	if err := db.Update(id, Account{Owner: "alice", Balance: 250}); err == nil {
+		t.Fatalf("expected simulated crash during update")
+	}
+
+	// the interrupted update must not be overwritten in the journal by later writes
+	source.writesLeft = 1000
+	if _, err := db.Insert(Account{Owner: "bob", Balance: 50}); !errors.Is(err, ErrRecoveryNeeded) {
+		t.Fatalf("expected ErrRecoveryNeeded inserting after simulated crash, got: %v", err)
+	}
+	if err := db.Defrag(); !errors.Is(err, ErrRecoveryNeeded) {
+		t.Fatalf("expected ErrRecoveryNeeded defragging after simulated crash, got: %v", err)
+	}
+
+	db, err = NewDB(tempFile, Account{}, WithJournal(journalFile))
+	if err != nil {
+		t.Fatalf("Failed to reopen DB: %s", err)
+	}
+
+	var account Account
+	if err := db.Find(id, &account); err != nil {
+		t.Fatalf("Failed to find account after replaying journal: %s", err)
+	}
+	if account.Balance != 250 {
+		t.Fatalf("expected interrupted update to be replayed, got balance %d", account.Balance)
+	}
+	if db.RowCount() != 1 {
+		t.Fatalf("expected 1 row after replaying journal, got %d", db.RowCount())
+	}
+
+	if info, _ := journalFile.Stat(); info.Size() != 0 {
+		t.Fatalf("expected journal to be cleared after recovery")
+	}
+
+	// a record which was never fully written to the journal is discarded
+	sizeBefore, _ := tempFile.Seek(0, io.SeekEnd)
+	record := encodeJournalRecord([]sourceWrite{{offset: sizeBefore, data: []byte("torn write")}})
+	journalFile.WriteAt(record[:len(record)-1], 0)
+
+	db, err = NewDB(tempFile, Account{}, WithJournal(journalFile))
+	if err != nil {
+		t.Fatalf("Failed to reopen DB with torn journal record: %s", err)
+	}
+
+	if sizeAfter, _ := db.Size(); sizeAfter != sizeBefore {
+		t.Fatalf("expected torn journal record to be discarded")
+	}
+	if err := db.Find(id, &account); err != nil || account.Balance != 250 {
+		t.Fatalf("Failed to find account after discarding torn journal record: %v", err)
+	}
+}
+
+func TestJournalRewrite(t *testing.T) {
+	type Account struct {
+		Owner   string `simpledb:"indexed"`
+		Balance int64
+	}
+	type AccountV2 struct {
+		Owner    string `simpledb:"indexed"`
+		Balance  int64
+		Currency string
+	}
+
+	tempFile, err := os.CreateTemp(os.TempDir(), "simpledb-")
+	if err != nil {
+		t.Fatalf("Failed to create temp file: %s", err)
+	}
+	journalFile, err := os.CreateTemp(os.TempDir(), "simpledb-journal-")
+	if err != nil {
+		t.Fatalf("Failed to create journal file: %s", err)
+	}
+
+	t.Cleanup(func() {
+		tempFile.Close()
+		journalFile.Close()
+		os.Remove(tempFile.Name())
+		os.Remove(journalFile.Name())
+	})
+
+	// the streamed journal record matches an encoded one
+	contents := []byte("rewritten source contents")
+	db := &DB{dbFile: &dbFile{journal: journalFile}}
+	if err := db.journalRewrite(bytes.NewReader(contents), int64(len(contents))); err != nil {
+		t.Fatalf("Failed to journal rewrite: %s", err)
+	}
+	record, _ := os.ReadFile(journalFile.Name())
+	expected := encodeJournalRecord([]sourceWrite{
+		{offset: 0, data: contents},
+		{offset: truncateOffset, data: encodeUint64(uint64(len(contents)))},
+	})
+	if !bytes.Equal(record, expected) {
+		t.Fatalf("streamed journal record does not match encoded record")
+	}
+	journalFile.Truncate(0)
+
+	source := &crashingSource{File: tempFile, writesLeft: 1 << 20}
+	db, err = NewDB(source, Account{}, WithJournal(journalFile))
+	if err != nil {
+		t.Fatalf("failed to create DB: %s", err)
+	}
+
+	ids := make([]uint64, 4000)
+	tx := db.Begin()
+	for i := range ids {
+		if ids[i], err = tx.Insert(Account{Owner: fmt.Sprintf("owner%d", i), Balance: int64(i)}); err != nil {
+			t.Fatalf("Failed to insert account: %s", err)
+		}
+	}
+	if err := tx.Commit(); err != nil {
+		t.Fatalf("Failed to commit accounts: %s", err)
+	}
+
+	tx = db.Begin()
+	for _, id := range ids[:1000] {
+		if err := tx.Drop(id); err != nil {
+			t.Fatalf("Failed to drop account: %s", err)
+		}
+	}
+	if err := tx.Commit(); err != nil {
+		t.Fatalf("Failed to commit dropped accounts: %s", err)
+	}
+
+	// crash partway through copying the migrated DB back into the source
+	source.writesLeft = 1
+	err = db.Migrate(Account{}, AccountV2{}, func(oldPtr, newPtr interface{}) error {
+		newPtr.(*AccountV2).Currency = "USD"
+		return nil
+	})
+	if err == nil {
+		t.Fatalf("expected simulated crash during migration")
+	}
+
+	db, err = NewDB(tempFile, AccountV2{}, WithJournal(journalFile))
+	if err != nil {
+		t.Fatalf("Failed to reopen DB after interrupted migration: %s", err)
+	}
+	if db.RowCount() != 3000 {
+		t.Fatalf("expected 3000 rows after replaying journal, got %d", db.RowCount())
+	}
+	for i, id := range ids[1000:] {
+		var account AccountV2
+		if err := db.Find(id, &account); err != nil {
+			t.Fatalf("Failed to find account after replaying journal: %s", err)
+		} else if account.Owner != fmt.Sprintf("owner%d", i+1000) || account.Currency != "USD" {
+			t.Fatalf("unexpected account after replaying journal: %+v", account)
+		}
+	}
+
+	if info, _ := journalFile.Stat(); info.Size() != 0 {
+		t.Fatalf("expected journal to be cleared after recovery")
+	}
+}
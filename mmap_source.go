@@ -0,0 +1,276 @@
+package simpledb
+
+import (
+	"errors"
+	"io"
+	"os"
+	"sync"
+)
+
+// MmapSource is a Source which reads and writes an *os.File through a shared memory mapping, so that
+// reading rows does not need any system calls. The file is always kept at the size of the data written
+// to it, while the mapping grows in larger steps as rows are inserted, and shrinks when the file is
+// truncated, for instance by db.Defrag. Calling Sync flushes the changed pages of the mapping to disk.
+// MmapSource is only supported on Linux.
+//
+// If the DB is opened with NewDB on an MmapSource, the underlying file is locked like any other
+// *os.File (see LockMode). The file is closed when the MmapSource is closed.
+type MmapSource struct {
+	file  *os.File
+	mutex sync.RWMutex
+
+	// data is the memory mapping of the file, whose length is the mapping's capacity.
+	data []byte
+
+	// size is the size of the file, and offset is the cursor used by Read, Write and Seek.
+	size   int64
+	offset int64
+
+	// dirtyStart and dirtyEnd are the range of the mapping written to since the last Sync.
+	dirtyStart int64
+	dirtyEnd   int64
+}
+
+// errMmapUnsupported is returned by NewMmapSource on systems other than Linux.
+var errMmapUnsupported = errors.New("MmapSource is only supported on Linux")
+
+// mmapPageSize is the granularity of the mapping's capacity.
+var mmapPageSize = int64(os.Getpagesize())
+
+// NewMmapSource maps the given file, which must be open for reading and writing, into memory.
+func NewMmapSource(file *os.File) (*MmapSource, error) {
+	if !mmapSupported {
+		return nil, errMmapUnsupported
+	}
+
+	info, err := file.Stat()
+	if err != nil {
+		return nil, err
+	}
+
+	source := &MmapSource{file: file, size: info.Size()}
+	if err := source.remap(mmapCapacity(source.size)); err != nil {
+		return nil, err
+	}
+	return source, nil
+}
+
+// mmapCapacity returns the mapping capacity needed to hold size bytes, rounded up to a whole page.
+func mmapCapacity(size int64) int64 {
+	return (size + mmapPageSize - 1) / mmapPageSize * mmapPageSize
+}
+
+// remap replaces the mapping with a new one of the given capacity. It assumes the caller is handling the mutex.
+func (source *MmapSource) remap(capacity int64) error {
+	if source.data != nil {
+		if err := source.flush(); err != nil {
+			return err
+		}
+		if err := munmapFile(source.data); err != nil {
+			return err
+		}
+		source.data = nil
+	}
+
+	if capacity == 0 {
+		return nil
+	}
+
+	data, err := mmapFile(source.file, int(capacity))
+	if err != nil {
+		return err
+	}
+	source.data = data
+	return nil
+}
+
+// flush writes the pages of the mapping written to since the last flush to disk.
+// It assumes the caller is handling the mutex.
+func (source *MmapSource) flush() error {
+	if source.dirtyEnd <= source.dirtyStart {
+		return nil
+	}
+
+	start := source.dirtyStart / mmapPageSize * mmapPageSize
+	end := source.dirtyEnd
+	if end > int64(len(source.data)) {
+		end = int64(len(source.data))
+	}
+	if err := msyncFile(source.data[start:end]); err != nil {
+		return err
+	}
+
+	source.dirtyStart, source.dirtyEnd = 0, 0
+	return nil
+}
+
+// ReadAt implements io.ReaderAt.
+func (source *MmapSource) ReadAt(p []byte, offset int64) (int, error) {
+	source.mutex.RLock()
+	defer source.mutex.RUnlock()
+	return source.readAt(p, offset)
+}
+
+func (source *MmapSource) readAt(p []byte, offset int64) (int, error) {
+	if offset < 0 {
+		return 0, errors.New("MmapSource.ReadAt: negative offset")
+	} else if offset >= source.size {
+		return 0, io.EOF
+	}
+
+	n := copy(p, source.data[offset:source.size])
+	if n < len(p) {
+		return n, io.EOF
+	}
+	return n, nil
+}
+
+// WriteAt implements io.WriterAt. Writing past the end of the file grows it, remapping the file if needed.
+func (source *MmapSource) WriteAt(p []byte, offset int64) (int, error) {
+	source.mutex.Lock()
+	defer source.mutex.Unlock()
+	return source.writeAt(p, offset)
+}
+
+func (source *MmapSource) writeAt(p []byte, offset int64) (int, error) {
+	if offset < 0 {
+		return 0, errors.New("MmapSource.WriteAt: negative offset")
+	}
+
+	end := offset + int64(len(p))
+	if end > source.size {
+		if err := source.file.Truncate(end); err != nil {
+			return 0, err
+		}
+		source.size = end
+
+		// the capacity is at least doubled, so that inserting rows only remaps the file occasionally
+		if end > int64(len(source.data)) {
+			capacity := mmapCapacity(end)
+			if doubled := 2 * int64(len(source.data)); doubled > capacity {
+				capacity = doubled
+			}
+			if err := source.remap(capacity); err != nil {
+				return 0, err
+			}
+		}
+	}
+
+	n := copy(source.data[offset:end], p)
+
+	if source.dirtyEnd <= source.dirtyStart {
+		source.dirtyStart, source.dirtyEnd = offset, end
+	} else {
+		if offset < source.dirtyStart {
+			source.dirtyStart = offset
+		}
+		if end > source.dirtyEnd {
+			source.dirtyEnd = end
+		}
+	}
+
+	return n, nil
+}
+
+// Read implements io.Reader.
+func (source *MmapSource) Read(p []byte) (int, error) {
+	source.mutex.Lock()
+	defer source.mutex.Unlock()
+
+	n, err := source.readAt(p, source.offset)
+	source.offset += int64(n)
+	if n > 0 && err == io.EOF {
+		err = nil
+	}
+	return n, err
+}
+
+// Write implements io.Writer.
+func (source *MmapSource) Write(p []byte) (int, error) {
+	source.mutex.Lock()
+	defer source.mutex.Unlock()
+
+	n, err := source.writeAt(p, source.offset)
+	source.offset += int64(n)
+	return n, err
+}
+
+// Seek implements io.Seeker.
+func (source *MmapSource) Seek(offset int64, whence int) (int64, error) {
+	source.mutex.Lock()
+	defer source.mutex.Unlock()
+
+	switch whence {
+	case io.SeekCurrent:
+		offset += source.offset
+	case io.SeekEnd:
+		offset += source.size
+	case io.SeekStart:
+	default:
+		return 0, errors.New("MmapSource.Seek: invalid whence")
+	}
+
+	if offset < 0 {
+		return 0, errors.New("MmapSource.Seek: negative position")
+	}
+	source.offset = offset
+	return offset, nil
+}
+
+// Truncate changes the size of the file, shrinking the mapping if the file is made smaller.
+func (source *MmapSource) Truncate(size int64) error {
+	source.mutex.Lock()
+	defer source.mutex.Unlock()
+
+	if err := source.file.Truncate(size); err != nil {
+		return err
+	}
+
+	if size < source.size {
+		if source.dirtyEnd > size {
+			source.dirtyEnd = size
+		}
+		source.size = size
+		return source.remap(mmapCapacity(size))
+	}
+
+	source.size = size
+	if size > int64(len(source.data)) {
+		return source.remap(mmapCapacity(size))
+	}
+	return nil
+}
+
+// Size returns the size of the file.
+func (source *MmapSource) Size() int64 {
+	source.mutex.RLock()
+	defer source.mutex.RUnlock()
+	return source.size
+}
+
+// Sync flushes the changed pages of the mapping, and the size of the file, to disk.
+func (source *MmapSource) Sync() error {
+	source.mutex.Lock()
+	defer source.mutex.Unlock()
+
+	if err := source.flush(); err != nil {
+		return err
+	}
+	return source.file.Sync()
+}
+
+// Close unmaps the file, flushing any changes to disk, and closes it.
+func (source *MmapSource) Close() error {
+	source.mutex.Lock()
+	defer source.mutex.Unlock()
+
+	if err := source.remap(0); err != nil {
+		return err
+	}
+	return source.file.Close()
+}
+
+// unwrapSource returns the mapped file, so that it can be locked by NewDB.
+func (source *MmapSource) unwrapSource() interface{} {
+	return source.file
+}
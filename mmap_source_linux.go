@@ -0,0 +1,42 @@
+//go:build linux
+// +build linux
+
+package simpledb
+
+import (
+	"os"
+	"syscall"
+	"unsafe"
+)
+
+// mmapSupported is true on systems where NewMmapSource can map files.
+const mmapSupported = true
+
+// mmapFile maps the first length bytes of the file into memory for reading and writing.
+func mmapFile(file *os.File, length int) ([]byte, error) {
+	data, err := syscall.Mmap(int(file.Fd()), 0, length, syscall.PROT_READ|syscall.PROT_WRITE, syscall.MAP_SHARED)
+	if err != nil {
+		return nil, &os.PathError{Op: "mmap", Path: file.Name(), Err: err}
+	}
+	return data, nil
+}
+
+// munmapFile unmaps a mapping returned by mmapFile.
+func munmapFile(data []byte) error {
+	if err := syscall.Munmap(data); err != nil {
+		return os.NewSyscallError("munmap", err)
+	}
+	return nil
+}
+
+// msyncFile writes the changes to the given page-aligned part of a mapping to disk.
+func msyncFile(data []byte) error {
+	if len(data) == 0 {
+		return nil
+	}
+	_, _, errno := syscall.Syscall(syscall.SYS_MSYNC, uintptr(unsafe.Pointer(&data[0])), uintptr(len(data)), syscall.MS_SYNC)
+	if errno != 0 {
+		return os.NewSyscallError("msync", errno)
+	}
+	return nil
+}
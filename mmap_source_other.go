@@ -0,0 +1,23 @@
+//go:build !linux
+// +build !linux
+
+package simpledb
+
+import (
+	"os"
+)
+
+// mmapSupported is true on systems where NewMmapSource can map files.
+const mmapSupported = false
+
+func mmapFile(file *os.File, length int) ([]byte, error) {
+	return nil, errMmapUnsupported
+}
+
+func munmapFile(data []byte) error {
+	return errMmapUnsupported
+}
+
+func msyncFile(data []byte) error {
+	return errMmapUnsupported
+}
@@ -0,0 +1,228 @@
+package simpledb
+
+import (
+	"bytes"
+	"io"
+	"os"
+	"testing"
+)
+
+func TestMmapSource(t *testing.T) {
+	if !mmapSupported {
+		t.Skip("MmapSource is not supported on this system")
+	}
+
+	tempFile, err := os.CreateTemp(os.TempDir(), "simpledb-")
+	if err != nil {
+		t.Fatalf("Failed to create temp file: %s", err)
+	}
+
+	t.Cleanup(func() {
+		tempFile.Close()
+		os.Remove(tempFile.Name())
+	})
+
+	source, err := NewMmapSource(tempFile)
+	if err != nil {
+		t.Fatalf("Failed to map file: %s", err)
+	}
+
+	expectSize := func(size int64) {
+		if source.Size() != size {
+			t.Fatalf("expected source size %d, got %d", size, source.Size())
+		}
+		info, err := tempFile.Stat()
+		if err != nil {
+			t.Fatalf("Failed to stat file: %s", err)
+		} else if info.Size() != size {
+			t.Fatalf("expected file size %d, got %d", size, info.Size())
+		}
+	}
+
+	buf := make([]byte, 4)
+	if n, err := source.ReadAt(buf, 0); n != 0 || err != io.EOF {
+		t.Fatalf("expected EOF reading empty source, got %d, %v", n, err)
+	}
+
+	if _, err := source.Write([]byte("hello")); err != nil {
+		t.Fatalf("Failed to write to source: %s", err)
+	}
+	expectSize(5)
+
+	// writing across several pages grows the mapping
+	large := bytes.Repeat([]byte("simpledb"), int(mmapPageSize))
+	if _, err := source.WriteAt(large, 3); err != nil {
+		t.Fatalf("Failed to write to source: %s", err)
+	}
+	expectSize(3 + int64(len(large)))
+	if int64(len(source.data)) < source.Size() {
+		t.Fatalf("mapping of %d bytes is smaller than the file", len(source.data))
+	}
+
+	if _, err := source.Seek(1, io.SeekStart); err != nil {
+		t.Fatalf("Failed to seek source: %s", err)
+	}
+	if _, err := io.ReadFull(source, buf); err != nil {
+		t.Fatalf("Failed to read from source: %s", err)
+	} else if string(buf) != "elsi" {
+		t.Fatalf("read unexpected data: %q", buf)
+	}
+
+	if n, err := source.ReadAt(buf, source.Size()-2); n != 2 || err != io.EOF {
+		t.Fatalf("expected short read with EOF at end of source, got %d, %v", n, err)
+	}
+
+	if err := source.Sync(); err != nil {
+		t.Fatalf("Failed to sync source: %s", err)
+	}
+	fileData, err := os.ReadFile(tempFile.Name())
+	if err != nil {
+		t.Fatalf("Failed to read file: %s", err)
+	} else if !bytes.Equal(fileData, append([]byte("hel"), large...)) {
+		t.Fatalf("file data does not match data written to source")
+	}
+
+	if err := source.Truncate(10); err != nil {
+		t.Fatalf("Failed to truncate source: %s", err)
+	}
+	expectSize(10)
+	if int64(len(source.data)) != mmapPageSize {
+		t.Fatalf("expected truncating to shrink mapping to one page, got %d bytes", len(source.data))
+	}
+
+	if end, err := source.Seek(0, io.SeekEnd); err != nil {
+		t.Fatalf("Failed to seek source: %s", err)
+	} else if end != 10 {
+		t.Fatalf("expected end of source at 10, got %d", end)
+	}
+
+	if err := source.Close(); err != nil {
+		t.Fatalf("Failed to close source: %s", err)
+	}
+	if source.data != nil {
+		t.Fatalf("expected closing source to unmap file")
+	}
+}
+
+func TestMmapSourceDB(t *testing.T) {
+	if !mmapSupported {
+		t.Skip("MmapSource is not supported on this system")
+	}
+
+	type Human struct {
+		Name string `simpledb:"indexed"`
+		Age  uint16
+		Bio  []byte
+	}
+
+	tempFile, err := os.CreateTemp(os.TempDir(), "simpledb-")
+	if err != nil {
+		t.Fatalf("Failed to create temp file: %s", err)
+	}
+
+	t.Cleanup(func() {
+		tempFile.Close()
+		os.Remove(tempFile.Name())
+	})
+
+	source, err := NewMmapSource(tempFile)
+	if err != nil {
+		t.Fatalf("Failed to map file: %s", err)
+	}
+
+	db, err := NewDB(source, Human{})
+	if err != nil {
+		t.Fatalf("failed to create DB: %s", err)
+	}
+
+	names := []string{"George", "Marg", "Bill", "Wendy"}
+	humans := make(map[uint64]Human)
+	ids := make([]uint64, 0, 1000)
+	for i := 0; i < 1000; i++ {
+		human := Human{
+			Name: names[i%len(names)],
+			Age:  uint16(i),
+			Bio:  bytes.Repeat([]byte{byte(i)}, i%50),
+		}
+		id, err := db.Insert(human)
+		if err != nil {
+			t.Fatalf("Failed to insert human: %s", err)
+		}
+		humans[id] = human
+		ids = append(ids, id)
+	}
+
+	sizeBefore, err := db.Size()
+	if err != nil {
+		t.Fatalf("Failed to get DB size: %s", err)
+	} else if sizeBefore <= mmapPageSize {
+		t.Fatalf("expected DB to grow beyond one page, got %d bytes", sizeBefore)
+	}
+
+	var found Human
+	if err := db.Find(ids[500], &found); err != nil {
+		t.Fatalf("Failed to find human: %s", err)
+	} else if found.Age != 500 {
+		t.Fatalf("found wrong human: %+v", found)
+	}
+
+	for _, id := range ids[:900] {
+		if err := db.Drop(id); err != nil {
+			t.Fatalf("Failed to drop human: %s", err)
+		}
+		delete(humans, id)
+	}
+
+	if err := db.Defrag(); err != nil {
+		t.Fatalf("Failed to defrag DB: %s", err)
+	}
+
+	sizeAfter, err := db.Size()
+	if err != nil {
+		t.Fatalf("Failed to get DB size: %s", err)
+	} else if sizeAfter >= sizeBefore {
+		t.Fatalf("expected defrag to shrink DB from %d bytes, got %d", sizeBefore, sizeAfter)
+	}
+	if info, err := tempFile.Stat(); err != nil {
+		t.Fatalf("Failed to stat file: %s", err)
+	} else if info.Size() != sizeAfter {
+		t.Fatalf("expected file size %d after defrag, got %d", sizeAfter, info.Size())
+	}
+	if int64(len(source.data)) != mmapCapacity(sizeAfter) {
+		t.Fatalf("expected defrag to shrink mapping to %d bytes, got %d", mmapCapacity(sizeAfter), len(source.data))
+	}
+
+	bills, err := db.Filter(FilterQuery{"Name": "Bill"})
+	if err != nil {
+		t.Fatalf("Failed to filter humans: %s", err)
+	} else if len(bills) != 25 {
+		t.Fatalf("expected 25 humans named Bill, got %d", len(bills))
+	}
+
+	if err := db.Close(); err != nil {
+		t.Fatalf("Failed to close DB: %s", err)
+	}
+
+	// the file can be read back without mapping it
+	reopened, err := os.OpenFile(tempFile.Name(), os.O_RDWR, 0)
+	if err != nil {
+		t.Fatalf("Failed to reopen DB file: %s", err)
+	}
+	t.Cleanup(func() { reopened.Close() })
+
+	db, err = NewDB(reopened, Human{})
+	if err != nil {
+		t.Fatalf("failed to reopen DB: %s", err)
+	}
+	if db.RowCount() != len(humans) {
+		t.Fatalf("expected %d rows after reopening, got %d", len(humans), db.RowCount())
+	}
+	for id, human := range humans {
+		var found Human
+		if err := db.Find(id, &found); err != nil {
+			t.Fatalf("Failed to find human %d: %s", id, err)
+		} else if found.Name != human.Name || found.Age != human.Age || !bytes.Equal(found.Bio, human.Bio) {
+			t.Fatalf("found human %+v, expected %+v", found, human)
+		}
+	}
+}
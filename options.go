@@ -0,0 +1,56 @@
+package simpledb
+
+import (
+	"fmt"
+)
+
+// Option is an optional setting which can be passed to NewDB, or to db.Table if it
+// only configures a single table.
+type Option func(db *DB) error
+
+// errNewDBOption returns the error returned by an Option which configures the Source shared
+// by every table, when it is passed to db.Table.
+func errNewDBOption(name string) error {
+	return fmt.Errorf("%s configures every table in the DB source, and can only be passed to NewDB", name)
+}
+
+// WithJournal is an Option which makes the DB record every Insert, Update, Drop and Pop call in the given
+// journal Source before writing to the DB source, usually another *os.File stored alongside the DB file.
+// If a call is interrupted, for instance by a crash or power failure, NewDB will replay it upon reopening
+// the DB with the same journal, or discard it if it was never fully recorded. This ensures rows are never
+// torn or lost, and that Update calls are atomic. Defrag and Migrate calls record the whole rewritten DB in
+// the journal before copying it over the DB source, so the journal can grow as large as the DB. Each change
+// is flushed to stable storage, using the Sync method of the journal and DB source if they have one,
+// while a DB without a journal leaves flushing its writes to the operating system.
+//
+// The journal is closed when the DB is closed. WithJournal can only be passed to NewDB.
+func WithJournal(journal Source) Option {
+	return func(db *DB) error {
+		if db.tables != nil {
+			return errNewDBOption("WithJournal")
+		}
+		db.journal = journal
+		return nil
+	}
+}
+
+// WithIndexSnapshot is an Option which makes the DB save a snapshot of its in-memory indices to the given
+// Source, usually another *os.File stored alongside the DB file, whenever the DB is closed or defragged.
+// Upon reopening the DB with the same snapshot Source, the indices are loaded from the snapshot instead
+// of scanning every row in the DB source, which is much faster for large DBs.
+//
+// The snapshot is validated with a checksum, and is cleared as soon as the DB source is modified, so a
+// full scan is performed if the DB was not closed cleanly. A full scan is also performed if the size of
+// the DB source has changed, or any row in the snapshot is no longer found at its cursor, so that rows
+// inserted, updated or dropped while the DB was opened without this Option are not missed.
+//
+// The snapshot Source is closed when the DB is closed. WithIndexSnapshot can only be passed to NewDB.
+func WithIndexSnapshot(snapshot Source) Option {
+	return func(db *DB) error {
+		if db.tables != nil {
+			return errNewDBOption("WithIndexSnapshot")
+		}
+		db.indexSnapshot = snapshot
+		return nil
+	}
+}
@@ -0,0 +1,211 @@
+package simpledb
+
+import (
+	"reflect"
+	"strings"
+)
+
+// Query is a test of a whole row, which can be passed to db.Select, db.FilterSorted or db.Iterate. A Where
+// is the simplest Query. Queries can be combined into larger expressions with And, Or and Not.
+//
+//  rows, err := db.Select(simpledb.Or(
+//    simpledb.Where{"Make": "Mazda", "Year": simpledb.GreaterThan(2005)},
+//    simpledb.Not(simpledb.Where{"Make": "Mazda"}),
+//  ))
+type Query interface {
+	// Match returns true if the given pointer to a decoded row satisfies the query.
+	Match(rowPtr interface{}) bool
+}
+
+// indexedQuery is implemented by queries which can be evaluated using only the indexed
+// columns of a row, without decoding it.
+type indexedQuery interface {
+	// matchIndexed tests the query using the given lookup function, which returns the value of an indexed
+	// column. The second return value is false if the result cannot be known without decoding the row.
+	matchIndexed(lookup indexLookup) (match, known bool)
+}
+
+// indexLookup returns the value of an indexed column for some row, or false
+// if the column is not indexed.
+type indexLookup = func(columnName string) (interface{}, bool)
+
+// FilterQuery is a set of requirements which are passed to db.Filter, in the same form as Where.
+type FilterQuery = map[string]interface{}
+
+// Where is a Query made of a set of requirements which a row must meet, mapping column names to either
+// a plain value which the column must be strictly equal to, or a Condition which the column must match.
+// Fields of struct columns can be queried with a dotted path, such as "Address.City", as can the
+// entries of map columns with string keys, such as "Labels.env". A row which has no such field
+// or entry does not match.
+type Where map[string]interface{}
+
+// Match returns true if every column of the row matches the value or Condition in the Where.
+func (query Where) Match(rowPtr interface{}) bool {
+	row := reflect.Indirect(reflect.ValueOf(rowPtr))
+	for columnName, queryValue := range query {
+		fieldValue := fieldByPath(row, columnName)
+		if !fieldValue.IsValid() || !matchesColumn(queryValue, fieldValue.Interface()) {
+			return false
+		}
+	}
+	return true
+}
+
+func (query Where) matchIndexed(lookup indexLookup) (bool, bool) {
+	known := true
+	for columnName, queryValue := range query {
+		indexedValue, ok := lookup(columnName)
+		if !ok {
+			known = false
+		} else if !matchesColumn(queryValue, indexedValue) {
+			return false, true
+		}
+	}
+	return known, known
+}
+
+// andQuery matches rows which match every one of its queries.
+type andQuery []Query
+
+// And returns a Query which matches rows that match all of the given queries.
+// With no queries, it matches every row.
+func And(queries ...Query) Query {
+	return andQuery(queries)
+}
+
+func (queries andQuery) Match(rowPtr interface{}) bool {
+	for _, query := range queries {
+		if !query.Match(rowPtr) {
+			return false
+		}
+	}
+	return true
+}
+
+func (queries andQuery) matchIndexed(lookup indexLookup) (bool, bool) {
+	known := true
+	for _, query := range queries {
+		match, ok := matchIndexed(query, lookup)
+		if !ok {
+			known = false
+		} else if !match {
+			return false, true
+		}
+	}
+	return known, known
+}
+
+// orQuery matches rows which match at least one of its queries.
+type orQuery []Query
+
+// Or returns a Query which matches rows that match at least one of the given queries.
+// With no queries, it matches no rows.
+func Or(queries ...Query) Query {
+	return orQuery(queries)
+}
+
+func (queries orQuery) Match(rowPtr interface{}) bool {
+	for _, query := range queries {
+		if query.Match(rowPtr) {
+			return true
+		}
+	}
+	return false
+}
+
+func (queries orQuery) matchIndexed(lookup indexLookup) (bool, bool) {
+	known := true
+	for _, query := range queries {
+		match, ok := matchIndexed(query, lookup)
+		if !ok {
+			known = false
+		} else if match {
+			return true, true
+		}
+	}
+	return false, known
+}
+
+// notQuery matches rows which do not match its query.
+type notQuery struct {
+	query Query
+}
+
+// Not returns a Query which matches rows that do not match the given query.
+func Not(query Query) Query {
+	return notQuery{query}
+}
+
+func (not notQuery) Match(rowPtr interface{}) bool {
+	return !not.query.Match(rowPtr)
+}
+
+func (not notQuery) matchIndexed(lookup indexLookup) (bool, bool) {
+	match, known := matchIndexed(not.query, lookup)
+	return !match, known
+}
+
+// matchIndexed tests the query against the indexed columns of a row, if the query supports it.
+func matchIndexed(query Query, lookup indexLookup) (match, known bool) {
+	if indexed, ok := query.(indexedQuery); ok {
+		return indexed.matchIndexed(lookup)
+	}
+	return false, false
+}
+
+// mayMatch returns false if the row with the given ID is known not to match the query,
+// using only the values stored in the DB's custom indices.
+func (db *DB) mayMatch(query Query, id uint64) bool {
+	if len(db.customIndices) == 0 {
+		return true
+	}
+
+	match, known := matchIndexed(query, func(columnName string) (interface{}, bool) {
+		// fields of an indexed struct column can be found in the indexed value
+		path := strings.SplitN(columnName, ".", 2)
+		customIndex, ok := db.customIndices[path[0]]
+		if !ok {
+			return nil, false
+		}
+		value, ok := customIndex[id]
+		if !ok || len(path) == 1 {
+			return value, ok
+		}
+
+		fieldValue := fieldByPath(reflect.ValueOf(value), path[1])
+		if !fieldValue.IsValid() {
+			return nil, false
+		}
+		return fieldValue.Interface(), true
+	})
+	return match || !known
+}
+
+// fieldByPath returns the field of the struct value v with the given dotted path of field names,
+// such as "Address.City". Entries of maps with string keys can also be found by their key, such
+// as "Labels.env", and pointers are followed. It returns the zero Value if there is no such field
+// or entry, or if a nil pointer is found along the path.
+func fieldByPath(v reflect.Value, path string) reflect.Value {
+	for _, name := range strings.Split(path, ".") {
+		if v.Kind() == reflect.Ptr {
+			if v.IsNil() {
+				return reflect.Value{}
+			}
+			v = v.Elem()
+		}
+
+		switch {
+		case v.Kind() == reflect.Struct:
+			v = v.FieldByName(name)
+		case v.Kind() == reflect.Map && v.Type().Key().Kind() == reflect.String:
+			v = v.MapIndex(reflect.ValueOf(name).Convert(v.Type().Key()))
+		default:
+			return reflect.Value{}
+		}
+
+		if !v.IsValid() {
+			return v
+		}
+	}
+	return v
+}
@@ -0,0 +1,169 @@
+package simpledb
+
+import (
+	"reflect"
+)
+
+// candidates returns the IDs of every row which could match the query, found using the table's hash and
+// ordered indices. The second return value is false if the query cannot be narrowed down using an index,
+// in which case every row of the table must be checked. It assumes the caller is handling the mutex.
+func (db *DB) candidates(query Query) ([]uint64, bool) {
+	if len(db.customIndices) == 0 {
+		return nil, false
+	}
+
+	switch query := query.(type) {
+	case Where:
+		var best []uint64
+		narrowed := false
+		for columnName, queryValue := range query {
+			if ids, ok := db.columnCandidates(columnName, queryValue); ok && (!narrowed || len(ids) < len(best)) {
+				best, narrowed = ids, true
+			}
+		}
+		return best, narrowed
+
+	case andQuery:
+		var best []uint64
+		narrowed := false
+		for _, subquery := range query {
+			if ids, ok := db.candidates(subquery); ok && (!narrowed || len(ids) < len(best)) {
+				best, narrowed = ids, true
+			}
+		}
+		return best, narrowed
+
+	case orQuery:
+		var union []uint64
+		seen := make(map[uint64]bool)
+		for _, subquery := range query {
+			ids, ok := db.candidates(subquery)
+			if !ok {
+				return nil, false
+			}
+			for _, id := range ids {
+				if !seen[id] {
+					seen[id] = true
+					union = append(union, id)
+				}
+			}
+		}
+		return union, true
+	}
+
+	return nil, false
+}
+
+// columnCandidates returns the IDs of every row whose value in the given column could match the
+// query value, if the column is indexed. Equality is tested with the column's hash index, while
+// ranges of values are found with its ordered index, if it has one.
+func (db *DB) columnCandidates(columnName string, queryValue interface{}) ([]uint64, bool) {
+	if _, ok := db.customIndices[columnName]; !ok {
+		return nil, false
+	}
+	field, _ := db.schema.dataType.FieldByName(columnName)
+
+	ids := make([]uint64, 0)
+	seen := make(map[uint64]bool)
+	collect := func(id uint64) bool {
+		// values from an In condition can overlap
+		if !seen[id] {
+			seen[id] = true
+			ids = append(ids, id)
+		}
+		return true
+	}
+
+	if keys, ok := equalityKeys(queryValue, field.Type); ok {
+		for _, key := range keys {
+			for id := range db.hashIndices[columnName][key] {
+				collect(id)
+			}
+		}
+		return ids, true
+	}
+
+	index, ok := db.orderedIndices[columnName]
+	if !ok {
+		return nil, false
+	}
+
+	ranges, ok := queryRanges(queryValue, field.Type)
+	if !ok {
+		return nil, false
+	}
+
+	for _, r := range ranges {
+		index.scan(r, false, collect)
+	}
+	return ids, true
+}
+
+// queryRanges returns the ranges of values of the given column type which could match the given value
+// from a Where. The second return value is false if the matching values cannot be described
+// as a set of ranges.
+func queryRanges(queryValue interface{}, columnType reflect.Type) ([]keyRange, bool) {
+	if c, ok := queryValue.(*comparison); ok {
+		return c.keyRanges(columnType)
+	} else if _, ok := queryValue.(Condition); ok {
+		return nil, false
+	}
+
+	// Plain values are compared with reflect.DeepEqual, which never matches values of a different type.
+	if reflect.TypeOf(queryValue) != columnType {
+		return nil, true
+	}
+	return []keyRange{pointRange(queryValue)}, true
+}
+
+// keyRanges returns the ranges of values of the given column type which could match the comparison.
+func (c *comparison) keyRanges(columnType reflect.Type) ([]keyRange, bool) {
+	// Operands which cannot be compared to the column never match it.
+	comparable := func(i int) bool {
+		operand := reflect.ValueOf(c.operands[i])
+		return operand.IsValid() && isComparableType(columnType, operand.Type()) && !isNaN(operand)
+	}
+
+	if c.operator == opNotEqual {
+		return nil, false
+	}
+
+	if c.operator == opIn {
+		ranges := make([]keyRange, 0, len(c.operands))
+		for i, operand := range c.operands {
+			if comparable(i) {
+				ranges = append(ranges, pointRange(operand))
+			}
+		}
+		return ranges, true
+	}
+
+	for i := range c.operands {
+		if !comparable(i) {
+			return nil, true
+		}
+	}
+
+	operand := reflect.ValueOf(c.operands[0])
+	switch c.operator {
+	case opEqual:
+		return []keyRange{pointRange(c.operands[0])}, true
+	case opLessThan:
+		return []keyRange{{max: operand}}, true
+	case opLessOrEqual:
+		return []keyRange{{max: operand, maxInclusive: true}}, true
+	case opGreaterThan:
+		return []keyRange{{min: operand}}, true
+	case opGreaterOrEqual:
+		return []keyRange{{min: operand, minInclusive: true}}, true
+	case opBetween:
+		return []keyRange{{
+			min:          operand,
+			max:          reflect.ValueOf(c.operands[1]),
+			minInclusive: true,
+			maxInclusive: true,
+		}}, true
+	}
+
+	return nil, false
+}
@@ -0,0 +1,118 @@
+package simpledb
+
+import (
+	"os"
+	"sort"
+	"testing"
+)
+
+func TestQueries(t *testing.T) {
+	type Car struct {
+		Year  uint16 `simpledb:"indexed"`
+		Make  string `simpledb:"indexed"`
+		Price float64
+	}
+
+	tempFile, err := os.CreateTemp(os.TempDir(), "simpledb-")
+	if err != nil {
+		t.Fatalf("Failed to create temp file: %s", err)
+	}
+
+	t.Cleanup(func() {
+		tempFile.Close()
+		os.Remove(tempFile.Name())
+	})
+
+	db, err := NewDB(tempFile, Car{})
+	if err != nil {
+		t.Fatalf("failed to create DB: %s", err)
+	}
+
+	cars := []Car{
+		{Year: 1999, Make: "Ford", Price: 5},
+		{Year: 2004, Make: "Mazda", Price: 12},
+		{Year: 2008, Make: "Mazda", Price: 18},
+		{Year: 2012, Make: "Toyota", Price: 25},
+	}
+	for _, car := range cars {
+		if _, err := db.Insert(car); err != nil {
+			t.Fatalf("Failed to insert car: %s", err)
+		}
+	}
+
+	type Fixture struct {
+		query    Query
+		expected []uint16
+	}
+
+	fixtures := []Fixture{
+		{And(), []uint16{1999, 2004, 2008, 2012}},
+		{Or(), nil},
+		{Where{"Make": "Mazda"}, []uint16{2004, 2008}},
+		{Where{"Colour": "red"}, nil},
+		{Not(Where{"Make": "Mazda"}), []uint16{1999, 2012}},
+		{Or(Where{"Make": "Ford"}, Where{"Make": "Toyota"}), []uint16{1999, 2012}},
+		{
+			And(Where{"Make": "Mazda"}, Where{"Price": GreaterThan(15)}),
+			[]uint16{2008},
+		},
+		{
+			Or(Where{"Year": LessThan(2000)}, Where{"Price": GreaterThan(20)}),
+			[]uint16{1999, 2012},
+		},
+		{
+			And(
+				Not(Where{"Make": "Ford"}),
+				Or(Where{"Price": LessThan(15)}, Where{"Year": Equal(2012)}),
+			),
+			[]uint16{2004, 2012},
+		},
+		{Not(Or(Where{"Make": "Mazda"}, Where{"Price": LessThan(10)})), []uint16{2012}},
+	}
+
+	years := func(rows []*Row) []uint16 {
+		var result []uint16
+		for _, row := range rows {
+			result = append(result, row.Value.(*Car).Year)
+		}
+		sort.Slice(result, func(i, j int) bool { return result[i] < result[j] })
+		return result
+	}
+
+	equal := func(a, b []uint16) bool {
+		if len(a) != len(b) {
+			return false
+		}
+		for i := range a {
+			if a[i] != b[i] {
+				return false
+			}
+		}
+		return true
+	}
+
+	for i, fixture := range fixtures {
+		rows, err := db.Select(fixture.query)
+		if err != nil {
+			t.Fatalf("Failed to filter cars: %s", err)
+		}
+		if actual := years(rows); !equal(actual, fixture.expected) {
+			t.Errorf("filter query %d returned cars from %v, expected %v", i, actual, fixture.expected)
+		}
+
+		var iterated []*Row
+		iter := db.Iterate(fixture.query)
+		for {
+			row, err := iter()
+			if err != nil {
+				t.Fatalf("Failed to iterate cars: %s", err)
+			} else if row == nil {
+				break
+			}
+			iterated = append(iterated, row)
+		}
+		if actual := years(iterated); !equal(actual, fixture.expected) {
+			t.Errorf("iterating with query %d returned cars from %v, expected %v", i, actual, fixture.expected)
+		}
+	}
+}
@@ -0,0 +1,306 @@
+package simpledb
+
+import (
+	"bytes"
+	"encoding/binary"
+	"io"
+	"math"
+	"reflect"
+	"sync"
+)
+
+// fieldEncoding describes how a field in a structPlan is encoded.
+type fieldEncoding uint8
+
+const (
+	// encodeReflected fields are encoded with encodeToBinary and decoded with decodeFromBinary.
+	encodeReflected fieldEncoding = iota
+
+	// encodeScalar fields are bools or sized numbers.
+	encodeScalar
+
+	// encodeString fields are strings.
+	encodeString
+
+	// encodeBytes fields are slices of bytes.
+	encodeBytes
+
+	// encodeScalarSlice fields are slices of bools or sized numbers.
+	encodeScalarSlice
+
+	// encodeScalarArray fields are arrays of bools or sized numbers.
+	encodeScalarArray
+)
+
+// fieldPlan is the precomputed encoding of one field in a structPlan.
+type fieldPlan struct {
+	index    []int
+	encoding fieldEncoding
+
+	// kind and size are the kind and encoded size of a scalar field, or of the
+	// elements of a scalar slice or array field.
+	kind reflect.Kind
+	size int
+}
+
+// structPlan is the precomputed layout of a struct type's columns, so that encodeStructToBinary and
+// decodeStructFromBinary do not need to look up and sort the struct's fields for every value. Fields
+// which are bools, numbers, strings, or slices and arrays of bools and numbers, are encoded without
+// encoding/binary. The encoding is the same as that of encodeToBinary.
+type structPlan struct {
+	fields []fieldPlan
+
+	// fixedSize is the total encoded size of the struct's scalar and scalar array fields.
+	fixedSize int
+}
+
+// structPlans caches the structPlan of each struct type which has been encoded or decoded.
+var structPlans sync.Map
+
+// getStructPlan returns the cached structPlan of the struct type t, creating it if needed.
+func getStructPlan(t reflect.Type) *structPlan {
+	if plan, ok := structPlans.Load(t); ok {
+		return plan.(*structPlan)
+	}
+
+	fields := getExportedFields(t)
+	plan := &structPlan{fields: make([]fieldPlan, len(fields))}
+	for i, field := range fields {
+		fieldType := field.Type
+		fp := fieldPlan{index: field.Index}
+
+		if fieldType != timeType && !isBinaryMarshalerType(fieldType) {
+			switch kind := fieldType.Kind(); kind {
+			case reflect.String:
+				fp.encoding = encodeString
+
+			case reflect.Slice, reflect.Array:
+				elemKind := fieldType.Elem().Kind()
+				if fp.size = scalarKindSize(elemKind); fp.size == 0 {
+					break
+				}
+				fp.kind = elemKind
+				if kind == reflect.Array {
+					fp.encoding = encodeScalarArray
+					plan.fixedSize += fp.size * fieldType.Len()
+				} else if elemKind == reflect.Uint8 {
+					fp.encoding = encodeBytes
+				} else {
+					fp.encoding = encodeScalarSlice
+				}
+
+			default:
+				if fp.size = scalarKindSize(kind); fp.size != 0 {
+					fp.encoding = encodeScalar
+					fp.kind = kind
+					plan.fixedSize += fp.size
+				}
+			}
+		}
+
+		plan.fields[i] = fp
+	}
+
+	actual, _ := structPlans.LoadOrStore(t, plan)
+	return actual.(*structPlan)
+}
+
+// scalarKindSize returns the encoded size of a bool or sized number of the given kind,
+// or zero for any other kind.
+func scalarKindSize(kind reflect.Kind) int {
+	switch kind {
+	case reflect.Bool, reflect.Int8, reflect.Uint8:
+		return 1
+	case reflect.Int16, reflect.Uint16:
+		return 2
+	case reflect.Int32, reflect.Uint32, reflect.Float32:
+		return 4
+	case reflect.Int64, reflect.Uint64, reflect.Float64, reflect.Complex64:
+		return 8
+	case reflect.Complex128:
+		return 16
+	}
+	return 0
+}
+
+// encode writes the encoding of the struct value to w, returning the number of bytes written.
+func (plan *structPlan) encode(w io.Writer, value reflect.Value) (int, error) {
+	buf := bytes.NewBuffer(make([]byte, 0, plan.fixedSize))
+	var scratch [16]byte
+
+	for i := range plan.fields {
+		field := &plan.fields[i]
+		fieldValue := value.FieldByIndex(field.index)
+
+		switch field.encoding {
+		case encodeScalar:
+			buf.Write(appendScalar(scratch[:0], field.kind, fieldValue))
+
+		case encodeString:
+			buf.Write(scratch[:binary.PutUvarint(scratch[:], uint64(fieldValue.Len()))])
+			buf.WriteString(fieldValue.String())
+
+		case encodeBytes:
+			buf.Write(scratch[:binary.PutUvarint(scratch[:], uint64(fieldValue.Len()))])
+			buf.Write(fieldValue.Bytes())
+
+		case encodeScalarSlice, encodeScalarArray:
+			if field.encoding == encodeScalarSlice {
+				buf.Write(scratch[:binary.PutUvarint(scratch[:], uint64(fieldValue.Len()))])
+			}
+			for j := 0; j < fieldValue.Len(); j++ {
+				buf.Write(appendScalar(scratch[:0], field.kind, fieldValue.Index(j)))
+			}
+
+		default:
+			if _, err := encodeToBinary(buf, fieldValue); err != nil {
+				return 0, err
+			}
+		}
+	}
+
+	bytesWritten, err := buf.WriteTo(w)
+	return int(bytesWritten), err
+}
+
+// decode reads the encoding of a struct from r into the given addressable struct value,
+// returning the number of bytes read.
+func (plan *structPlan) decode(r io.Reader, value reflect.Value) (int, error) {
+	byteReader := &wrappedByteReader{Reader: r}
+
+	bytesRead := 0
+	for i := range plan.fields {
+		field := &plan.fields[i]
+		fieldValue := value.FieldByIndex(field.index)
+
+		if field.encoding == encodeReflected {
+			n, err := decodeFromBinary(byteReader, fieldValue.Addr())
+			bytesRead += n
+			if err != nil {
+				return bytesRead, err
+			}
+			continue
+		}
+
+		length := 1
+		if field.encoding == encodeScalarArray {
+			length = fieldValue.Len()
+		} else if field.encoding != encodeScalar {
+			n, err := binary.ReadUvarint(byteReader)
+			if err != nil {
+				return bytesRead, err
+			}
+			bytesRead += len(encodeUvarint(n))
+			length = int(n)
+		}
+
+		switch field.encoding {
+		case encodeString, encodeBytes:
+			data := make([]byte, length)
+			n, err := io.ReadFull(byteReader, data)
+			bytesRead += n
+			if err != nil {
+				return bytesRead, err
+			}
+			if field.encoding == encodeString {
+				fieldValue.SetString(string(data))
+			} else {
+				fieldValue.SetBytes(data)
+			}
+
+		case encodeScalar:
+			n, err := io.ReadFull(byteReader, byteReader.buf[:field.size])
+			bytesRead += n
+			if err != nil {
+				return bytesRead, err
+			}
+			setScalar(fieldValue, field.kind, byteReader.buf[:field.size])
+
+		default:
+			if field.encoding == encodeScalarSlice {
+				fieldValue.Set(reflect.MakeSlice(fieldValue.Type(), length, length))
+			}
+			for j := 0; j < length; j++ {
+				n, err := io.ReadFull(byteReader, byteReader.buf[:field.size])
+				bytesRead += n
+				if err != nil {
+					return bytesRead, err
+				}
+				setScalar(fieldValue.Index(j), field.kind, byteReader.buf[:field.size])
+			}
+		}
+	}
+
+	return bytesRead, nil
+}
+
+// appendScalar appends the big-endian encoding of a bool or sized number to buf.
+func appendScalar(buf []byte, kind reflect.Kind, value reflect.Value) []byte {
+	switch kind {
+	case reflect.Bool:
+		if value.Bool() {
+			return append(buf, 1)
+		}
+		return append(buf, 0)
+	case reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return appendUint(buf, uint64(value.Int()), scalarKindSize(kind))
+	case reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return appendUint(buf, value.Uint(), scalarKindSize(kind))
+	case reflect.Float32:
+		return appendUint(buf, uint64(math.Float32bits(float32(value.Float()))), 4)
+	case reflect.Float64:
+		return appendUint(buf, math.Float64bits(value.Float()), 8)
+	case reflect.Complex64:
+		c := value.Complex()
+		buf = appendUint(buf, uint64(math.Float32bits(float32(real(c)))), 4)
+		return appendUint(buf, uint64(math.Float32bits(float32(imag(c)))), 4)
+	case reflect.Complex128:
+		c := value.Complex()
+		buf = appendUint(buf, math.Float64bits(real(c)), 8)
+		return appendUint(buf, math.Float64bits(imag(c)), 8)
+	}
+	return buf
+}
+
+// appendUint appends the lowest size bytes of n to buf in big-endian order.
+func appendUint(buf []byte, n uint64, size int) []byte {
+	for shift := (size - 1) * 8; shift >= 0; shift -= 8 {
+		buf = append(buf, byte(n>>shift))
+	}
+	return buf
+}
+
+// setScalar sets a bool or sized number from its big-endian encoding. This is the reverse of appendScalar.
+func setScalar(value reflect.Value, kind reflect.Kind, data []byte) {
+	var n uint64
+	for _, b := range data {
+		n = n<<8 | uint64(b)
+	}
+
+	switch kind {
+	case reflect.Bool:
+		value.SetBool(n != 0)
+	case reflect.Int8:
+		value.SetInt(int64(int8(n)))
+	case reflect.Int16:
+		value.SetInt(int64(int16(n)))
+	case reflect.Int32:
+		value.SetInt(int64(int32(n)))
+	case reflect.Int64:
+		value.SetInt(int64(n))
+	case reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		value.SetUint(n)
+	case reflect.Float32:
+		value.SetFloat(float64(math.Float32frombits(uint32(n))))
+	case reflect.Float64:
+		value.SetFloat(math.Float64frombits(n))
+	case reflect.Complex64:
+		r := math.Float32frombits(binary.BigEndian.Uint32(data[:4]))
+		i := math.Float32frombits(binary.BigEndian.Uint32(data[4:]))
+		value.SetComplex(complex(float64(r), float64(i)))
+	case reflect.Complex128:
+		r := math.Float64frombits(binary.BigEndian.Uint64(data[:8]))
+		i := math.Float64frombits(binary.BigEndian.Uint64(data[8:]))
+		value.SetComplex(complex(r, i))
+	}
+}
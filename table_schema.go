@@ -8,29 +8,59 @@ import (
 
 type tableSchema struct {
 	dataType reflect.Type
+
+	// plan is the cached structPlan of dataType, used to encode and decode rows.
+	plan *structPlan
 }
 
 func (schema *tableSchema) ColumnNames() []string {
 	return getExportedFieldNames(schema.dataType)
 }
 
+// Columns returns a description of each column in the schema, in encoding order.
+func (schema *tableSchema) Columns() []Column {
+	columnNames := schema.ColumnNames()
+	columns := make([]Column, len(columnNames))
+	for i, columnName := range columnNames {
+		field, _ := schema.dataType.FieldByName(columnName)
+		columns[i] = Column{
+			Name: columnName,
+			Type: columnTypeSignature(field.Type),
+		}
+	}
+	return columns
+}
+
 func (schema *tableSchema) Reflect(strct interface{}) error {
 	dataType := reflect.TypeOf(strct)
 	if dataType.Kind() != reflect.Struct {
 		return fmt.Errorf("Only struct types can be passed to schema.Reflect. Got: %s", dataType)
 	}
 
-	fields := reflect.VisibleFields(dataType)
+	fields := getExportedFields(dataType)
 
 	for _, field := range fields {
-		if field.IsExported() && !isValidColumnType(field.Type) {
+		if !isValidNestedColumnType(field.Type, []reflect.Type{dataType}) {
 			return fmt.Errorf("Only structs exporting fields of fixed-size types, "+
-				"strings, or slices of such types can be passed to schema.Reflect; "+
+				"strings, non-recursive structs, or slices and arrays of such types can be passed to schema.Reflect; "+
 				"Found kind '%s' in struct '%s'", field.Type, dataType)
 		}
 	}
 
+	for _, field := range fields {
+		if !hasTagOption(field, "ordered") {
+			continue
+		}
+		if !hasTagOption(field, "indexed") && !hasTagOption(field, "unique") {
+			return fmt.Errorf("Ordered field '%s' in struct '%s' must also be tagged as indexed", field.Name, dataType)
+		} else if !isOrderedColumnType(field.Type) {
+			return fmt.Errorf("Ordered field '%s' in struct '%s' has type '%s', which cannot be ordered",
+				field.Name, dataType, field.Type)
+		}
+	}
+
 	schema.dataType = dataType
+	schema.plan = getStructPlan(dataType)
 	return nil
 }
 
@@ -42,7 +72,7 @@ func (schema *tableSchema) Encode(w io.Writer, e interface{}) (int, error) {
 		return 0, fmt.Errorf("invalid data type for DB encoding '%s'", value.Type())
 	}
 
-	return encodeStructToBinary(w, value)
+	return schema.plan.encode(w, value)
 }
 
 func (schema *tableSchema) Decode(r io.Reader, e interface{}) (int, error) {
@@ -51,5 +81,5 @@ func (schema *tableSchema) Decode(r io.Reader, e interface{}) (int, error) {
 		return 0, fmt.Errorf("invalid data type for DB decoding '%s'", value.Type())
 	}
 
-	return decodeStructFromBinary(r, value)
+	return schema.plan.decode(r, value.Elem())
 }
@@ -15,10 +15,20 @@ func TestTableSchema(t *testing.T) {
 		}
 	}
 
-	failIfNoError([]int{})                                     // only structs
-	failIfNoError(struct{ Age uint }{})                        // no unsized integer types
-	failIfNoError(struct{ IntArray []int }{})                  // no slices unless they are fixed-size element types
-	failIfNoError(struct{ SubStruct struct{ Name string } }{}) // no composite field types
+	failIfNoError([]int{})                                  // only structs
+	failIfNoError(struct{ Age uint }{})                     // no unsized integer types
+	failIfNoError(struct{ IntArray []int }{})               // no slices unless they are fixed-size element types
+	failIfNoError(struct{ SubStruct struct{ Age uint } }{}) // no struct fields unless their own fields are valid
+
+	type Node struct {
+		Children []Node
+	}
+	failIfNoError(struct{ Root Node }{}) // no recursive struct types
+
+	type LinkedNode struct {
+		Next *LinkedNode
+	}
+	failIfNoError(struct{ Head *LinkedNode }{}) // not even through a pointer
 
 	type Person struct {
 		private uint
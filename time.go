@@ -0,0 +1,51 @@
+package simpledb
+
+import (
+	"encoding/binary"
+	"io"
+	"reflect"
+	"time"
+)
+
+// timeType is the type of time.Time, which is encoded as an instant in time rather
+// than as a struct, as it has no exported fields.
+var timeType = reflect.TypeOf(time.Time{})
+
+// timeSize is the size of an encoded time.Time: a signed 64-bit count of seconds since
+// the Unix epoch, followed by an unsigned 32-bit count of nanoseconds within that second.
+const timeSize = 12
+
+// encodeTimeToBinary encodes a time.Time value as the instant it represents. Its location and
+// monotonic clock reading are not encoded, so it is decoded in UTC with no monotonic clock reading.
+func encodeTimeToBinary(w io.Writer, timeValue reflect.Value) (int, error) {
+	t := timeValue.Interface().(time.Time)
+
+	buf := make([]byte, timeSize)
+	binary.BigEndian.PutUint64(buf, uint64(t.Unix()))
+	binary.BigEndian.PutUint32(buf[8:], uint32(t.Nanosecond()))
+	return w.Write(buf)
+}
+
+// decodeTimeFromBinary decodes a time.Time encoded by encodeTimeToBinary into the given time value.
+func decodeTimeFromBinary(r io.Reader, timeValue reflect.Value) (int, error) {
+	buf := make([]byte, timeSize)
+	if n, err := io.ReadFull(r, buf); err != nil {
+		return n, err
+	}
+
+	seconds := int64(binary.BigEndian.Uint64(buf))
+	nanoseconds := int64(binary.BigEndian.Uint32(buf[8:]))
+	timeValue.Set(reflect.ValueOf(time.Unix(seconds, nanoseconds).UTC()))
+	return timeSize, nil
+}
+
+// compareTimes compares two time.Time values by the instant they represent.
+func compareTimes(a, b reflect.Value) int {
+	aTime, bTime := a.Interface().(time.Time), b.Interface().(time.Time)
+	if aTime.Before(bTime) {
+		return -1
+	} else if aTime.After(bTime) {
+		return 1
+	}
+	return 0
+}
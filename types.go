@@ -3,6 +3,7 @@ package simpledb
 import (
 	"reflect"
 	"sort"
+	"strings"
 )
 
 // PrimitiveFixedSizeKinds are the accepted primitive types, usable for columns in a DB schema struct.
@@ -61,42 +62,237 @@ func isVariableSizeType(t reflect.Type) bool {
 }
 
 func isValidColumnType(t reflect.Type) bool {
-	if t.Kind() == reflect.String {
+	return isValidNestedColumnType(t, nil)
+}
+
+// isValidNestedColumnType returns true if t is a valid column type when nested inside the given
+// struct types. Recursive struct types are not valid, as their schema cannot be described.
+func isValidNestedColumnType(t reflect.Type, parents []reflect.Type) bool {
+	return isValidColumnKind(t, parents, true)
+}
+
+// hasPlainEncoding returns true if t is a valid column type without encoding any value with its MarshalBinary
+// method, and would encode some data. Such types keep the encoding of their kind even if they implement
+// encoding.BinaryMarshaler, so that rows written before those types were encoded with MarshalBinary are
+// still decoded correctly. Structs without exported columns would encode nothing, so they are excluded.
+func hasPlainEncoding(t reflect.Type) bool {
+	return isValidColumnKind(t, nil, false)
+}
+
+// isValidColumnKind returns true if t is a valid column type when nested inside the given struct types.
+// If marshalers is false, types encoded with their MarshalBinary method and structs without any exported
+// fields are not valid.
+func isValidColumnKind(t reflect.Type, parents []reflect.Type, marshalers bool) bool {
+	if t == timeType || (marshalers && isBinaryMarshalerType(t)) {
 		return true
 	}
 
-	// strings & slices of bytes are allowed
-	if t.Kind() == reflect.Slice {
-		return isValidColumnType(t.Elem())
+	switch t.Kind() {
+	case reflect.String:
+		return true
+
+	// slices and arrays of any valid type are allowed
+	case reflect.Slice, reflect.Array:
+		return isValidColumnKind(t.Elem(), parents, marshalers)
+
+	case reflect.Map:
+		return isValidColumnKind(t.Key(), parents, marshalers) && isValidColumnKind(t.Elem(), parents, marshalers)
+
+	// pointers can be nil, so a pointer to a struct type is still recursive
+	case reflect.Ptr:
+		return isValidColumnKind(t.Elem(), parents, marshalers)
+
+	case reflect.Struct:
+		for _, parent := range parents {
+			if parent == t {
+				return false
+			}
+		}
+		parents = append(parents, t)
+
+		fields := getExportedFields(t)
+		if !marshalers && len(fields) == 0 {
+			return false
+		}
+		for _, field := range fields {
+			if !isValidColumnKind(field.Type, parents, marshalers) {
+				return false
+			}
+		}
+		return true
 	}
 
 	return isFixedSizeType(t)
 }
 
+// needsRecursiveEncoding returns true if t is a slice or array whose elements
+// cannot be encoded all at once by encoding/binary.
 func needsRecursiveEncoding(t reflect.Type) bool {
-	return t.Kind() == reflect.Slice && isVariableSizeType(t.Elem())
+	return (t.Kind() == reflect.Slice || t.Kind() == reflect.Array) && !isFixedSizeType(t.Elem())
 }
 
-func getExportedFieldNames(t reflect.Type) []string {
+// getExportedFields returns the exported fields of the struct type t, which are encoded as columns,
+// sorted by name. Fields promoted from an exported embedded struct are not included, as they are
+// encoded as part of the embedded struct.
+func getExportedFields(t reflect.Type) []reflect.StructField {
 	fields := reflect.VisibleFields(t)
-	fieldNames := make([]string, 0, len(fields))
+	columns := make([]reflect.StructField, 0, len(fields))
+
+nextField:
 	for _, field := range fields {
-		if field.IsExported() {
-			fieldNames = append(fieldNames, field.Name)
+		if !field.IsExported() {
+			continue
 		}
+
+		parent := t
+		for _, i := range field.Index[:len(field.Index)-1] {
+			embedded := parent.Field(i)
+			if embedded.IsExported() {
+				continue nextField
+			}
+			parent = embedded.Type
+		}
+
+		columns = append(columns, field)
+	}
+
+	sort.Slice(columns, func(i, j int) bool {
+		return columns[i].Name < columns[j].Name
+	})
+	return columns
+}
+
+func getExportedFieldNames(t reflect.Type) []string {
+	fields := getExportedFields(t)
+	fieldNames := make([]string, len(fields))
+	for i, field := range fields {
+		fieldNames[i] = field.Name
 	}
-	sort.Strings(fieldNames)
 	return fieldNames
 }
 
+// getExportedIndexedFields returns the sorted names of the exported indexed fields of t.
+// Unique fields are always indexed.
 func getExportedIndexedFields(t reflect.Type) []string {
-	fields := reflect.VisibleFields(t)
+	return getExportedFieldsWithTag(t, "indexed", "unique")
+}
+
+func getExportedUniqueFields(t reflect.Type) []string {
+	return getExportedFieldsWithTag(t, "unique")
+}
+
+func getExportedOrderedFields(t reflect.Type) []string {
+	return getExportedFieldsWithTag(t, "ordered")
+}
+
+// getExportedFieldsWithTag returns the sorted names of the exported fields of t whose
+// struct tag includes any of the given options.
+func getExportedFieldsWithTag(t reflect.Type, options ...string) []string {
+	fields := getExportedFields(t)
 	fieldNames := make([]string, 0, len(fields))
 	for _, field := range fields {
-		if field.IsExported() && field.Tag.Get(StructTag) == "indexed" {
-			fieldNames = append(fieldNames, field.Name)
+		for _, option := range options {
+			if hasTagOption(field, option) {
+				fieldNames = append(fieldNames, field.Name)
+				break
+			}
 		}
 	}
 	sort.Strings(fieldNames)
 	return fieldNames
 }
+
+// hasTagOption returns true if the comma-separated options in the field's
+// struct tag include the given option, e.g. `simpledb:"indexed,ordered"`.
+func hasTagOption(field reflect.StructField, option string) bool {
+	for _, tagOption := range strings.Split(field.Tag.Get(StructTag), ",") {
+		if strings.TrimSpace(tagOption) == option {
+			return true
+		}
+	}
+	return false
+}
+
+// isOrderedColumnType returns true if values of the column type can be stored in an ordered index,
+// i.e. numbers other than complex numbers, strings, bools, times, and arrays or slices of such types.
+func isOrderedColumnType(t reflect.Type) bool {
+	switch kind := t.Kind(); {
+	case isRealKind(kind), kind == reflect.String, kind == reflect.Bool, t == timeType:
+		return true
+	case kind == reflect.Array, kind == reflect.Slice:
+		return isOrderedColumnType(t.Elem())
+	}
+	return false
+}
+
+// isComparableType returns true if values of type a can always be ordered against values of type b
+// by compareValues, with the exception of NaN.
+func isComparableType(a, b reflect.Type) bool {
+	aKind, bKind := a.Kind(), b.Kind()
+	switch {
+	case isRealKind(aKind) && isRealKind(bKind):
+		return true
+	case aKind == reflect.String && bKind == reflect.String, aKind == reflect.Bool && bKind == reflect.Bool:
+		return true
+	case a == timeType && b == timeType:
+		return true
+	case (aKind == reflect.Array || aKind == reflect.Slice) && (bKind == reflect.Array || bKind == reflect.Slice):
+		return isComparableType(a.Elem(), b.Elem())
+	}
+	return false
+}
+
+func isNumericKind(kind reflect.Kind) bool {
+	for _, numericKind := range PrimitiveFixedSizeKinds {
+		if kind == numericKind && kind != reflect.Bool {
+			return true
+		}
+	}
+	return false
+}
+
+// isConvertibleColumnType returns true if values of column type from can be converted to
+// column type to without loss of meaning, i.e. between numeric types, or between strings
+// and byte slices.
+func isConvertibleColumnType(from, to reflect.Type) bool {
+	if !from.ConvertibleTo(to) {
+		return false
+	}
+
+	if isNumericKind(from.Kind()) && isNumericKind(to.Kind()) {
+		return true
+	}
+
+	isStringOrBytes := func(t reflect.Type) bool {
+		return t.Kind() == reflect.String || (t.Kind() == reflect.Slice && t.Elem().Kind() == reflect.Uint8)
+	}
+	return isStringOrBytes(from) && isStringOrBytes(to)
+}
+
+func isIntegerKind(kind reflect.Kind) bool {
+	return isSignedKind(kind) || isUnsignedKind(kind)
+}
+
+func isSignedKind(kind reflect.Kind) bool {
+	switch kind {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return true
+	}
+	return false
+}
+
+func isUnsignedKind(kind reflect.Kind) bool {
+	switch kind {
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		return true
+	}
+	return false
+}
+
+func isRealKind(kind reflect.Kind) bool {
+	return isIntegerKind(kind) || kind == reflect.Float32 || kind == reflect.Float64
+}
+
+func isComplexKind(kind reflect.Kind) bool {
+	return kind == reflect.Complex64 || kind == reflect.Complex128
+}